@@ -3,25 +3,50 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"net/http"
 	"sync"
 
 	_ "net/http/pprof"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
+	identity "github.com/zhupanovdm/go-runtime-monitor/model/agent"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/app"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc"
 	client "github.com/zhupanovdm/go-runtime-monitor/providers/monitor/http/v2"
 	"github.com/zhupanovdm/go-runtime-monitor/service/agent"
 )
 
+var buildVersion = "N/A"
+
 func cli(cfg *config.Config, flag *flag.FlagSet) {
 	flag.StringVar(&cfg.Address, "a", config.DefaultAddress, "Monitor server address")
+	flag.StringVar(&cfg.GRPCAddress, "g", "", "Monitor server gRPC address, agent reports via gRPC if set, can't be used with -crypto-key")
 	flag.DurationVar(&cfg.ReportInterval, "r", config.DefaultReportInterval, "Agent reporting interval")
 	flag.DurationVar(&cfg.PollInterval, "p", config.DefaultPollInterval, "Agent polling interval")
 	flag.StringVar(&cfg.Key, "k", "", "Packet signing key")
+	flag.StringVar(&cfg.CryptoKey, "crypto-key", "", "Monitor server public key file to encrypt payloads")
+	flag.StringVar(&cfg.OutboxDir, "o", "", "Directory to keep unsent reports")
+	flag.Int64Var(&cfg.OutboxMaxSize, "outbox-size", config.DefaultOutboxMaxSize, "Unsent reports size limit in bytes")
+	flag.DurationVar(&cfg.OutboxMaxAge, "outbox-age", config.DefaultOutboxMaxAge, "Unsent reports age limit")
+	flag.StringVar(&cfg.AgentID, "id", "", "Agent ID, hostname is used if not set")
+	flag.StringVar(&cfg.StatsDAddress, "statsd", "", "UDP address to receive StatsD metrics on, e.g. :8125")
+	flag.BoolVar(&cfg.LegacyMetrics, "legacy-metrics", config.DefaultLegacyMetrics, "Report legacy runtime.MemStats metrics names")
+	flag.StringVar(&cfg.NetInclude, "net-include", "", "Network interfaces to collect counters of, regexp")
+	flag.StringVar(&cfg.NetExclude, "net-exclude", "", "Network interfaces not to collect counters of, regexp")
+	flag.StringVar(&cfg.DiskInclude, "disk-include", "", "Block devices to collect I/O counters of, regexp")
+	flag.StringVar(&cfg.DiskExclude, "disk-exclude", "", "Block devices not to collect I/O counters of, regexp")
+	flag.StringVar(&cfg.MountInclude, "mount-include", "", "Mount points to collect filesystem usage of, regexp")
+	flag.StringVar(&cfg.MountExclude, "mount-exclude", "", "Mount points not to collect filesystem usage of, regexp")
+	flag.StringVar(&cfg.CgroupRoot, "cgroup-root", config.DefaultCgroupRoot, "Cgroup hierarchy mount point, container metrics are not collected if empty")
+	flag.StringVar(&cfg.ProcRoot, "proc-root", config.DefaultProcRoot, "Procfs mount point")
+	flag.Func("proc", "Monitored process: PID, pidfile:<path>, name:<regexp> or cmdline:<regexp>, may be repeated", func(s string) error {
+		cfg.Processes = append(cfg.Processes, s)
+		return nil
+	})
 }
 
 func main() {
@@ -40,18 +65,68 @@ func main() {
 		return
 	}
 
-	mon, err := client.NewClient(monitor.NewConfig(cfg))
+	newClient := client.NewClient
+	if len(cfg.GRPCAddress) != 0 {
+		newClient = func(cfg *monitor.Config) (monitor.Provider, error) { return grpc.NewClient(cfg) }
+	}
+	monCfg := monitor.NewConfig(cfg)
+	monCfg.Agent = identity.New(cfg.AgentID, buildVersion, cfg.ReportInterval)
+	mon, err := newClient(monCfg)
 	if err != nil {
 		logger.Err(err).Msg("failed to create monitor client")
 		return
 	}
+	if closer, ok := mon.(io.Closer); ok {
+		defer func() {
+			cancel()
+			wg.Wait()
+			if err := closer.Close(); err != nil {
+				logger.Err(err).Msg("failed to close monitor client")
+			}
+		}()
+	}
+
+	outbox, err := agent.NewOutbox(cfg)
+	if err != nil {
+		logger.Err(err).Msg("failed to create outbox")
+		return
+	}
 
 	froze := agent.NewFroze()
-	reporterSvc := agent.NewMetricsReporter(cfg, froze, mon)
-	collector := agent.NewMetricsCollector(cfg, froze, agent.MemStats(), agent.PS())
+	reporterSvc := agent.NewMetricsReporter(cfg, froze, mon, outbox)
+	interfaces, err := agent.NewFilter(cfg.NetInclude, cfg.NetExclude)
+	if err != nil {
+		logger.Err(err).Msg("failed to create network interfaces filter")
+		return
+	}
+	devices, err := agent.NewFilter(cfg.DiskInclude, cfg.DiskExclude)
+	if err != nil {
+		logger.Err(err).Msg("failed to create block devices filter")
+		return
+	}
+	mounts, err := agent.NewFilter(cfg.MountInclude, cfg.MountExclude)
+	if err != nil {
+		logger.Err(err).Msg("failed to create mount points filter")
+		return
+	}
+	collectors := []agent.Collector{agent.RuntimeMetrics(cfg.LegacyMetrics), agent.PS(), agent.Net(interfaces), agent.Disk(devices, mounts)}
+	if cfg.CgroupRoot != "" {
+		collectors = append(collectors, agent.Cgroup(cfg.CgroupRoot))
+	}
+	if len(cfg.Processes) != 0 {
+		processes, err := agent.Processes(cfg.ProcRoot, cfg.Processes...)
+		if err != nil {
+			logger.Err(err).Msg("failed to create processes collector")
+			return
+		}
+		collectors = append(collectors, processes)
+	}
+	collector := agent.NewMetricsCollector(cfg, froze, collectors...)
+	statsdSvc := agent.NewStatsDListener(cfg, froze)
 
 	go reporterSvc.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
 	go collector.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
+	go statsdSvc.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
 
 	srv := &http.Server{Addr: cfg.PProfAddress}
 	defer func() {
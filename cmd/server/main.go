@@ -3,27 +3,45 @@ package main
 import (
 	"context"
 	"flag"
+	"os"
 	"sync"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/handlers"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/app"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/selfmetrics"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/service/alerting"
 	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
 	"github.com/zhupanovdm/go-runtime-monitor/storage"
 	"github.com/zhupanovdm/go-runtime-monitor/storage/file"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/instrumented"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/kv"
 	"github.com/zhupanovdm/go-runtime-monitor/storage/sqldb"
 	"github.com/zhupanovdm/go-runtime-monitor/storage/trivial"
 )
 
 func cli(cfg *config.Config, flag *flag.FlagSet) {
 	flag.StringVar(&cfg.Address, "a", config.DefaultAddress, "Monitor server address")
+	flag.StringVar(&cfg.GRPCAddress, "g", "", "Monitor server gRPC address, can't be used with -crypto-key")
 	flag.BoolVar(&cfg.Restore, "r", config.DefaultRestore, "Monitor will restore metrics at startup")
 	flag.DurationVar(&cfg.StoreInterval, "i", config.DefaultStoreInterval, "Monitor store interval")
 	flag.StringVar(&cfg.StoreFile, "f", config.DefaultStoreFile, "Monitor store file")
+	flag.IntVar(&cfg.StoreRotate, "store-rotate", config.DefaultStoreRotate, "Number of previous dumps kept")
+	flag.BoolVar(&cfg.StoreWAL, "wal", false, "Monitor keeps metrics in write-ahead log instead of dumping them")
+	flag.StringVar(&cfg.StoreSync, "wal-sync", config.DefaultStoreSync, "Write-ahead log fsync policy: always, everysec or no")
+	flag.StringVar(&cfg.StoreKV, "kv", "", "Monitor keeps metrics in embedded key-value store file")
+	flag.DurationVar(&cfg.Retention, "t", config.DefaultRetention, "Monitor metrics history retention")
 	flag.StringVar(&cfg.Key, "k", "", "Packet signing key")
-	flag.StringVar(&cfg.Database, "d", "", "Database connection string")
+	flag.StringVar(&cfg.Database, "d", "", "Database connection string (PostgreSQL DSN or sqlite:///path/to/file.db)")
+	flag.StringVar(&cfg.CryptoKey, "crypto-key", "", "Private key file to decrypt agents payloads")
+	flag.StringVar(&cfg.AlertRules, "alert-rules", "", "Alerting rules file")
+	flag.DurationVar(&cfg.AlertInterval, "alert-interval", config.DefaultAlertInterval, "Alerting rules evaluation interval")
+	flag.StringVar(&cfg.AlertWebhook, "alert-webhook", "", "Alerts webhook URL")
+	flag.IntVar(&cfg.AgentStaleReports, "agent-stale", config.DefaultAgentStaleReports, "Missed reports after which agent is considered stale")
+	flag.DurationVar(&cfg.SelfMetricsInterval, "self-metrics", config.DefaultSelfMetricsInterval, "Monitor self-metrics publish interval")
 }
 
 func main() {
@@ -37,7 +55,21 @@ func main() {
 		return
 	}
 
-	dumper := file.New(cfg)
+	if flag.Arg(0) == migrateCommand {
+		if err := migrate(ctx, cfg, flag.Args()[1:]); err != nil {
+			logger.Err(err).Msg("failed to migrate database")
+			// deploy scripts rely on exit code to detect failed migration
+			os.Exit(1)
+		}
+		return
+	}
+
+	var recorder *selfmetrics.Recorder
+	if cfg.SelfMetricsInterval != 0 {
+		recorder = selfmetrics.NewRecorder()
+	}
+
+	dumper := instrumented.New(file.New(cfg), "dump", recorder)
 	if dumper != nil {
 		defer dumper.Close(ctx)
 		if err := dumper.Init(ctx); err != nil {
@@ -46,7 +78,8 @@ func main() {
 		}
 	}
 
-	st := storage.New(cfg, sqldb.New(sqldb.PGX{}), trivial.New)
+	st := storage.New(cfg, sqldb.New(sqldb.SQLite{}), sqldb.New(sqldb.PGX{}), kv.New, file.NewWAL, trivial.New)
+	st = instrumented.New(st, "metrics", recorder)
 	if st != nil {
 		if err := st.Init(ctx); err != nil {
 			logger.Err(err).Msg("failed to init metrics storage")
@@ -59,11 +92,34 @@ func main() {
 		logger.Err(err).Msg("failed to restore metrics")
 	}
 
+	notifiers := []alerting.Notifier{alerting.LogNotifier()}
+	if len(cfg.AlertWebhook) != 0 {
+		notifiers = append(notifiers, alerting.WebhookNotifier(cfg.AlertWebhook))
+	}
+	alerts, err := alerting.NewAlerting(cfg, mon, notifiers...)
+	if err != nil {
+		logger.Err(err).Msg("failed to init alerting")
+		return
+	}
+
+	agents := registry.NewRegistry(cfg)
+
 	var wg sync.WaitGroup
 	go mon.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
+	go alerts.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
+	go agents.BackgroundTask().With(task.CompletionWait(&wg))(ctx)
+	go selfmetrics.NewPublisher(cfg, recorder, mon).BackgroundTask().With(task.CompletionWait(&wg))(ctx)
 
-	root := handlers.NewMetricsRouter(handlers.NewMetricsHandler(mon), handlers.NewMetricsAPIHandler(cfg, mon))
-	server := monitor.NewServer(cfg, root)
+	root := handlers.NewMetricsRouter(
+		handlers.NewMetricsHandler(mon),
+		handlers.NewMetricsAPIHandler(cfg, mon, agents),
+		handlers.NewAlertsHandler(alerts),
+		handlers.NewAgentsHandler(agents))
+	server, err := monitor.NewServer(cfg, root, handlers.NewMetricsGRPCHandler(cfg, mon, agents), recorder)
+	if err != nil {
+		logger.Err(err).Msg("failed to create server")
+		return
+	}
 	server.Start(ctx)
 
 	logger.Info().Msgf("%v signal received", <-app.TerminationSignal())
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/sqldb"
+)
+
+const migrateCommand = "migrate"
+
+// migrate handles `server migrate [version]` command. Database schema is migrated to the latest version if version is
+// omitted, version 0 reverts all migrations.
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(cfg.Database) == 0 {
+		return errors.New("database is not configured")
+	}
+	version := sqldb.LatestVersion
+	switch len(args) {
+	case 0:
+	case 1:
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid schema version: %s", args[0])
+		}
+		version = v
+	default:
+		return fmt.Errorf("usage: %s [version]", migrateCommand)
+	}
+	return sqldb.Migrate(ctx, cfg, version, sqldb.SQLite{}, sqldb.PGX{})
+}
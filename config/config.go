@@ -9,13 +9,24 @@ import (
 )
 
 const (
-	DefaultAddress        = "localhost:8080"
-	DefaultReportInterval = 10 * time.Second
-	DefaultPollInterval   = 2 * time.Second
-	DefaultRestore        = true
-	DefaultStoreInterval  = 300 * time.Second
-	DefaultStoreFile      = "/tmp/devops-metrics-db.json"
-	DefaultPProfAddress   = ":9000"
+	DefaultAddress             = "localhost:8080"
+	DefaultReportInterval      = 10 * time.Second
+	DefaultPollInterval        = 2 * time.Second
+	DefaultRestore             = true
+	DefaultStoreInterval       = 300 * time.Second
+	DefaultStoreFile           = "/tmp/devops-metrics-db.json"
+	DefaultRetention           = 24 * time.Hour
+	DefaultPProfAddress        = ":9000"
+	DefaultOutboxMaxSize       = 64 << 20
+	DefaultOutboxMaxAge        = 24 * time.Hour
+	DefaultAlertInterval       = 15 * time.Second
+	DefaultAgentStaleReports   = 3
+	DefaultStoreSync           = "always"
+	DefaultStoreRotate         = 3
+	DefaultSelfMetricsInterval = 10 * time.Second
+	DefaultCgroupRoot          = "/sys/fs/cgroup"
+	DefaultProcRoot            = "/proc"
+	DefaultLegacyMetrics       = true
 )
 
 type (
@@ -24,27 +35,121 @@ type (
 		// Address is Monitor server address.
 		Address string `env:"ADDRESS"`
 
+		// GRPCAddress is Monitor server gRPC address. Server won't serve gRPC and agent will report via HTTP if not set.
+		GRPCAddress string `env:"GRPC_ADDRESS"`
+
 		// PollInterval specifies runtime metrics polling period.
 		PollInterval time.Duration `env:"POLL_INTERVAL"`
 
 		// ReportInterval specifies collected metrics send period.
 		ReportInterval time.Duration `env:"REPORT_INTERVAL"`
 
-		// StoreInterval specifies dumping period. Dumps on every update if not set.
+		// StoreInterval specifies dumping period. Dumps on every update if not set, concurrent updates share dumps. Every
+		// dump rewrites whole history within Retention, so StoreWAL suits frequent updates better.
 		StoreInterval time.Duration `env:"STORE_INTERVAL"`
 
-		// StoreFile sets file to dump gathered metrics.
+		// StoreFile sets file to dump gathered metrics. Metrics are not dumped if they are kept in write-ahead log or
+		// key-value store.
 		StoreFile string `env:"STORE_FILE"`
 
+		// StoreRotate is a number of previous dumps kept. Restore falls back to the latest valid one if actual dump is
+		// corrupted.
+		StoreRotate int `env:"STORE_ROTATE"`
+
+		// StoreWAL makes monitor server keep metrics in memory backed by write-ahead log instead of dumping them. Updates
+		// are appended to StoreFile with .wal suffix, StoreFile keeps log snapshot compacted every StoreInterval. Ignored
+		// if Database or StoreKV is set.
+		StoreWAL bool `env:"STORE_WAL"`
+
+		// StoreSync is write-ahead log fsync policy: always, everysec or no. Under everysec policy log is synced in
+		// background, so updates of the last second may be lost on crash.
+		StoreSync string `env:"STORE_SYNC"`
+
+		// StoreKV sets embedded key-value store file. Monitor server keeps metrics in it persisting every update. Ignored
+		// if Database is set.
+		StoreKV string `env:"STORE_KV"`
+
+		// Retention limits metrics history depth. Actual metrics values are kept regardless. History is unlimited if not set.
+		Retention time.Duration `env:"RETENTION"`
+
 		// Restore enables metrics restore from dump on monitor server startup. Disables dumping if not set.
 		Restore bool `env:"RESTORE"`
 
 		// Key is a secret key for signing metrics that will be transmitted to monitor server.
 		Key string `env:"KEY"`
 
-		// Database describes database connection which will be used to persist gathered metrics.
+		// CryptoKey is a path to PEM encoded key file used to encrypt HTTP request bodies. Agent expects monitor server's
+		// public key, monitor server expects corresponding private key. Payloads are transmitted unencrypted if not set.
+		CryptoKey string `env:"CRYPTO_KEY"`
+
+		// Database describes database connection which will be used to persist gathered metrics. PostgreSQL is used
+		// unless data source has sqlite:// scheme, e.g. sqlite:///var/lib/monitor.db.
 		Database string `env:"DATABASE_DSN"`
 
+		// OutboxDir is agent's directory to keep metrics batches that failed to be reported until monitor server
+		// recovers. Failed batches are dropped if not set.
+		OutboxDir string `env:"OUTBOX_DIR"`
+
+		// OutboxMaxSize limits total size of kept batches in bytes. Oldest batches are evicted first. Unlimited if not set.
+		OutboxMaxSize int64 `env:"OUTBOX_MAX_SIZE"`
+
+		// OutboxMaxAge limits age of kept batches. Older batches are discarded. Unlimited if not set.
+		OutboxMaxAge time.Duration `env:"OUTBOX_MAX_AGE"`
+
+		// AlertRules is a path to JSON file with alerting rules. Alerting is disabled if not set.
+		AlertRules string `env:"ALERT_RULES"`
+
+		// AlertInterval specifies alerting rules evaluation period.
+		AlertInterval time.Duration `env:"ALERT_INTERVAL"`
+
+		// AlertWebhook is URL to post fired and resolved alerts to. Alerts are only logged if not set.
+		AlertWebhook string `env:"ALERT_WEBHOOK"`
+
+		// AgentID identifies agent on monitor server across restarts. Agent's hostname is used if not set.
+		AgentID string `env:"AGENT_ID"`
+
+		// AgentStaleReports is a number of missed report intervals after which monitor server considers agent stale.
+		AgentStaleReports int `env:"AGENT_STALE_REPORTS"`
+
+		// LegacyMetrics makes agent also report runtime metrics under legacy names of runtime.MemStats fields, e.g. Alloc
+		// or HeapInuse, along with PollCount and RandomValue. Enabled by default as existing dashboards and alerting rules
+		// rely on them. Legacy metrics are derived from runtime/metrics, so some of them are approximate, e.g. LastGC.
+		LegacyMetrics bool `env:"LEGACY_METRICS"`
+
+		// NetInclude and NetExclude are regular expressions selecting network interfaces agent collects counters of, e.g.
+		// ^eth and ^(lo|veth). All interfaces are selected if not set.
+		NetInclude string `env:"NET_INCLUDE"`
+		NetExclude string `env:"NET_EXCLUDE"`
+
+		// DiskInclude and DiskExclude are regular expressions selecting block devices agent collects I/O counters of.
+		// All devices are selected if not set.
+		DiskInclude string `env:"DISK_INCLUDE"`
+		DiskExclude string `env:"DISK_EXCLUDE"`
+
+		// MountInclude and MountExclude are regular expressions selecting mount points agent collects filesystem usage of.
+		// All physical filesystems are selected if not set.
+		MountInclude string `env:"MOUNT_INCLUDE"`
+		MountExclude string `env:"MOUNT_EXCLUDE"`
+
+		// StatsDAddress is UDP address agent receives metrics pushed by applications over StatsD line protocol on. Agent
+		// doesn't listen if not set.
+		StatsDAddress string `env:"STATSD_ADDRESS"`
+
+		// CgroupRoot is a mount point of cgroup hierarchy agent collects container resource usage and limits from.
+		// Container metrics are not collected if not set.
+		CgroupRoot string `env:"CGROUP_ROOT"`
+
+		// ProcRoot is a mount point of procfs agent reads monitored processes resource usage from.
+		ProcRoot string `env:"PROC_ROOT"`
+
+		// Processes lists processes monitored by agent: PID, pidfile:<path>, name:<regexp> matched against process name or
+		// cmdline:<regexp> matched against command line. Processes are not monitored if not set.
+		Processes []string `env:"PROCESSES" envSeparator:","`
+
+		// SelfMetricsInterval specifies period of publishing monitor server's own metrics, e.g. storage latency and HTTP
+		// requests rate. Self-metrics are not collected if not set.
+		SelfMetricsInterval time.Duration `env:"SELF_METRICS_INTERVAL"`
+
 		// PProfAddress is address for pprof utility
 		PProfAddress string
 	}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
+)
+
+const agentsHandlerName = "Agents REST API handler"
+
+type AgentsHandler struct {
+	agents registry.Registry
+}
+
+// Agents godoc
+// @Tags Agents
+// @Summary Lists reporting agents
+// @Description Returns agents known to monitor server. Agent is stale if it has not reported for several report intervals
+// @ID agentsList
+// @Param stale query boolean false "stale agents filter"
+// @Produce json
+// @Success 200 {array} registry.Agent "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /agents [get]
+func (h *AgentsHandler) Agents(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	ctx, _ := logging.SetIfAbsentCID(req.Context(), logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(agentsHandlerName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Agents]")
+
+	var filter *bool
+	if s := req.URL.Query().Get("stale"); len(s) != 0 {
+		stale, err := strconv.ParseBool(s)
+		if err != nil {
+			logger.Err(err).Msg("failed to process request query")
+			httplib.Error(resp, http.StatusBadRequest, err)
+			return
+		}
+		filter = &stale
+	}
+
+	agents := h.agents.Agents(logging.SetLogger(ctx, logger))
+	if filter != nil {
+		filtered := make([]registry.Agent, 0, len(agents))
+		for _, a := range agents {
+			if a.Stale == *filter {
+				filtered = append(filtered, a)
+			}
+		}
+		agents = filtered
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(agents); err != nil {
+		logger.Err(err).Msg("failed to encode response body")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+}
+
+// assignInstance places metrics reported by identified agent to the agent's namespace. Identified agent is not allowed
+// to report metrics of another instance. Metrics reported anonymously are left as is.
+func assignInstance(info *agent.Info, list ...*metric.Metric) error {
+	if info == nil {
+		return nil
+	}
+	for _, mtr := range list {
+		switch instance := mtr.Labels.Instance(); instance {
+		case "":
+			mtr.Labels = mtr.Labels.WithInstance(info.ID)
+		case info.ID:
+		default:
+			return fmt.Errorf("agent %s is not allowed to report metrics of instance %s", info.ID, instance)
+		}
+	}
+	return nil
+}
+
+func NewAgentsHandler(agents registry.Registry) *AgentsHandler {
+	return &AgentsHandler{agents: agents}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
+	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/trivial"
+)
+
+func TestAgentsHandler(t *testing.T) {
+	cfg := &config.Config{}
+	svc := &monitorServiceStub{}
+	agents := registry.NewRegistry(cfg)
+	ts := httptest.NewServer(NewMetricsRouter(NewMetricsHandler(svc), NewMetricsAPIHandler(cfg, svc, agents), nil, NewAgentsHandler(agents)))
+	defer ts.Close()
+
+	info := &agent.Info{ID: "foo", Hostname: "foo.local", Version: "1.0", StartedAt: time.Now(), ReportInterval: time.Second}
+	report := func(header http.Header, body string) int {
+		req, err := http.NewRequest("POST", ts.URL+"/updates", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, report(model.NewAgentHeader(info), `[{"id":"foo","type":"gauge","value":1},{"id":"bar","type":"gauge","value":2}]`))
+	assert.Equal(t, http.StatusOK, report(http.Header{}, `[{"id":"foo","type":"gauge","value":1}]`), "anonymous report")
+
+	malformed := model.NewAgentHeader(info)
+	malformed.Set(model.AgentReportIntervalHeader, "often")
+	assert.Equal(t, http.StatusBadRequest, report(malformed, `[]`))
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       int
+	}{
+		{
+			name:       "Get all agents",
+			url:        "/agents",
+			wantStatus: http.StatusOK,
+			want:       1,
+		},
+		{
+			name:       "Get alive agents",
+			url:        "/agents?stale=false",
+			wantStatus: http.StatusOK,
+			want:       1,
+		},
+		{
+			name:       "Get stale agents",
+			url:        "/agents?stale=true",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Malformed filter",
+			url:        "/agents?stale=maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result, _ := testRequest(t, ts, "GET", tt.url, nil)
+			if assert.Equal(t, tt.wantStatus, status) && status == http.StatusOK {
+				var list []registry.Agent
+				require.NoError(t, json.Unmarshal(result, &list))
+				require.Len(t, list, tt.want)
+				if tt.want != 0 {
+					assert.Equal(t, "foo", list[0].ID)
+					assert.Equal(t, uint64(1), list[0].Reports)
+					assert.Equal(t, uint64(2), list[0].Metrics)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsApiHandlerInstances(t *testing.T) {
+	cfg := &config.Config{}
+	svc := monitor.NewMonitor(cfg, nil, trivial.New(cfg))
+	ts := httptest.NewServer(NewMetricsRouter(NewMetricsHandler(svc), NewMetricsAPIHandler(cfg, svc, registry.NewRegistry(cfg)), nil, nil))
+	defer ts.Close()
+
+	report := func(id string, body string, wantStatus int) {
+		req, err := http.NewRequest("POST", ts.URL+"/updates", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if len(id) != 0 {
+			req.Header = model.NewAgentHeader(&agent.Info{ID: id})
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+	}
+	report("foo", `[{"id":"Alloc","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":1}]`, http.StatusOK)
+	report("bar", `[{"id":"Alloc","type":"gauge","value":2},{"id":"PollCount","type":"counter","delta":5}]`, http.StatusOK)
+	report("foo", `[{"id":"PollCount","type":"counter","delta":1}]`, http.StatusOK)
+	report("", `[{"id":"Alloc","type":"gauge","value":3}]`, http.StatusOK)
+	report("", `[{"id":"Alloc","type":"gauge","value":4,"instance":"baz"}]`, http.StatusOK)
+	report("bar", `[{"id":"PollCount","type":"counter","delta":1,"instance":"bar"}]`, http.StatusOK)
+	report("bar", `[{"id":"PollCount","type":"counter","delta":100,"instance":"foo"}]`, http.StatusBadRequest)
+	report("bar", `[{"id":"Alloc","type":"gauge","value":100,"instance":"baz"}]`, http.StatusBadRequest)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Gauge of instance",
+			method:     "POST",
+			url:        "/value",
+			body:       `{"id":"Alloc","type":"gauge","instance":"foo"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"id":"Alloc","type":"gauge","instance":"foo","value":1}`,
+		},
+		{
+			name:       "Counter of instance",
+			method:     "POST",
+			url:        "/value",
+			body:       `{"id":"PollCount","type":"counter","instance":"bar"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"id":"PollCount","type":"counter","instance":"bar","delta":6}`,
+		},
+		{
+			name:       "Anonymous gauge",
+			method:     "POST",
+			url:        "/value",
+			body:       `{"id":"Alloc","type":"gauge"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"id":"Alloc","type":"gauge","value":3}`,
+		},
+		{
+			name:       "Explicit instance",
+			method:     "POST",
+			url:        "/value",
+			body:       `{"id":"Alloc","type":"gauge","instance":"baz"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"id":"Alloc","type":"gauge","instance":"baz","value":4}`,
+		},
+		{
+			name:       "Reserved label",
+			method:     "POST",
+			url:        "/value",
+			body:       `{"id":"Alloc","type":"gauge","labels":{"instance":"foo"}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Counter of instance v1",
+			method:     "GET",
+			url:        "/value/counter/PollCount?instance=foo",
+			wantStatus: http.StatusOK,
+			want:       "2",
+		},
+		{
+			name:       "Ambiguous instance v1",
+			method:     "GET",
+			url:        "/value/counter/PollCount?instance=foo&labels=%7Binstance%3D%22bar%22%7D",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result, _ := testRequest(t, ts, tt.method, tt.url, []byte(tt.body))
+			if assert.Equal(t, tt.wantStatus, status) && len(tt.want) != 0 {
+				assert.JSONEq(t, tt.want, string(result))
+			}
+		})
+	}
+}
+
+func TestMetricsGRPCHandlerAgentIdentity(t *testing.T) {
+	cfg := &config.Config{}
+	agents := registry.NewRegistry(cfg)
+	client := newGRPCTestClient(t, NewMetricsGRPCHandler(cfg, &monitorServiceStub{}, agents))
+
+	info := &agent.Info{ID: "foo", StartedAt: time.Now()}
+	kv := make([]string, 0)
+	for name, values := range model.NewAgentHeader(info) {
+		kv = append(kv, name, values[0])
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), kv...)
+
+	_, err := client.UpdateBulk(ctx, &pb.UpdateBulkRequest{})
+	require.NoError(t, err)
+
+	foreign := model.NewFromCanonical(metric.NewGaugeMetric("Alloc", 1).WithLabels(metric.Labels{}.WithInstance("bar")))
+	_, err = client.UpdateBulk(ctx, &pb.UpdateBulkRequest{Metrics: []*pb.Metric{pb.NewMetric(foreign)}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "agent can't report metrics of another instance")
+
+	list := agents.Agents(ctx)
+	require.Len(t, list, 1)
+	assert.Equal(t, "foo", list[0].ID)
+	assert.Equal(t, uint64(1), list[0].Reports)
+}
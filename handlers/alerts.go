@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/service/alerting"
+)
+
+const alertsHandlerName = "Alerts REST API handler"
+
+type AlertsHandler struct {
+	alerting alerting.Alerting
+}
+
+// Alerts godoc
+// @Tags Alerts
+// @Summary Lists alerts
+// @Description Returns pending, firing and recently resolved alerts
+// @ID alertsList
+// @Param state query string false "alert state filter" Enums(pending, firing, resolved)
+// @Produce json
+// @Success 200 {array} alerting.Alert "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /alerts [get]
+func (h *AlertsHandler) Alerts(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	ctx, _ := logging.SetIfAbsentCID(req.Context(), logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(alertsHandlerName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Alerts]")
+
+	state := alerting.State(req.URL.Query().Get("state"))
+	switch state {
+	case "", alerting.StatePending, alerting.StateFiring, alerting.StateResolved:
+	default:
+		err := fmt.Errorf("unknown alert state: %v", state)
+		logger.Err(err).Msg("validation failed")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx = logging.SetLogger(ctx, logger)
+	alerts := h.alerting.Alerts(ctx, state)
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(alerts); err != nil {
+		logger.Err(err).Msg("failed to encode response body")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+}
+
+func NewAlertsHandler(service alerting.Alerting) *AlertsHandler {
+	return &AlertsHandler{alerting: service}
+}
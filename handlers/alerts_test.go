@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/service/alerting"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
+)
+
+var _ alerting.Alerting = (*alertingServiceStub)(nil)
+
+type alertingServiceStub struct{}
+
+func (s *alertingServiceStub) BackgroundTask() task.Task {
+	return task.VoidTask
+}
+
+func (s *alertingServiceStub) Name() string {
+	return "Stub alerting service"
+}
+
+func (s *alertingServiceStub) Evaluate(context.Context) {}
+
+func (s *alertingServiceStub) Alerts(_ context.Context, state alerting.State) []alerting.Alert {
+	alerts := make([]alerting.Alert, 0)
+	if state == "" || state == alerting.StatePending {
+		alerts = append(alerts, alerting.Alert{
+			Rule:     "heap",
+			Expr:     "HeapAlloc > 500MB for 2m",
+			MetricID: "HeapAlloc",
+			Labels:   metric.Labels{"host": "foo"},
+			State:    alerting.StatePending,
+			Value:    1 << 30,
+			ActiveAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return alerts
+}
+
+func TestAlertsHandler(t *testing.T) {
+	svc := &monitorServiceStub{}
+	ts := httptest.NewServer(NewMetricsRouter(NewMetricsHandler(svc), NewMetricsAPIHandler(&config.Config{}, svc, registry.NewRegistry(&config.Config{})), NewAlertsHandler(&alertingServiceStub{}), nil))
+	defer ts.Close()
+
+	pending := `[{"rule":"heap","expr":"HeapAlloc > 500MB for 2m","metric_id":"HeapAlloc","labels":{"host":"foo"},"state":"pending","value":1073741824,"active_at":"2022-01-01T00:00:00Z"}]`
+
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+		want            string
+	}{
+		{
+			name:            "Get all alerts",
+			url:             "/alerts",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            pending,
+		},
+		{
+			name:            "Get pending alerts",
+			url:             "/alerts?state=pending",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            pending,
+		},
+		{
+			name:            "Get firing alerts",
+			url:             "/alerts?state=firing",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            `[]`,
+		},
+		{
+			name:       "Get alerts of unknown state",
+			url:        "/alerts?state=silenced",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result, hdr := testRequest(t, ts, "GET", tt.url, nil)
+			if assert.Equal(t, tt.wantStatus, status) {
+				if len(tt.want) != 0 {
+					assert.JSONEq(t, tt.want, string(result))
+				}
+				if len(tt.wantContentType) != 0 {
+					assert.Contains(t, hdr.Get("Content-Type"), tt.wantContentType)
+				}
+			}
+		})
+	}
+}
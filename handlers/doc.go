@@ -17,3 +17,9 @@ package handlers
 
 // @Tag.name Diag
 // @Tag.description Diagnostics API
+
+// @Tag.name Alerts
+// @Tag.description Alerting API
+
+// @Tag.name Agents
+// @Tag.description Reporting agents API
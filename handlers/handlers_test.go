@@ -14,12 +14,13 @@ import (
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
 	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
 )
 
 const notFoundSample = "not-found"
 
 func NewServer(cfg *config.Config, svc monitor.Monitor) *httptest.Server {
-	return httptest.NewServer(NewMetricsRouter(NewMetricsHandler(svc), NewMetricsAPIHandler(cfg, svc)))
+	return httptest.NewServer(NewMetricsRouter(NewMetricsHandler(svc), NewMetricsAPIHandler(cfg, svc, registry.NewRegistry(cfg)), nil, nil))
 }
 
 var _ monitor.Monitor = (*monitorServiceStub)(nil)
@@ -34,7 +35,7 @@ func (s *monitorServiceStub) BackgroundTask() task.Task {
 	return task.VoidTask
 }
 
-func (s *monitorServiceStub) GetAll(context.Context) (metric.List, error) {
+func (s *monitorServiceStub) GetAll(context.Context, metric.Labels) (metric.List, error) {
 	return make([]*metric.Metric, 0), nil
 }
 
@@ -46,18 +47,26 @@ func (s *monitorServiceStub) Name() string {
 	return "Stub monitor service"
 }
 
-func (s *monitorServiceStub) Get(_ context.Context, id string, typ metric.Type) (*metric.Metric, error) {
+func (s *monitorServiceStub) Get(_ context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
 	if id == notFoundSample {
 		return nil, nil
 	}
 
 	v, _ := typ.New()
 	return &metric.Metric{
-		ID:    id,
-		Value: v,
+		ID:     id,
+		Value:  v,
+		Labels: labels,
 	}, nil
 }
 
+func (s *monitorServiceStub) Query(_ context.Context, query *metric.Query) (metric.Series, error) {
+	if query.ID == notFoundSample {
+		return metric.Series{}, nil
+	}
+	return metric.Series{{Timestamp: query.From, Value: 1}}, nil
+}
+
 func (s *monitorServiceStub) Update(context.Context, *metric.Metric) error {
 	return nil
 }
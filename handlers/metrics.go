@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 
 	"github.com/go-chi/chi/v5"
@@ -28,6 +29,8 @@ type MetricsHandler struct {
 // @Param type path string true "metric type" Enums(gauge, counter)
 // @Param id path string true "metric id"
 // @Param value path number true "metric value"
+// @Param labels query string false "metric labels, e.g. {cpu=\"1\"}"
+// @Param instance query string false "metric source instance"
 // @Success 200 {string} string "OK"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
@@ -56,9 +59,17 @@ func (h *MetricsHandler) Update(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	labels, err := decodeLabels(req.URL.Query())
+	if err != nil {
+		logger.Err(err).Msg("malformed metric labels")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
 	mtr := &metric.Metric{
-		ID:    chi.URLParam(req, "id"),
-		Value: value,
+		ID:     chi.URLParam(req, "id"),
+		Value:  value,
+		Labels: labels,
 	}
 	logger.UpdateContext(logging.LogCtxFrom(mtr))
 
@@ -76,6 +87,8 @@ func (h *MetricsHandler) Update(resp http.ResponseWriter, req *http.Request) {
 // @ID v1metricsValue
 // @Param type path string true "metric type" Enums(gauge, counter)
 // @Param id path string true "metric id"
+// @Param labels query string false "metric labels, e.g. {cpu=\"1\"}"
+// @Param instance query string false "metric source instance"
 // @Produce plain
 // @Success 200 {number} number "OK"
 // @Failure 400 {string} string "Bad request"
@@ -101,8 +114,16 @@ func (h *MetricsHandler) Value(resp http.ResponseWriter, req *http.Request) {
 	id := chi.URLParam(req, "id")
 	logger.UpdateContext(logging.LogCtxKeyStr(logging.MetricIDKey, id))
 
+	labels, err := decodeLabels(req.URL.Query())
+	if err != nil {
+		logger.Err(err).Msg("malformed metric labels")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+	logger.UpdateContext(logging.LogCtxFrom(labels))
+
 	ctx = logging.SetLogger(ctx, logger)
-	mtr, err := h.monitor.Get(ctx, id, typ)
+	mtr, err := h.monitor.Get(ctx, id, typ, labels)
 	if err != nil {
 		logger.Err(err).Msg("metric read failed")
 		httplib.Error(resp, http.StatusInternalServerError, nil)
@@ -111,7 +132,7 @@ func (h *MetricsHandler) Value(resp http.ResponseWriter, req *http.Request) {
 
 	if mtr == nil {
 		logger.Warn().Msg("requested metric not found")
-		httplib.Error(resp, http.StatusNotFound, fmt.Errorf("%s (%v) metric not found", id, typ))
+		httplib.Error(resp, http.StatusNotFound, fmt.Errorf("%s%v (%v) metric not found", id, labels, typ))
 		return
 	}
 
@@ -126,8 +147,11 @@ func (h *MetricsHandler) Value(resp http.ResponseWriter, req *http.Request) {
 // @Summary Queries all metrics values
 // @Description gets all metrics values
 // @ID v1metricsGetAll
+// @Param labels query string false "labels filter, e.g. {cpu=\"1\"}"
+// @Param instance query string false "metric source instance filter"
 // @Produce html
 // @Success 200 {string} string "OK"
+// @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router / [get]
 func (h *MetricsHandler) GetAll(resp http.ResponseWriter, req *http.Request) {
@@ -137,7 +161,14 @@ func (h *MetricsHandler) GetAll(resp http.ResponseWriter, req *http.Request) {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerName), logging.WithCID(ctx))
 	logger.Info().Msg("handling [GetAll]")
 
-	list, err := h.monitor.GetAll(ctx)
+	filter, err := decodeLabels(req.URL.Query())
+	if err != nil {
+		logger.Err(err).Msg("malformed labels filter")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	list, err := h.monitor.GetAll(ctx, filter)
 	if err != nil {
 		logger.Err(err).Msg("failed to query metrics")
 		httplib.Error(resp, http.StatusInternalServerError, nil)
@@ -147,12 +178,69 @@ func (h *MetricsHandler) GetAll(resp http.ResponseWriter, req *http.Request) {
 	sort.Sort(metric.ByString(list))
 
 	resp.Header().Add("Content-Type", "text/html")
-	if err := view.Index.Execute(resp, list); err != nil {
+	if err := view.Index.Execute(resp, view.NewIndex(list)); err != nil {
+		logger.Err(err).Msg("failed to write response body")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+	}
+}
+
+// Exposition godoc
+// @Tags v1
+// @Summary Exposes all metrics for Prometheus
+// @Description gets all metrics values in Prometheus text exposition format
+// @ID v1metricsExposition
+// @Param labels query string false "labels filter, e.g. {cpu=\"1\"}"
+// @Param instance query string false "metric source instance filter"
+// @Produce plain
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /metrics [get]
+func (h *MetricsHandler) Exposition(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	ctx, _ := logging.SetIfAbsentCID(req.Context(), logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Exposition]")
+
+	filter, err := decodeLabels(req.URL.Query())
+	if err != nil {
+		logger.Err(err).Msg("malformed labels filter")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	list, err := h.monitor.GetAll(ctx, filter)
+	if err != nil {
+		logger.Err(err).Msg("failed to query metrics")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+	logger.Trace().Msgf("got %d records", len(list))
+
+	resp.Header().Set("Content-Type", view.PrometheusContentType)
+	if err := view.Prometheus(resp, list); err != nil {
 		logger.Err(err).Msg("failed to write response body")
 		httplib.Error(resp, http.StatusInternalServerError, nil)
 	}
 }
 
+// decodeLabels reads labels passed with "labels" query parameter. Metric source instance may be passed separately with
+// "instance" query parameter.
+func decodeLabels(values url.Values) (metric.Labels, error) {
+	labels, err := metric.ParseLabels(values.Get("labels"))
+	if err != nil {
+		return nil, fmt.Errorf("decoder: %w", err)
+	}
+	if instance := values.Get(metric.InstanceLabel); len(instance) != 0 {
+		if other := labels.Instance(); len(other) != 0 && other != instance {
+			return nil, fmt.Errorf("decoder: ambiguous instance: %s or %s", other, instance)
+		}
+		labels = labels.WithInstance(instance)
+	}
+	return labels, nil
+}
+
 func NewMetricsHandler(service monitor.Monitor) *MetricsHandler {
 	return &MetricsHandler{service}
 }
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
@@ -13,12 +16,14 @@ import (
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
 	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
 )
 
 const metricsHandlerAPIName = "Metrics REST API handler"
 
 type MetricsAPIHandler struct {
 	monitor monitor.Monitor
+	agents  registry.Registry
 	key     string
 }
 
@@ -40,6 +45,13 @@ func (h *MetricsAPIHandler) Update(resp http.ResponseWriter, req *http.Request)
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerAPIName), logging.WithCID(ctx))
 	logger.Info().Msg("handling [Update]")
 
+	info, err := model.AgentFromHeader(req.Header)
+	if err != nil {
+		logger.Err(err).Msg("failed to process agent identity")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
 	body, err := h.decodeRequestBody(req.Body)
 	if err != nil {
 		logger.Err(err).Msg("failed to process request body")
@@ -47,13 +59,18 @@ func (h *MetricsAPIHandler) Update(resp http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	if err = body.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckHash(h.key)); err != nil {
+	if err = body.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckLabels, model.CheckHash(h.key)); err != nil {
 		logger.Err(err).Msg("validation failed")
 		httplib.Error(resp, http.StatusBadRequest, err)
 		return
 	}
 
 	mtr := body.ToCanonical()
+	if err := assignInstance(info, mtr); err != nil {
+		logger.Err(err).Msg("instance validation failed")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
 	logger.UpdateContext(logging.LogCtxFrom(mtr))
 	ctx = logging.SetLogger(ctx, logger)
 	if err := h.monitor.Update(ctx, mtr); err != nil {
@@ -61,6 +78,9 @@ func (h *MetricsAPIHandler) Update(resp http.ResponseWriter, req *http.Request)
 		httplib.Error(resp, http.StatusInternalServerError, nil)
 		return
 	}
+	if info != nil {
+		h.agents.Report(ctx, info, 1)
+	}
 }
 
 // UpdateBulk godoc
@@ -81,6 +101,13 @@ func (h *MetricsAPIHandler) UpdateBulk(resp http.ResponseWriter, req *http.Reque
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerAPIName), logging.WithCID(ctx))
 	logger.Info().Msg("handling [Updates]")
 
+	info, err := model.AgentFromHeader(req.Header)
+	if err != nil {
+		logger.Err(err).Msg("failed to process agent identity")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
 	var metrics []model.Metrics
 	if err := json.NewDecoder(req.Body).Decode(&metrics); err != nil {
 		logger.Err(err).Msg("failed to process request body")
@@ -90,16 +117,25 @@ func (h *MetricsAPIHandler) UpdateBulk(resp http.ResponseWriter, req *http.Reque
 
 	list := make(metric.List, 0, len(metrics))
 	for _, m := range metrics {
-		if err := m.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckHash(h.key)); err != nil {
+		if err := m.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckLabels, model.CheckHash(h.key)); err != nil {
 			logger.Err(err).Msg("validation failed")
 			httplib.Error(resp, http.StatusBadRequest, err)
 			return
 		}
 		list = append(list, m.ToCanonical())
 	}
+	if err := assignInstance(info, list...); err != nil {
+		logger.Err(err).Msg("instance validation failed")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
 	if err := h.monitor.UpdateBulk(ctx, list); err != nil {
 		logger.Err(err).Msg("failed to batch update metrics")
 		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+	if info != nil {
+		h.agents.Report(ctx, info, len(list))
 	}
 }
 
@@ -129,7 +165,7 @@ func (h *MetricsAPIHandler) Value(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err = body.Validate(model.CheckID, model.CheckType); err != nil {
+	if err = body.Validate(model.CheckID, model.CheckType, model.CheckLabels); err != nil {
 		logger.Err(err).Msg("validation failed")
 		httplib.Error(resp, http.StatusBadRequest, err)
 		return
@@ -137,9 +173,9 @@ func (h *MetricsAPIHandler) Value(resp http.ResponseWriter, req *http.Request) {
 
 	mtr := body.ToCanonical()
 
-	logger.UpdateContext(logging.LogCtxFrom(logging.LogCtxKeyStr(logging.MetricIDKey, mtr.ID), mtr.Type()))
+	logger.UpdateContext(logging.LogCtxFrom(logging.LogCtxKeyStr(logging.MetricIDKey, mtr.ID), mtr.Type(), mtr.Labels))
 	ctx = logging.SetLogger(ctx, logger)
-	if mtr, err = h.monitor.Get(ctx, mtr.ID, mtr.Type()); err != nil {
+	if mtr, err = h.monitor.Get(ctx, mtr.ID, mtr.Type(), mtr.Labels); err != nil {
 		logger.Err(err).Msg("metric read failed")
 		httplib.Error(resp, http.StatusInternalServerError, nil)
 		return
@@ -168,6 +204,60 @@ func (h *MetricsAPIHandler) Value(resp http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// Query godoc
+// @Tags v2
+// @Summary Queries metric history
+// @Description Returns metric values within time range. Values are aggregated by step intervals if step is specified
+// @ID v2metricsQuery
+// @Param id query string true "metric id"
+// @Param type query string true "metric type" Enums(gauge, counter)
+// @Param labels query string false "metric labels, e.g. {cpu=\"1\"}"
+// @Param instance query string false "metric source instance"
+// @Param from query string false "range start (RFC3339 or unix seconds)"
+// @Param to query string false "range end (RFC3339 or unix seconds), now if not set"
+// @Param step query string false "aggregation step duration, e.g. 30s"
+// @Param agg query string false "aggregation function" Enums(avg, min, max, last, sum) default(avg)
+// @Produce json
+// @Success 200 {object} model.Series "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /query [get]
+func (h *MetricsAPIHandler) Query(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	ctx, _ := logging.SetIfAbsentCID(req.Context(), logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerAPIName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Query]")
+
+	query, err := h.decodeQuery(req.URL.Query())
+	if err != nil {
+		logger.Err(err).Msg("failed to process request query")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+	if err = query.Validate(); err != nil {
+		logger.Err(err).Msg("validation failed")
+		httplib.Error(resp, http.StatusBadRequest, err)
+		return
+	}
+
+	logger.UpdateContext(logging.LogCtxFrom(query))
+	ctx = logging.SetLogger(ctx, logger)
+	series, err := h.monitor.Query(ctx, query)
+	if err != nil {
+		logger.Err(err).Msg("metric history read failed")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(resp).Encode(model.NewSeries(query, series)); err != nil {
+		logger.Err(err).Msg("failed to encode response body")
+		httplib.Error(resp, http.StatusInternalServerError, nil)
+		return
+	}
+}
+
 // Ping godoc
 // @Tags Diag
 // @Summary Service diagnostic method
@@ -197,9 +287,50 @@ func (h *MetricsAPIHandler) decodeRequestBody(body io.Reader) (*model.Metrics, e
 	return metrics, nil
 }
 
-func NewMetricsAPIHandler(cfg *config.Config, service monitor.Monitor) *MetricsAPIHandler {
+func (h *MetricsAPIHandler) decodeQuery(values url.Values) (*metric.Query, error) {
+	query := &metric.Query{
+		ID:          values.Get("id"),
+		Type:        metric.Type(values.Get("type")),
+		To:          time.Now(),
+		Aggregation: metric.AggregationAvg,
+	}
+
+	var err error
+	if query.Labels, err = decodeLabels(values); err != nil {
+		return nil, err
+	}
+	if from := values.Get("from"); len(from) != 0 {
+		if query.From, err = parseTime(from); err != nil {
+			return nil, fmt.Errorf("decoder: invalid range start: %w", err)
+		}
+	}
+	if to := values.Get("to"); len(to) != 0 {
+		if query.To, err = parseTime(to); err != nil {
+			return nil, fmt.Errorf("decoder: invalid range end: %w", err)
+		}
+	}
+	if step := values.Get("step"); len(step) != 0 {
+		if query.Step, err = time.ParseDuration(step); err != nil {
+			return nil, fmt.Errorf("decoder: invalid step: %w", err)
+		}
+	}
+	if agg := values.Get("agg"); len(agg) != 0 {
+		query.Aggregation = metric.Aggregation(agg)
+	}
+	return query, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func NewMetricsAPIHandler(cfg *config.Config, service monitor.Monitor, agents registry.Registry) *MetricsAPIHandler {
 	return &MetricsAPIHandler{
 		monitor: service,
+		agents:  agents,
 		key:     cfg.Key,
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -72,6 +73,72 @@ func TestMetricsApiHandler(t *testing.T) {
 			wantContentType: "application/json",
 			want:            `{"id":"foo","type":"counter","delta":0}`,
 		},
+		{
+			name:       "Update histogram",
+			method:     "POST",
+			url:        "/update",
+			body:       `{"id":"foo","type":"histogram","histogram":{"bounds":[1],"counts":[1,2],"sum":7.5,"count":3}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "Get histogram",
+			method:          "POST",
+			url:             "/value",
+			body:            `{"id":"foo","type":"histogram"}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            `{"id":"foo","type":"histogram","histogram":{"bounds":[],"counts":[0],"sum":0,"count":0}}`,
+		},
+		{
+			name:       "Update with inconsistent histogram",
+			method:     "POST",
+			url:        "/update",
+			body:       `{"id":"foo","type":"histogram","histogram":{"bounds":[1],"counts":[1],"sum":7.5,"count":1}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Update histogram without value",
+			method:     "POST",
+			url:        "/update",
+			body:       `{"id":"foo","type":"histogram"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Update labeled gauge",
+			method:     "POST",
+			url:        "/update",
+			body:       `{"id":"foo","type":"gauge","labels":{"cpu":"1"},"value":1.23}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "Get labeled gauge",
+			method:          "POST",
+			url:             "/value",
+			body:            `{"id":"foo","type":"gauge","labels":{"cpu":"1"}}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            `{"id":"foo","type":"gauge","labels":{"cpu":"1"},"value":0}`,
+		},
+		{
+			name:       "Update with invalid label name",
+			method:     "POST",
+			url:        "/update",
+			body:       `{"id":"foo","type":"gauge","labels":{"1cpu":"1"},"value":1.23}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "Query labeled history",
+			method:          "GET",
+			url:             "/query?id=foo&type=gauge&labels=%7Bcpu%3D%221%22%7D&from=0",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:       "Query malformed labels",
+			method:     "GET",
+			url:        "/query?id=foo&type=gauge&labels=cpu",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Ping service",
 			method:     "GET",
@@ -141,6 +208,52 @@ func TestMetricsApiHandler(t *testing.T) {
 			body:       `{"id":"foo","type":"gauge"}`,
 			wantStatus: http.StatusMethodNotAllowed,
 		},
+		{
+			name:            "Query history",
+			method:          "GET",
+			url:             "/query?id=foo&type=gauge&from=0&to=60&step=10s&agg=max",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            `{"id":"foo","type":"gauge","points":[{"timestamp":"` + time.Unix(0, 0).Format(time.RFC3339Nano) + `","value":1}]}`,
+		},
+		{
+			name:            "Query empty history",
+			method:          "GET",
+			url:             "/query?id=not-found&type=counter&from=2022-03-01T12:00:00Z",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			want:            `{"id":"not-found","type":"counter","points":[]}`,
+		},
+		{
+			name:       "Query without id",
+			method:     "GET",
+			url:        "/query?type=gauge",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Query unknown metric type",
+			method:     "GET",
+			url:        "/query?id=foo&type=baz",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Query malformed range",
+			method:     "GET",
+			url:        "/query?id=foo&type=gauge&from=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Query inverted range",
+			method:     "GET",
+			url:        "/query?id=foo&type=gauge&from=60&to=0",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Query unknown aggregation",
+			method:     "GET",
+			url:        "/query?id=foo&type=gauge&step=1m&agg=median",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Ignore hash",
 			method:     "POST",
@@ -173,6 +286,7 @@ func TestMetricsApiHandlerWithVSignatureVerification(t *testing.T) {
 	var value = 0.99
 	var zeroGauge float64 = 0
 	var delta int64 = 99
+	var histogram = model.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 7.5, Count: 3}
 
 	zeroGaugeBody := &model.Metrics{
 		ID:    "foo",
@@ -181,6 +295,15 @@ func TestMetricsApiHandlerWithVSignatureVerification(t *testing.T) {
 	}
 	require.NoError(t, zeroGaugeBody.Sign(known), "failed to sign sample")
 
+	tamperedLabelsBody := model.Metrics{
+		ID:     "foo",
+		MType:  string(metric.GaugeType),
+		Labels: map[string]string{"cpu": "1"},
+		Value:  &value,
+	}
+	require.NoError(t, tamperedLabelsBody.Sign(known), "failed to sign sample")
+	tamperedLabelsBody.Labels = map[string]string{"cpu": "2"}
+
 	tests := []struct {
 		name        string
 		method      string
@@ -214,6 +337,38 @@ func TestMetricsApiHandlerWithVSignatureVerification(t *testing.T) {
 			signWithKey: known,
 			wantStatus:  http.StatusOK,
 		},
+		{
+			name:   "Update histogram with signed value",
+			method: "POST",
+			url:    "/update",
+			body: model.Metrics{
+				ID:        "baz",
+				MType:     string(metric.HistogramType),
+				Histogram: &histogram,
+			},
+			signWithKey: known,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:   "Update labeled gauge with signed value",
+			method: "POST",
+			url:    "/update",
+			body: model.Metrics{
+				ID:     "foo",
+				MType:  string(metric.GaugeType),
+				Labels: map[string]string{"cpu": "1"},
+				Value:  &value,
+			},
+			signWithKey: known,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "Update gauge with tampered labels",
+			method:     "POST",
+			url:        "/update",
+			body:       tamperedLabelsBody,
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "Unsigned",
 			method: "POST",
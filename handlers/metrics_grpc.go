@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
+	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
+)
+
+const metricsHandlerGRPCName = "Metrics gRPC handler"
+
+var _ pb.MonitorServer = (*MetricsGRPCHandler)(nil)
+
+type MetricsGRPCHandler struct {
+	pb.UnimplementedMonitorServer
+	monitor monitor.Monitor
+	agents  registry.Registry
+	key     string
+}
+
+func (h *MetricsGRPCHandler) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerGRPCName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Update]")
+
+	info, err := agentFromMetadata(ctx)
+	if err != nil {
+		logger.Err(err).Msg("failed to process agent identity")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	body := req.Metric.ToModel()
+	if body == nil {
+		logger.Error().Msg("metric is not specified")
+		return nil, status.Error(codes.InvalidArgument, "metric is not specified")
+	}
+	if err := body.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckLabels, model.CheckHash(h.key)); err != nil {
+		logger.Err(err).Msg("validation failed")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	mtr := body.ToCanonical()
+	if err := assignInstance(info, mtr); err != nil {
+		logger.Err(err).Msg("instance validation failed")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	logger.UpdateContext(logging.LogCtxFrom(mtr))
+	ctx = logging.SetLogger(ctx, logger)
+	if err := h.monitor.Update(ctx, mtr); err != nil {
+		logger.Err(err).Msg("failed to persist metric")
+		return nil, status.Error(codes.Internal, "failed to persist metric")
+	}
+	if info != nil {
+		h.agents.Report(ctx, info, 1)
+	}
+	return &pb.UpdateResponse{}, nil
+}
+
+func (h *MetricsGRPCHandler) UpdateBulk(ctx context.Context, req *pb.UpdateBulkRequest) (*pb.UpdateBulkResponse, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerGRPCName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Updates]")
+
+	info, err := agentFromMetadata(ctx)
+	if err != nil {
+		logger.Err(err).Msg("failed to process agent identity")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	list, err := h.decodeBatch(req)
+	if err != nil {
+		logger.Err(err).Msg("validation failed")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := assignInstance(info, list...); err != nil {
+		logger.Err(err).Msg("instance validation failed")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx = logging.SetLogger(ctx, logger)
+	if err := h.monitor.UpdateBulk(ctx, list); err != nil {
+		logger.Err(err).Msg("failed to batch update metrics")
+		return nil, status.Error(codes.Internal, "failed to batch update metrics")
+	}
+	if info != nil {
+		h.agents.Report(ctx, info, len(list))
+	}
+	return &pb.UpdateBulkResponse{}, nil
+}
+
+func (h *MetricsGRPCHandler) Value(ctx context.Context, req *pb.ValueRequest) (*pb.ValueResponse, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerGRPCName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Value]")
+
+	body := &model.Metrics{
+		ID:       req.Id,
+		MType:    req.Type,
+		Instance: req.Instance,
+		Labels:   req.Labels,
+	}
+	if err := body.Validate(model.CheckID, model.CheckType, model.CheckLabels); err != nil {
+		logger.Err(err).Msg("validation failed")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	key := body.ToCanonical()
+	id, typ, labels := key.ID, key.Type(), key.Labels
+	logger.UpdateContext(logging.LogCtxFrom(logging.LogCtxKeyStr(logging.MetricIDKey, id), typ, labels))
+	ctx = logging.SetLogger(ctx, logger)
+	mtr, err := h.monitor.Get(ctx, id, typ, labels)
+	if err != nil {
+		logger.Err(err).Msg("metric read failed")
+		return nil, status.Error(codes.Internal, "metric read failed")
+	}
+	if mtr == nil {
+		logger.Warn().Msg("requested metric not found")
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+
+	body = model.NewFromCanonical(mtr)
+	if len(h.key) != 0 {
+		if err := body.Sign(h.key); err != nil {
+			logger.Err(err).Msg("signing failed")
+			return nil, status.Error(codes.Internal, "signing failed")
+		}
+	}
+	return &pb.ValueResponse{Metric: pb.NewMetric(body)}, nil
+}
+
+func (h *MetricsGRPCHandler) Push(stream pb.Monitor_PushServer) error {
+	ctx, _ := logging.SetIfAbsentCID(stream.Context(), logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(metricsHandlerGRPCName), logging.WithCID(ctx))
+	logger.Info().Msg("handling [Push]")
+
+	info, err := agentFromMetadata(ctx)
+	if err != nil {
+		logger.Err(err).Msg("failed to process agent identity")
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx = logging.SetLogger(ctx, logger)
+	resp := &pb.PushResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			logger.Trace().Msgf("%d batches of %d metrics received", resp.Batches, resp.Metrics)
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			logger.Err(err).Msg("failed to receive batch")
+			return err
+		}
+
+		list, err := h.decodeBatch(req)
+		if err != nil {
+			logger.Err(err).Msgf("validation of batch #%d failed", resp.Batches+1)
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := assignInstance(info, list...); err != nil {
+			logger.Err(err).Msgf("instance validation of batch #%d failed", resp.Batches+1)
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := h.monitor.UpdateBulk(ctx, list); err != nil {
+			logger.Err(err).Msgf("failed to update metrics of batch #%d", resp.Batches+1)
+			return status.Error(codes.Internal, "failed to batch update metrics")
+		}
+		if info != nil {
+			h.agents.Report(ctx, info, len(list))
+		}
+		resp.Batches++
+		resp.Metrics += uint64(len(list))
+	}
+}
+
+func (h *MetricsGRPCHandler) decodeBatch(req *pb.UpdateBulkRequest) (metric.List, error) {
+	list := make(metric.List, 0, len(req.Metrics))
+	for _, m := range req.Metrics {
+		body := m.ToModel()
+		if body == nil {
+			return nil, errors.New("metric is not specified")
+		}
+		if err := body.Validate(model.CheckID, model.CheckValue, model.CheckType, model.CheckLabels, model.CheckHash(h.key)); err != nil {
+			return nil, err
+		}
+		list = append(list, body.ToCanonical())
+	}
+	return list, nil
+}
+
+// agentFromMetadata decodes reporting agent identity from incoming call metadata.
+func agentFromMetadata(ctx context.Context) (*agent.Info, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for key, values := range md {
+		if len(values) != 0 {
+			header.Set(key, values[0])
+		}
+	}
+	return model.AgentFromHeader(header)
+}
+
+func NewMetricsGRPCHandler(cfg *config.Config, service monitor.Monitor, agents registry.Registry) *MetricsGRPCHandler {
+	return &MetricsGRPCHandler{
+		monitor: service,
+		agents:  agents,
+		key:     cfg.Key,
+	}
+}
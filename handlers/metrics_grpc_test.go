@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
+	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/service/registry"
+)
+
+func TestMetricsGRPCHandler(t *testing.T) {
+	cfg := &config.Config{}
+	client := newGRPCTestClient(t, NewMetricsGRPCHandler(cfg, &monitorServiceStub{}, registry.NewRegistry(cfg)))
+
+	value := 1.23
+	delta := int64(1)
+
+	tests := []struct {
+		name     string
+		call     func(context.Context) (interface{}, error)
+		wantCode codes.Code
+		want     interface{}
+	}{
+		{
+			name: "Update gauge",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "foo", Type: "gauge", Value: &value}})
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Update labeled counter",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "foo", Type: "counter", Labels: map[string]string{"cpu": "1"}, Delta: &delta}})
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Update without metric",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Update(ctx, &pb.UpdateRequest{})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Update without value",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "foo", Type: "gauge"}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Update unknown type",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "foo", Type: "unknown", Value: &value}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Batch update",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.UpdateBulk(ctx, &pb.UpdateBulkRequest{Metrics: []*pb.Metric{
+					{Id: "foo", Type: "gauge", Value: &value},
+					{Id: "bar", Type: "counter", Delta: &delta},
+				}})
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Batch update with invalid metric",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.UpdateBulk(ctx, &pb.UpdateBulkRequest{Metrics: []*pb.Metric{{Id: "", Type: "gauge", Value: &value}}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Get counter",
+			call: func(ctx context.Context) (interface{}, error) {
+				resp, err := client.Value(ctx, &pb.ValueRequest{Id: "foo", Type: "counter", Labels: map[string]string{"cpu": "1"}})
+				if err != nil {
+					return nil, err
+				}
+				return resp.Metric.ToModel().ToCanonical().String(), nil
+			},
+			wantCode: codes.OK,
+			want:     `counter/foo{cpu="1"}/0`,
+		},
+		{
+			name: "Get not found",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Value(ctx, &pb.ValueRequest{Id: notFoundSample, Type: "counter"})
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "Get invalid labels",
+			call: func(ctx context.Context) (interface{}, error) {
+				return client.Value(ctx, &pb.ValueRequest{Id: "foo", Type: "counter", Labels: map[string]string{"0cpu": "1"}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call(context.TODO())
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.want != nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMetricsGRPCHandlerPush(t *testing.T) {
+	key := "secret"
+	cfg := &config.Config{Key: key}
+	client := newGRPCTestClient(t, NewMetricsGRPCHandler(cfg, &monitorServiceStub{}, registry.NewRegistry(cfg)))
+
+	newBatch := func(key string, list ...*metric.Metric) *pb.UpdateBulkRequest {
+		req := &pb.UpdateBulkRequest{}
+		for _, mtr := range list {
+			m := model.NewFromCanonical(mtr)
+			require.NoError(t, m.Sign(key))
+			req.Metrics = append(req.Metrics, pb.NewMetric(m))
+		}
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		batches  []*pb.UpdateBulkRequest
+		wantCode codes.Code
+		want     *pb.PushResponse
+	}{
+		{
+			name: "Basic test",
+			batches: []*pb.UpdateBulkRequest{
+				newBatch(key, metric.NewGaugeMetric("foo", 1), metric.NewCounterMetric("bar", 1)),
+				newBatch(key, metric.NewGaugeMetric("baz", 1)),
+				newBatch(key),
+			},
+			wantCode: codes.OK,
+			want:     &pb.PushResponse{Batches: 3, Metrics: 3},
+		},
+		{
+			name:     "Empty stream",
+			wantCode: codes.OK,
+			want:     &pb.PushResponse{},
+		},
+		{
+			name: "Verification failed",
+			batches: []*pb.UpdateBulkRequest{
+				newBatch(key, metric.NewGaugeMetric("foo", 1)),
+				newBatch("unknown", metric.NewGaugeMetric("foo", 1)),
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Push(context.TODO())
+			require.NoError(t, err)
+			for _, batch := range tt.batches {
+				if err := stream.Send(batch); err != nil {
+					break
+				}
+			}
+
+			resp, err := stream.CloseAndRecv()
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.want != nil {
+				require.NotNil(t, resp)
+				assert.Equal(t, tt.want.Batches, resp.Batches)
+				assert.Equal(t, tt.want.Metrics, resp.Metrics)
+			}
+		})
+	}
+}
+
+func newGRPCTestClient(t *testing.T, handler pb.MonitorServer) pb.MonitorClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := monitor.NewGRPCServer(handler)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	require.NoError(t, err, "failed to dial server")
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewMonitorClient(conn)
+}
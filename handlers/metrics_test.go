@@ -28,6 +28,46 @@ func TestMetricsHandler(t *testing.T) {
 			wantStatus:      http.StatusOK,
 			wantContentType: "text/html",
 		},
+		{
+			name:            "Get Prometheus exposition",
+			method:          "GET",
+			url:             "/metrics",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain",
+		},
+		{
+			name:            "Get filtered summary",
+			method:          "GET",
+			url:             "/?labels=%7Bcpu%3D%221%22%7D",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html",
+		},
+		{
+			name:       "Get summary with malformed filter",
+			method:     "GET",
+			url:        "/?labels=cpu",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Update labeled gauge",
+			method:     "POST",
+			url:        "/update/gauge/foo/1.5?labels=%7Bcpu%3D%221%22%7D",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "Get labeled gauge",
+			method:          "GET",
+			url:             "/value/gauge/foo?labels=%7Bcpu%3D%221%22%7D",
+			wantStatus:      http.StatusOK,
+			want:            "0.000",
+			wantContentType: "text/plain",
+		},
+		{
+			name:       "Update with malformed labels",
+			method:     "POST",
+			url:        "/update/gauge/foo/1.5?labels=%7Bcpu%3D1%7D",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Update gauge",
 			method:     "POST",
@@ -62,6 +102,26 @@ func TestMetricsHandler(t *testing.T) {
 			want:            "0",
 			wantContentType: "text/plain",
 		},
+		{
+			name:       "Update histogram",
+			method:     "POST",
+			url:        "/update/histogram/foo/0.5:1,+Inf:2;7.5",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "Get histogram",
+			method:          "GET",
+			url:             "/value/histogram/foo",
+			wantStatus:      http.StatusOK,
+			want:            "+Inf:0;0",
+			wantContentType: "text/plain",
+		},
+		{
+			name:       "Update with incorrect histogram value",
+			method:     "POST",
+			url:        "/update/histogram/foo/0.5:1;7.5",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Update with incorrect gauge value",
 			method:     "POST",
@@ -6,7 +6,7 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func NewMetricsRouter(metricsHandler *MetricsHandler, metricsAPI *MetricsAPIHandler) http.Handler {
+func NewMetricsRouter(metricsHandler *MetricsHandler, metricsAPI *MetricsAPIHandler, alerts *AlertsHandler, agents *AgentsHandler) http.Handler {
 	router := chi.NewRouter()
 	router.Get("/", metricsHandler.GetAll)
 	router.Route("/update", func(r chi.Router) {
@@ -20,6 +20,14 @@ func NewMetricsRouter(metricsHandler *MetricsHandler, metricsAPI *MetricsAPIHand
 		r.Post("/", metricsAPI.Value)
 		r.Get("/{type}/{id}", metricsHandler.Value)
 	})
+	router.Get("/query", metricsAPI.Query)
+	router.Get("/metrics", metricsHandler.Exposition)
 	router.Get("/ping", metricsAPI.Ping)
+	if alerts != nil {
+		router.Get("/alerts", alerts.Alerts)
+	}
+	if agents != nil {
+		router.Get("/agents", agents.Agents)
+	}
 	return router
 }
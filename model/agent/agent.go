@@ -0,0 +1,67 @@
+// Package agent describes identity of metrics reporting agent.
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+var _ logging.LogCtxProvider = (*Info)(nil)
+
+// Info identifies agent instance reporting metrics to monitor server.
+type Info struct {
+	// ID identifies agent across restarts.
+	ID string
+
+	// Hostname is a name of agent's host.
+	Hostname string
+
+	// Version is agent build version.
+	Version string
+
+	// StartedAt is agent process start time. It changes on each agent restart.
+	StartedAt time.Time
+
+	// ReportInterval is a period agent reports metrics with.
+	ReportInterval time.Duration
+}
+
+func (i *Info) String() string {
+	return fmt.Sprintf("%s@%s", i.ID, i.Hostname)
+}
+
+func (i *Info) LoggerCtx(ctx zerolog.Context) zerolog.Context {
+	return ctx.Str(logging.AgentIDKey, i.ID)
+}
+
+// Validate checks agent identity is complete.
+func (i *Info) Validate() error {
+	if len(i.ID) == 0 {
+		return errors.New("agent id is empty")
+	}
+	if i.ReportInterval < 0 {
+		return errors.New("agent report interval must not be negative")
+	}
+	return nil
+}
+
+// New creates identity of agent instance started now. Agent's hostname is used as ID if id is empty.
+func New(id, version string, reportInterval time.Duration) *Info {
+	hostname, _ := os.Hostname()
+	if len(id) == 0 {
+		id = hostname
+	}
+	return &Info{
+		ID:             id,
+		Hostname:       hostname,
+		Version:        version,
+		StartedAt:      time.Now(),
+		ReportInterval: reportInterval,
+	}
+}
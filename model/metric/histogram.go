@@ -0,0 +1,185 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// Histogram is a distribution of observed values among buckets. Bucket i counts observations less or equal to
+// Bounds[i] and greater than previous bound. The last bucket counts observations greater than all bounds.
+type Histogram struct {
+	Bounds []float64
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+var _ Value = (*Histogram)(nil)
+
+// String represents histogram as comma separated list of bucket counts followed by sum of observations,
+// e.g. "0.5:1,1:3,+Inf:0;2.5". The representation is accepted by Parse.
+func (h Histogram) String() string {
+	var builder strings.Builder
+	for i, count := range h.Counts {
+		if i != 0 {
+			builder.WriteByte(',')
+		}
+		bound := "+Inf"
+		if i < len(h.Bounds) {
+			bound = strconv.FormatFloat(h.Bounds[i], 'g', -1, 64)
+		}
+		builder.WriteString(fmt.Sprintf("%s:%d", bound, count))
+	}
+	builder.WriteString(fmt.Sprintf(";%s", strconv.FormatFloat(h.Sum, 'g', -1, 64)))
+	return builder.String()
+}
+
+func (h *Histogram) LoggerCtx(ctx zerolog.Context) zerolog.Context {
+	if h == nil {
+		return ctx
+	}
+	return logging.LogCtxUpdateWith(ctx.Stringer(logging.MetricValueKey, h), h.Type())
+}
+
+func (h *Histogram) Parse(s string) error {
+	parts := strings.Split(s, ";")
+	if len(parts) != 2 {
+		return fmt.Errorf("can't parse histogram from '%s': buckets and sum expected", s)
+	}
+
+	sum, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return fmt.Errorf("can't parse histogram sum from '%s': %v", s, err)
+	}
+
+	buckets := strings.Split(parts[0], ",")
+	hist := Histogram{
+		Bounds: make([]float64, 0, len(buckets)-1),
+		Counts: make([]uint64, 0, len(buckets)),
+		Sum:    sum,
+	}
+	for i, bucket := range buckets {
+		kv := strings.Split(bucket, ":")
+		if len(kv) != 2 {
+			return fmt.Errorf("can't parse histogram bucket from '%s'", bucket)
+		}
+		bound, err := strconv.ParseFloat(kv[0], 64)
+		if err != nil {
+			return fmt.Errorf("can't parse histogram bucket bound from '%s': %v", bucket, err)
+		}
+		count, err := strconv.ParseUint(kv[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("can't parse histogram bucket count from '%s': %v", bucket, err)
+		}
+		if last := i == len(buckets)-1; last != math.IsInf(bound, 1) {
+			return fmt.Errorf("can't parse histogram from '%s': +Inf bound must be the last one", s)
+		} else if !last {
+			hist.Bounds = append(hist.Bounds, bound)
+		}
+		hist.Counts = append(hist.Counts, count)
+		hist.Count += count
+	}
+	if err := hist.Validate(); err != nil {
+		return fmt.Errorf("can't parse histogram from '%s': %v", s, err)
+	}
+	*h = hist
+	return nil
+}
+
+func (Histogram) Type() Type {
+	return HistogramType
+}
+
+// Validate checks histogram consistency.
+func (h *Histogram) Validate() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("histogram: %d buckets expected for %d bounds, got %d", len(h.Bounds)+1, len(h.Bounds), len(h.Counts))
+	}
+	for i, bound := range h.Bounds {
+		if math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return fmt.Errorf("histogram: bound %v is not finite", bound)
+		}
+		if i != 0 && bound <= h.Bounds[i-1] {
+			return errors.New("histogram: bounds must be strictly increasing")
+		}
+	}
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	if count != h.Count {
+		return fmt.Errorf("histogram: total count %d doesn't match buckets count %d", h.Count, count)
+	}
+	return nil
+}
+
+// Observe registers single observation in corresponding bucket.
+func (h *Histogram) Observe(v float64) {
+	h.ObserveN(v, 1)
+}
+
+// ObserveN registers n observations of the same value, e.g. a sampled one.
+func (h *Histogram) ObserveN(v float64, n uint64) {
+	h.Counts[sort.SearchFloat64s(h.Bounds, v)] += n
+	h.Sum += v * float64(n)
+	h.Count += n
+}
+
+// Merge adds bucket counts, sum and count of other histogram. Both histograms must have the same bounds.
+func (h *Histogram) Merge(other *Histogram) error {
+	if !h.SameBounds(other) {
+		return errors.New("histogram: can't merge histograms with different bounds")
+	}
+	for i, count := range other.Counts {
+		h.Counts[i] += count
+	}
+	h.Sum += other.Sum
+	h.Count += other.Count
+	return nil
+}
+
+// SameBounds returns true if both histograms have the same bucket bounds.
+func (h *Histogram) SameBounds(other *Histogram) bool {
+	if len(h.Bounds) != len(other.Bounds) {
+		return false
+	}
+	for i, bound := range h.Bounds {
+		if bound != other.Bounds[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns deep copy of histogram.
+func (h Histogram) Copy() Histogram {
+	bounds := make([]float64, len(h.Bounds))
+	copy(bounds, h.Bounds)
+	counts := make([]uint64, len(h.Counts))
+	copy(counts, h.Counts)
+	return Histogram{
+		Bounds: bounds,
+		Counts: counts,
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
+
+// NewHistogram creates empty histogram with specified bucket bounds. Bounds are sorted.
+func NewHistogram(bounds ...float64) Histogram {
+	sorted := make([]float64, len(bounds))
+	copy(sorted, bounds)
+	sort.Float64s(sorted)
+	return Histogram{
+		Bounds: sorted,
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
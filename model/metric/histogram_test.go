@@ -0,0 +1,153 @@
+package metric
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    Histogram
+		wantErr bool
+	}{
+		{
+			name: "Basic test",
+			arg:  "0.5:1,1:3,+Inf:2;7.5",
+			want: Histogram{Bounds: []float64{.5, 1}, Counts: []uint64{1, 3, 2}, Sum: 7.5, Count: 6},
+		},
+		{
+			name: "Single bucket",
+			arg:  "+Inf:2;3",
+			want: Histogram{Bounds: []float64{}, Counts: []uint64{2}, Sum: 3, Count: 2},
+		},
+		{
+			name:    "Missing sum",
+			arg:     "0.5:1,+Inf:2",
+			wantErr: true,
+		},
+		{
+			name:    "Missing +Inf bucket",
+			arg:     "0.5:1,1:2;3",
+			wantErr: true,
+		},
+		{
+			name:    "Misplaced +Inf bucket",
+			arg:     "+Inf:1,1:2;3",
+			wantErr: true,
+		},
+		{
+			name:    "Unordered bounds",
+			arg:     "1:1,0.5:2,+Inf:0;3",
+			wantErr: true,
+		},
+		{
+			name:    "Negative count",
+			arg:     "1:-1,+Inf:0;3",
+			wantErr: true,
+		},
+		{
+			name:    "Incorrect",
+			arg:     "foo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Histogram{}
+			err := h.Parse(tt.arg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, h,
+					fmt.Sprintf("%T.Parse(\"%s\") affects to %T(%v)", h, tt.arg, tt.want, tt.want))
+			}
+		})
+	}
+}
+
+func TestHistogram_String(t *testing.T) {
+	tests := []struct {
+		name string
+		h    Histogram
+		want string
+	}{
+		{
+			name: "Basic test",
+			h:    Histogram{Bounds: []float64{.5, 1}, Counts: []uint64{1, 3, 2}, Sum: 7.5, Count: 6},
+			want: "0.5:1,1:3,+Inf:2;7.5",
+		},
+		{
+			name: "Empty histogram",
+			h:    NewHistogram(),
+			want: "+Inf:0;0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.h.String())
+		})
+	}
+}
+
+func TestHistogram_Observe(t *testing.T) {
+	t.Run("Basic test", func(t *testing.T) {
+		h := NewHistogram(10, 1, 5)
+		for _, v := range []float64{0.5, 1, 3, 7, 100} {
+			h.Observe(v)
+		}
+		assert.Equal(t, Histogram{Bounds: []float64{1, 5, 10}, Counts: []uint64{2, 1, 1, 1}, Sum: 111.5, Count: 5}, h)
+	})
+
+	t.Run("Multiple observations", func(t *testing.T) {
+		h := NewHistogram(1, 5)
+		h.ObserveN(3, 10)
+		h.ObserveN(0.5, 0)
+		assert.Equal(t, Histogram{Bounds: []float64{1, 5}, Counts: []uint64{0, 10, 0}, Sum: 30, Count: 10}, h)
+	})
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       Histogram
+		other   Histogram
+		want    Histogram
+		wantErr bool
+	}{
+		{
+			name:  "Basic test",
+			h:     Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 5, Count: 3},
+			other: Histogram{Bounds: []float64{1}, Counts: []uint64{3, 0}, Sum: 1, Count: 3},
+			want:  Histogram{Bounds: []float64{1}, Counts: []uint64{4, 2}, Sum: 6, Count: 6},
+		},
+		{
+			name:    "Different bounds",
+			h:       Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 5, Count: 3},
+			other:   Histogram{Bounds: []float64{2}, Counts: []uint64{3, 0}, Sum: 1, Count: 3},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.h.Merge(&tt.other)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, tt.h)
+			}
+		})
+	}
+}
+
+func TestHistogram_Type(t *testing.T) {
+	t.Run("Basic test", func(t *testing.T) {
+		assert.Equal(t, HistogramType, NewHistogram().Type())
+	})
+}
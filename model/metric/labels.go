@@ -0,0 +1,170 @@
+package metric
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+var _ logging.LogCtxProvider = (Labels)(nil)
+
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// InstanceLabel is a reserved label identifying metric source instance (agent), e.g. {instance="host-1"}. Metrics
+// reported by different agents are kept apart by the label.
+const InstanceLabel = "instance"
+
+// Labels is a set of metric dimensions. Labels are part of metric identity: metrics with the same ID and type but
+// different labels are distinct metrics.
+type Labels map[string]string
+
+// String represents labels in canonical form sorted by label name, e.g. {cpu="1",host="foo"}. Empty labels are
+// represented by empty string. The representation is accepted by Parse.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for i, name := range names {
+		if i != 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(name)
+		builder.WriteByte('=')
+		builder.WriteString(strconv.Quote(l[name]))
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+func (l Labels) LoggerCtx(ctx zerolog.Context) zerolog.Context {
+	if len(l) == 0 {
+		return ctx
+	}
+	return ctx.Stringer(logging.MetricLabelsKey, l)
+}
+
+// Parse reads labels from comma separated list of name="value" pairs. Enclosing braces are optional. Empty string
+// is parsed to nil labels.
+func (l *Labels) Parse(s string) error {
+	src := strings.TrimSpace(s)
+	if strings.HasPrefix(src, "{") {
+		if !strings.HasSuffix(src, "}") {
+			return fmt.Errorf("can't parse labels from '%s': unbalanced braces", s)
+		}
+		src = src[1 : len(src)-1]
+	}
+
+	labels := make(Labels)
+	for src = strings.TrimSpace(src); len(src) != 0; {
+		eq := strings.IndexByte(src, '=')
+		if eq == -1 {
+			return fmt.Errorf("can't parse labels from '%s': name=\"value\" pair expected", s)
+		}
+		name := strings.TrimSpace(src[:eq])
+		quoted, err := strconv.QuotedPrefix(strings.TrimSpace(src[eq+1:]))
+		if err != nil {
+			return fmt.Errorf("can't parse label %s value from '%s': %v", name, s, err)
+		}
+		value, _ := strconv.Unquote(quoted)
+		if _, ok := labels[name]; ok {
+			return fmt.Errorf("can't parse labels from '%s': duplicate label %s", s, name)
+		}
+		labels[name] = value
+
+		src = strings.TrimSpace(strings.TrimSpace(src[eq+1:])[len(quoted):])
+		if len(src) != 0 {
+			if src[0] != ',' {
+				return fmt.Errorf("can't parse labels from '%s': comma expected after label %s", s, name)
+			}
+			src = strings.TrimSpace(src[1:])
+		}
+	}
+	if err := labels.Validate(); err != nil {
+		return fmt.Errorf("can't parse labels from '%s': %v", s, err)
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	*l = labels
+	return nil
+}
+
+// Validate checks that label names are valid identifiers.
+func (l Labels) Validate() error {
+	for name := range l {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf("labels: invalid label name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if labels contain every label of the filter. Empty filter matches any labels.
+func (l Labels) Matches(filter Labels) bool {
+	for name, value := range filter {
+		if v, ok := l[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal returns true if both label sets are the same. Nil and empty labels are equal.
+func (l Labels) Equal(other Labels) bool {
+	return len(l) == len(other) && l.Matches(other)
+}
+
+// Copy returns copy of labels. Copy of empty labels is nil.
+func (l Labels) Copy() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	labels := make(Labels, len(l))
+	for name, value := range l {
+		labels[name] = value
+	}
+	return labels
+}
+
+// Instance returns metric source instance. Empty instance stands for anonymous source.
+func (l Labels) Instance() string {
+	return l[InstanceLabel]
+}
+
+// WithInstance returns copy of labels with specified source instance. Empty instance removes instance label.
+func (l Labels) WithInstance(instance string) Labels {
+	labels := make(Labels, len(l)+1)
+	for name, value := range l {
+		labels[name] = value
+	}
+	if len(instance) == 0 {
+		delete(labels, InstanceLabel)
+	} else {
+		labels[InstanceLabel] = instance
+	}
+	return labels.Copy()
+}
+
+// ParseLabels is a shorthand of Labels.Parse.
+func ParseLabels(s string) (Labels, error) {
+	var labels Labels
+	if err := labels.Parse(s); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
@@ -0,0 +1,166 @@
+package metric
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels Labels
+		want   string
+	}{
+		{
+			name:   "Basic test",
+			labels: Labels{"host": "foo", "cpu": "1"},
+			want:   `{cpu="1",host="foo"}`,
+		},
+		{
+			name:   "Escaped value",
+			labels: Labels{"path": `C:\tmp "a"`},
+			want:   `{path="C:\\tmp \"a\""}`,
+		},
+		{
+			name: "Empty labels",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.labels.String())
+		})
+	}
+}
+
+func TestLabels_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    Labels
+		wantErr bool
+	}{
+		{
+			name: "Basic test",
+			arg:  `{cpu="1",host="foo"}`,
+			want: Labels{"host": "foo", "cpu": "1"},
+		},
+		{
+			name: "Without braces",
+			arg:  ` cpu = "1" , host="foo" `,
+			want: Labels{"host": "foo", "cpu": "1"},
+		},
+		{
+			name: "Escaped value",
+			arg:  `{path="C:\\tmp \"a\", b"}`,
+			want: Labels{"path": `C:\tmp "a", b`},
+		},
+		{
+			name: "Empty labels",
+			arg:  "{}",
+		},
+		{
+			name: "Empty string",
+			arg:  "",
+		},
+		{
+			name:    "Unquoted value",
+			arg:     "{cpu=1}",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid name",
+			arg:     `{1cpu="1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Duplicate label",
+			arg:     `{cpu="1",cpu="2"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing comma",
+			arg:     `{cpu="1" host="foo"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unbalanced braces",
+			arg:     `{cpu="1"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l Labels
+			err := l.Parse(tt.arg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, l, fmt.Sprintf("%T.Parse(`%s`) affects to %v", l, tt.arg, tt.want))
+			}
+		})
+	}
+}
+
+func TestLabels_Matches(t *testing.T) {
+	labels := Labels{"cpu": "1", "host": "foo"}
+
+	tests := []struct {
+		name   string
+		filter Labels
+		want   bool
+	}{
+		{
+			name:   "Subset",
+			filter: Labels{"cpu": "1"},
+			want:   true,
+		},
+		{
+			name:   "Same labels",
+			filter: Labels{"cpu": "1", "host": "foo"},
+			want:   true,
+		},
+		{
+			name: "Empty filter",
+			want: true,
+		},
+		{
+			name:   "Different value",
+			filter: Labels{"cpu": "2"},
+		},
+		{
+			name:   "Absent label",
+			filter: Labels{"cpu": "1", "dc": "bar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, labels.Matches(tt.filter))
+		})
+	}
+}
+
+func TestLabels_Equal(t *testing.T) {
+	assert.True(t, Labels(nil).Equal(Labels{}))
+	assert.True(t, Labels{"cpu": "1"}.Equal(Labels{"cpu": "1"}))
+	assert.False(t, Labels{"cpu": "1"}.Equal(Labels{"cpu": "1", "host": "foo"}))
+	assert.False(t, Labels{"cpu": "1"}.Equal(nil))
+}
+
+func TestLabels_WithInstance(t *testing.T) {
+	labels := Labels{"cpu": "1"}
+
+	withInstance := labels.WithInstance("foo")
+	assert.Equal(t, Labels{"cpu": "1", InstanceLabel: "foo"}, withInstance)
+	assert.Equal(t, "foo", withInstance.Instance())
+	assert.Equal(t, Labels{"cpu": "1"}, labels, "receiver is not modified")
+
+	assert.Equal(t, labels, withInstance.WithInstance(""))
+	assert.Nil(t, Labels(nil).WithInstance(""))
+	assert.Empty(t, labels.Instance())
+}
@@ -17,6 +17,20 @@ func (l List) ToMap() map[Type]List {
 	return m
 }
 
+// Filter returns metrics which labels match the filter.
+func (l List) Filter(filter Labels) List {
+	if len(filter) == 0 {
+		return l
+	}
+	list := make(List, 0, len(l))
+	for _, mtr := range l {
+		if mtr.Labels.Matches(filter) {
+			list = append(list, mtr)
+		}
+	}
+	return list
+}
+
 type ByString List
 
 func (m ByString) Len() int           { return len(m) }
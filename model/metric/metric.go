@@ -17,17 +17,30 @@ var _ json.Unmarshaler = (*Metric)(nil)
 type Metric struct {
 	ID string
 	Value
+	Labels Labels `json:",omitempty"`
 }
 
 func (m *Metric) String() string {
 	if m.Value == nil {
-		return fmt.Sprintf("?/%s/?", m.ID)
+		return fmt.Sprintf("?/%s/?", m.Key())
 	}
-	return fmt.Sprintf("%s/%s/%v", m.Value.Type(), m.ID, m.Value)
+	return fmt.Sprintf("%s/%s/%v", m.Value.Type(), m.Key(), m.Value)
 }
 
 func (m *Metric) LoggerCtx(ctx zerolog.Context) zerolog.Context {
-	return logging.LogCtxUpdateWith(ctx.Str(logging.MetricIDKey, m.ID), m.Value)
+	return logging.LogCtxUpdateWith(ctx.Str(logging.MetricIDKey, m.ID), m.Labels, m.Value)
+}
+
+// Key returns metric ID combined with its labels, e.g. CPUutilization{cpu="1"}. Metrics of the same type are
+// identified by key.
+func (m *Metric) Key() string {
+	return m.ID + m.Labels.String()
+}
+
+// WithLabels sets copy of labels to metric.
+func (m *Metric) WithLabels(labels Labels) *Metric {
+	m.Labels = labels.Copy()
+	return m
 }
 
 func (m Metric) MarshalJSON() ([]byte, error) {
@@ -59,6 +72,9 @@ func (m *Metric) UnmarshalJSON(bytes []byte) error {
 	if err := json.Unmarshal(bytes, mtr); err != nil {
 		return err
 	}
+	if err := m.Labels.Validate(); err != nil {
+		return err
+	}
 
 	switch mtr.Type {
 	case GaugeType:
@@ -73,6 +89,15 @@ func (m *Metric) UnmarshalJSON(bytes []byte) error {
 			return err
 		}
 		m.Value = &v
+	case HistogramType:
+		v := Histogram{}
+		if err := json.Unmarshal(mtr.Value, &v); err != nil {
+			return err
+		}
+		if err := v.Validate(); err != nil {
+			return err
+		}
+		m.Value = &v
 	default:
 		return fmt.Errorf("json decoder: unknown type %v", mtr.Type)
 	}
@@ -93,3 +118,10 @@ func NewCounterMetric(id string, counter Counter) *Metric {
 		Value: &counter,
 	}
 }
+
+func NewHistogramMetric(id string, histogram Histogram) *Metric {
+	return &Metric{
+		ID:    id,
+		Value: &histogram,
+	}
+}
@@ -10,6 +10,7 @@ import (
 func TestMetric_MarshalJSON(t *testing.T) {
 	c := Counter(777)
 	g := Gauge(77.7)
+	h := Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 7.5, Count: 3}
 
 	tests := []struct {
 		name    string
@@ -33,6 +34,23 @@ func TestMetric_MarshalJSON(t *testing.T) {
 			},
 			want: `{"ID":"foo","Type":"gauge","Value":77.7}`,
 		},
+		{
+			name: "Encode labeled metric",
+			metric: Metric{
+				ID:     "foo",
+				Value:  &g,
+				Labels: Labels{"cpu": "1"},
+			},
+			want: `{"ID":"foo","Type":"gauge","Value":77.7,"Labels":{"cpu":"1"}}`,
+		},
+		{
+			name: "Encode histogram",
+			metric: Metric{
+				ID:    "foo",
+				Value: &h,
+			},
+			want: `{"ID":"foo","Type":"histogram","Value":{"Bounds":[1],"Counts":[1,2],"Sum":7.5,"Count":3}}`,
+		},
 		{
 			name: "Encode failed",
 			metric: Metric{
@@ -57,6 +75,7 @@ func TestMetric_MarshalJSON(t *testing.T) {
 func TestMetric_UnmarshalJSON(t *testing.T) {
 	c := Counter(777)
 	g := Gauge(77.7)
+	h := Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 7.5, Count: 3}
 
 	tests := []struct {
 		name    string
@@ -80,11 +99,38 @@ func TestMetric_UnmarshalJSON(t *testing.T) {
 				Value: &g,
 			},
 		},
+		{
+			name:   "Decode labeled metric",
+			sample: `{"ID":"foo","Type":"gauge","Value":77.7,"Labels":{"cpu":"1"}}`,
+			want: &Metric{
+				ID:     "foo",
+				Value:  &g,
+				Labels: Labels{"cpu": "1"},
+			},
+		},
+		{
+			name:   "Decode histogram",
+			sample: `{"ID":"foo","Type":"histogram","Value":{"Bounds":[1],"Counts":[1,2],"Sum":7.5,"Count":3}}`,
+			want: &Metric{
+				ID:    "foo",
+				Value: &h,
+			},
+		},
 		{
 			name:    "Decode failed",
 			sample:  `{"ID":"foo","Type":"counter","Value":77.7}`,
 			wantErr: true,
 		},
+		{
+			name:    "Decode invalid labels",
+			sample:  `{"ID":"foo","Type":"gauge","Value":77.7,"Labels":{"1cpu":"1"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Decode inconsistent histogram",
+			sample:  `{"ID":"foo","Type":"histogram","Value":{"Bounds":[1],"Counts":[1],"Sum":7.5,"Count":1}}`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -125,6 +171,15 @@ func TestMetric_String(t *testing.T) {
 			},
 			want: "counter/foo/777",
 		},
+		{
+			name: "Labeled metric representation",
+			metric: Metric{
+				ID:     "foo",
+				Value:  &g,
+				Labels: Labels{"cpu": "1"},
+			},
+			want: `gauge/foo{cpu="1"}/77.700`,
+		},
 		{
 			name: "Absent value representation",
 			metric: Metric{
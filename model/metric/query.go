@@ -0,0 +1,179 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+const (
+	AggregationAvg  Aggregation = "avg"
+	AggregationMin  Aggregation = "min"
+	AggregationMax  Aggregation = "max"
+	AggregationLast Aggregation = "last"
+	AggregationSum  Aggregation = "sum"
+)
+
+var _ logging.LogCtxProvider = (*Query)(nil)
+
+// maxTime is used as upper time bound of unlimited range.
+var maxTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+type (
+	// Aggregation reduces values of step interval to a single value.
+	Aggregation string
+
+	// Query describes request of metric history within time range.
+	Query struct {
+		ID          string
+		Type        Type
+		Labels      Labels
+		From        time.Time
+		To          time.Time
+		Step        time.Duration
+		Aggregation Aggregation
+	}
+
+	// Point is a single value of metric series.
+	Point struct {
+		Timestamp time.Time
+		Value     float64
+	}
+
+	// Series is a chronologically ordered list of metric values.
+	Series []Point
+)
+
+func (a Aggregation) Validate() error {
+	switch a {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast, AggregationSum:
+		return nil
+	default:
+		return fmt.Errorf("unknown aggregation: %v", a)
+	}
+}
+
+func (q *Query) LoggerCtx(ctx zerolog.Context) zerolog.Context {
+	ctx = logging.LogCtxUpdateWith(ctx.Str(logging.MetricIDKey, q.ID), q.Type, q.Labels)
+	return ctx.Time("from", q.From).Time("to", q.To).Dur("step", q.Step).Str("aggregation", string(q.Aggregation))
+}
+
+// Validate checks query consistency.
+func (q *Query) Validate() error {
+	if len(q.ID) == 0 {
+		return fmt.Errorf("query: metric ID is empty")
+	}
+	if err := q.Type.Validate(); err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if err := q.Labels.Validate(); err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if !q.To.IsZero() && q.To.Before(q.From) {
+		return fmt.Errorf("query: time range is empty")
+	}
+	if q.Step < 0 {
+		return fmt.Errorf("query: step must not be negative")
+	}
+	if q.Step != 0 {
+		if err := q.Aggregation.Validate(); err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+	}
+	return nil
+}
+
+// Range returns queried time bounds. Zero upper bound is treated as unlimited.
+func (q *Query) Range() (from time.Time, to time.Time) {
+	from, to = q.From, q.To
+	if to.IsZero() {
+		to = maxTime
+	}
+	return
+}
+
+// Matches returns true if sample belongs to queried metric and time range.
+func (q *Query) Matches(smp *Sample) bool {
+	from, to := q.Range()
+	return smp.ID == q.ID && smp.Type() == q.Type && smp.Labels.Equal(q.Labels) && !smp.Timestamp.Before(from) && !smp.Timestamp.After(to)
+}
+
+// Apply builds series from samples matching the query. Without step every sample is represented as point. Otherwise,
+// samples are grouped by step intervals aligned to step and each group is reduced with query aggregation.
+func (q *Query) Apply(samples Samples) Series {
+	matched := make(Samples, 0, len(samples))
+	for _, smp := range samples {
+		if q.Matches(smp) {
+			matched = append(matched, smp)
+		}
+	}
+	matched.SortByTime()
+
+	series := make(Series, 0, len(matched))
+	if q.Step == 0 {
+		for _, smp := range matched {
+			series = append(series, Point{Timestamp: smp.Timestamp, Value: ToFloat(smp.Value)})
+		}
+		return series
+	}
+
+	for i := 0; i < len(matched); {
+		start := matched[i].Timestamp.Truncate(q.Step)
+		j := i
+		for j < len(matched) && matched[j].Timestamp.Truncate(q.Step).Equal(start) {
+			j++
+		}
+		series = append(series, Point{Timestamp: start, Value: q.aggregate(matched[i:j])})
+		i = j
+	}
+	return series
+}
+
+func (q *Query) aggregate(samples Samples) float64 {
+	switch q.Aggregation {
+	case AggregationAvg, AggregationSum:
+		var sum float64
+		for _, smp := range samples {
+			sum += ToFloat(smp.Value)
+		}
+		if q.Aggregation == AggregationAvg {
+			return sum / float64(len(samples))
+		}
+		return sum
+	case AggregationMin:
+		min := math.Inf(1)
+		for _, smp := range samples {
+			min = math.Min(min, ToFloat(smp.Value))
+		}
+		return min
+	case AggregationMax:
+		max := math.Inf(-1)
+		for _, smp := range samples {
+			max = math.Max(max, ToFloat(smp.Value))
+		}
+		return max
+	}
+	return ToFloat(samples[len(samples)-1].Value)
+}
+
+// ToFloat converts metric value to float number. Histogram is represented by mean of observations.
+func ToFloat(value Value) float64 {
+	switch v := value.(type) {
+	case *Gauge:
+		return float64(*v)
+	case *Counter:
+		return float64(*v)
+	case *Histogram:
+		if v.Count == 0 {
+			return 0
+		}
+		return v.Sum / float64(v.Count)
+	case *Metric:
+		return ToFloat(v.Value)
+	}
+	return math.NaN()
+}
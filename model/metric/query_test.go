@@ -0,0 +1,166 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_Apply(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	samples := Samples{
+		NewSample(NewGaugeMetric("foo", 4), ts.Add(70*time.Second)),
+		NewSample(NewGaugeMetric("foo", 1), ts),
+		NewSample(NewGaugeMetric("foo", 3), ts.Add(30*time.Second)),
+		NewSample(NewGaugeMetric("foo", 2), ts.Add(-time.Minute)),
+		NewSample(NewGaugeMetric("bar", 9), ts),
+		NewSample(NewCounterMetric("foo", 9), ts),
+		NewSample(NewGaugeMetric("foo", 7).WithLabels(Labels{"cpu": "1"}), ts),
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  Series
+	}{
+		{
+			name:  "Raw history",
+			query: Query{ID: "foo", Type: GaugeType},
+			want: Series{
+				{Timestamp: ts.Add(-time.Minute), Value: 2},
+				{Timestamp: ts, Value: 1},
+				{Timestamp: ts.Add(30 * time.Second), Value: 3},
+				{Timestamp: ts.Add(70 * time.Second), Value: 4},
+			},
+		},
+		{
+			name:  "Labeled history",
+			query: Query{ID: "foo", Type: GaugeType, Labels: Labels{"cpu": "1"}},
+			want: Series{
+				{Timestamp: ts, Value: 7},
+			},
+		},
+		{
+			name:  "Time range",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, To: ts.Add(30 * time.Second)},
+			want: Series{
+				{Timestamp: ts, Value: 1},
+				{Timestamp: ts.Add(30 * time.Second), Value: 3},
+			},
+		},
+		{
+			name:  "Average",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, Step: time.Minute, Aggregation: AggregationAvg},
+			want: Series{
+				{Timestamp: ts, Value: 2},
+				{Timestamp: ts.Add(time.Minute), Value: 4},
+			},
+		},
+		{
+			name:  "Min",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, Step: time.Minute, Aggregation: AggregationMin},
+			want: Series{
+				{Timestamp: ts, Value: 1},
+				{Timestamp: ts.Add(time.Minute), Value: 4},
+			},
+		},
+		{
+			name:  "Max",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, Step: time.Minute, Aggregation: AggregationMax},
+			want: Series{
+				{Timestamp: ts, Value: 3},
+				{Timestamp: ts.Add(time.Minute), Value: 4},
+			},
+		},
+		{
+			name:  "Last",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, Step: time.Minute, Aggregation: AggregationLast},
+			want: Series{
+				{Timestamp: ts, Value: 3},
+				{Timestamp: ts.Add(time.Minute), Value: 4},
+			},
+		},
+		{
+			name:  "Sum",
+			query: Query{ID: "foo", Type: GaugeType, Step: 5 * time.Minute, Aggregation: AggregationSum},
+			want: Series{
+				{Timestamp: ts.Add(-5 * time.Minute), Value: 2},
+				{Timestamp: ts, Value: 8},
+			},
+		},
+		{
+			name:  "Counter",
+			query: Query{ID: "foo", Type: CounterType},
+			want: Series{
+				{Timestamp: ts, Value: 9},
+			},
+		},
+		{
+			name:  "Not found",
+			query: Query{ID: "baz", Type: GaugeType},
+			want:  Series{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.Apply(samples))
+		})
+	}
+}
+
+func TestQuery_Validate(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr bool
+	}{
+		{
+			name:  "Basic test",
+			query: Query{ID: "foo", Type: GaugeType, From: ts, To: ts.Add(time.Hour), Step: time.Minute, Aggregation: AggregationAvg},
+		},
+		{
+			name:  "Unlimited range",
+			query: Query{ID: "foo", Type: CounterType},
+		},
+		{
+			name:    "Empty ID",
+			query:   Query{Type: GaugeType},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			query:   Query{ID: "foo", Type: "bar"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid labels",
+			query:   Query{ID: "foo", Type: GaugeType, Labels: Labels{"1cpu": "1"}},
+			wantErr: true,
+		},
+		{
+			name:    "Inverted range",
+			query:   Query{ID: "foo", Type: GaugeType, From: ts, To: ts.Add(-time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown aggregation",
+			query:   Query{ID: "foo", Type: GaugeType, Step: time.Minute, Aggregation: "median"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var _ json.Marshaler = (*Sample)(nil)
+var _ json.Unmarshaler = (*Sample)(nil)
+
+// Sample is a metric state registered at the specific moment.
+type Sample struct {
+	*Metric
+	Timestamp time.Time
+}
+
+// Samples is a metrics history.
+type Samples []*Sample
+
+func (s *Sample) String() string {
+	return fmt.Sprintf("%v@%s", s.Metric, s.Timestamp.Format(time.RFC3339Nano))
+}
+
+func (s Sample) MarshalJSON() ([]byte, error) {
+	if s.Metric == nil || s.Value == nil {
+		return nil, errors.New("sample value is not specified")
+	}
+	return json.Marshal(&struct {
+		ID        string
+		Type      Type
+		Value     Value
+		Labels    Labels `json:",omitempty"`
+		Timestamp time.Time
+	}{
+		ID:        s.ID,
+		Type:      s.Type(),
+		Value:     s.Value,
+		Labels:    s.Labels,
+		Timestamp: s.Timestamp,
+	})
+}
+
+func (s *Sample) UnmarshalJSON(bytes []byte) error {
+	smp := &struct {
+		Timestamp time.Time
+	}{}
+	if err := json.Unmarshal(bytes, smp); err != nil {
+		return err
+	}
+
+	mtr := &Metric{}
+	if err := json.Unmarshal(bytes, mtr); err != nil {
+		return err
+	}
+	s.Metric = mtr
+	s.Timestamp = smp.Timestamp
+	return nil
+}
+
+// Latest reduces history to list of metrics actual values. Metrics are listed in order of its first occurrence.
+func (s Samples) Latest() List {
+	type key struct {
+		name string
+		typ  Type
+	}
+
+	index := make(map[key]int)
+	latest := make(Samples, 0)
+	for _, smp := range s {
+		k := key{smp.Key(), smp.Type()}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(latest)
+			latest = append(latest, smp)
+			continue
+		}
+		if !smp.Timestamp.Before(latest[i].Timestamp) {
+			latest[i] = smp
+		}
+	}
+
+	list := make(List, 0, len(latest))
+	for _, smp := range latest {
+		list = append(list, smp.Metric)
+	}
+	return list
+}
+
+// SortByTime sorts samples chronologically preserving original order of simultaneous samples.
+func (s Samples) SortByTime() {
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Timestamp.Before(s[j].Timestamp) })
+}
+
+// NewSample creates sample of the specified metric registered at the moment.
+func NewSample(mtr *Metric, timestamp time.Time) *Sample {
+	return &Sample{
+		Metric:    mtr,
+		Timestamp: timestamp,
+	}
+}
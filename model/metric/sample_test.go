@@ -0,0 +1,148 @@
+package metric
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample_MarshalJSON(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		sample  Sample
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Encode gauge",
+			sample: Sample{Metric: NewGaugeMetric("foo", 77.7), Timestamp: ts},
+			want:   `{"ID":"foo","Type":"gauge","Value":77.7,"Timestamp":"2022-03-01T12:00:00Z"}`,
+		},
+		{
+			name:   "Encode counter",
+			sample: Sample{Metric: NewCounterMetric("foo", 777), Timestamp: ts},
+			want:   `{"ID":"foo","Type":"counter","Value":777,"Timestamp":"2022-03-01T12:00:00Z"}`,
+		},
+		{
+			name:   "Encode labeled gauge",
+			sample: Sample{Metric: NewGaugeMetric("foo", 77.7).WithLabels(Labels{"cpu": "1"}), Timestamp: ts},
+			want:   `{"ID":"foo","Type":"gauge","Value":77.7,"Labels":{"cpu":"1"},"Timestamp":"2022-03-01T12:00:00Z"}`,
+		},
+		{
+			name:    "Encode failed",
+			sample:  Sample{Metric: &Metric{ID: "foo"}, Timestamp: ts},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := json.Marshal(tt.sample)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.JSONEq(t, tt.want, string(result))
+			}
+		})
+	}
+}
+
+func TestSample_UnmarshalJSON(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		sample  string
+		want    *Sample
+		wantErr bool
+	}{
+		{
+			name:   "Decode gauge",
+			sample: `{"ID":"foo","Type":"gauge","Value":77.7,"Timestamp":"2022-03-01T12:00:00Z"}`,
+			want:   NewSample(NewGaugeMetric("foo", 77.7), ts),
+		},
+		{
+			name:   "Decode metric without timestamp",
+			sample: `{"ID":"foo","Type":"counter","Value":777}`,
+			want:   NewSample(NewCounterMetric("foo", 777), time.Time{}),
+		},
+		{
+			name:    "Decode failed",
+			sample:  `{"ID":"foo","Type":"counter","Value":77.7}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smp := &Sample{}
+			err := json.Unmarshal([]byte(tt.sample), smp)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, smp)
+			}
+		})
+	}
+}
+
+func TestSamples_Latest(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		samples Samples
+		want    List
+	}{
+		{
+			name: "Basic test",
+			samples: Samples{
+				NewSample(NewGaugeMetric("foo", 1), ts),
+				NewSample(NewCounterMetric("foo", 1), ts),
+				NewSample(NewGaugeMetric("foo", 2), ts.Add(time.Second)),
+				NewSample(NewCounterMetric("foo", 3), ts.Add(time.Second)),
+			},
+			want: List{
+				NewGaugeMetric("foo", 2),
+				NewCounterMetric("foo", 3),
+			},
+		},
+		{
+			name: "Labeled metrics",
+			samples: Samples{
+				NewSample(NewGaugeMetric("foo", 1).WithLabels(Labels{"cpu": "1"}), ts),
+				NewSample(NewGaugeMetric("foo", 2).WithLabels(Labels{"cpu": "2"}), ts),
+				NewSample(NewGaugeMetric("foo", 3).WithLabels(Labels{"cpu": "1"}), ts.Add(time.Second)),
+			},
+			want: List{
+				NewGaugeMetric("foo", 3).WithLabels(Labels{"cpu": "1"}),
+				NewGaugeMetric("foo", 2).WithLabels(Labels{"cpu": "2"}),
+			},
+		},
+		{
+			name: "Unordered history",
+			samples: Samples{
+				NewSample(NewGaugeMetric("foo", 2), ts.Add(time.Second)),
+				NewSample(NewGaugeMetric("foo", 1), ts),
+			},
+			want: List{
+				NewGaugeMetric("foo", 2),
+			},
+		},
+		{
+			name:    "Empty history",
+			samples: Samples{},
+			want:    List{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.samples.Latest())
+		})
+	}
+}
@@ -9,8 +9,9 @@ import (
 )
 
 const (
-	GaugeType   Type = "gauge"
-	CounterType Type = "counter"
+	GaugeType     Type = "gauge"
+	CounterType   Type = "counter"
+	HistogramType Type = "histogram"
 )
 
 var _ logging.LogCtxProvider = (*Type)(nil)
@@ -23,7 +24,7 @@ func (t Type) String() string {
 
 func (t Type) Validate() error {
 	switch t {
-	case GaugeType, CounterType:
+	case GaugeType, CounterType, HistogramType:
 		return nil
 	default:
 		return fmt.Errorf("unkown metric type: %v", t)
@@ -40,6 +41,9 @@ func (t Type) New() (value Value, err error) {
 		value = new(Gauge)
 	case CounterType:
 		value = new(Counter)
+	case HistogramType:
+		hist := NewHistogram()
+		value = &hist
 	default:
 		err = fmt.Errorf("type %v creation is not supported", t)
 	}
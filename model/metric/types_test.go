@@ -10,6 +10,7 @@ import (
 func TestType_New(t *testing.T) {
 	gauge := Gauge(0)
 	counter := Counter(0)
+	histogram := Histogram{Bounds: []float64{}, Counts: []uint64{0}}
 
 	tests := []struct {
 		name      string
@@ -27,6 +28,11 @@ func TestType_New(t *testing.T) {
 			t:         CounterType,
 			wantValue: &counter,
 		},
+		{
+			name:      "Histogram creation",
+			t:         HistogramType,
+			wantValue: &histogram,
+		},
 		{
 			name:    "Unknown type creation",
 			t:       "foo",
@@ -52,6 +58,7 @@ func TestType_New(t *testing.T) {
 func TestType_Parse(t *testing.T) {
 	gauge := Gauge(.5)
 	counter := Counter(5)
+	histogram := Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}, Sum: 3.5, Count: 2}
 
 	tests := []struct {
 		name      string
@@ -72,6 +79,18 @@ func TestType_Parse(t *testing.T) {
 			arg:       "5",
 			wantValue: &counter,
 		},
+		{
+			name:      "Histogram parse",
+			t:         HistogramType,
+			arg:       "1:1,+Inf:1;3.5",
+			wantValue: &histogram,
+		},
+		{
+			name:    "Histogram parse error",
+			t:       HistogramType,
+			arg:     "must-fail",
+			wantErr: true,
+		},
 		{
 			name:    "Gauge parse error",
 			t:       GaugeType,
@@ -151,6 +170,10 @@ func TestType_Validate(t *testing.T) {
 			name: "Counter type validate",
 			t:    CounterType,
 		},
+		{
+			name: "Histogram type validate",
+			t:    HistogramType,
+		},
 		{
 			name:    "Unknown type validate",
 			t:       "foo",
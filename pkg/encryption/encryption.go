@@ -0,0 +1,209 @@
+// Package encryption implements hybrid public key encryption of transmitted payloads. Payload is sealed with
+// AES-256-GCM. The AES key is either encrypted with recipient's RSA public key (RSA-OAEP with SHA-256) or derived from
+// ECDH shared secret of ephemeral and recipient's elliptic curve keys. NIST curves and X25519 are supported.
+//
+// Sealed message layout:
+//
+//	version (1 byte) | scheme (1 byte) | encapsulated key length (2 bytes, big endian) | encapsulated key | nonce | ciphertext
+//
+// Message header (everything before nonce) is authenticated as additional data.
+package encryption
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Header marks HTTP request which body is sealed.
+const Header = "X-Content-Encryption"
+
+// Scheme is a value of Header for sealed requests.
+const Scheme = "hybrid-aes256gcm"
+
+const (
+	version byte = 1
+
+	schemeRSA  byte = 1
+	schemeECDH byte = 2
+
+	keySize    = 32
+	headerSize = 4
+)
+
+var ErrMalformed = errors.New("malformed message")
+
+// Encryptor seals payloads with recipient's public key.
+type Encryptor struct {
+	key crypto.PublicKey
+}
+
+// Encrypt seals plain data. Every call produces a unique message.
+func (e *Encryptor) Encrypt(plain []byte) ([]byte, error) {
+	var scheme byte
+	var key, encapsulated []byte
+	switch pub := e.key.(type) {
+	case *rsa.PublicKey:
+		scheme = schemeRSA
+		key = make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("encrypt: key generation failed: %w", err)
+		}
+		var err error
+		if encapsulated, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil); err != nil {
+			return nil, fmt.Errorf("encrypt: key encapsulation failed: %w", err)
+		}
+	case *ecdh.PublicKey:
+		scheme = schemeECDH
+		ephemeral, err := pub.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: ephemeral key generation failed: %w", err)
+		}
+		secret, err := ephemeral.ECDH(pub)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: key agreement failed: %w", err)
+		}
+		encapsulated = ephemeral.PublicKey().Bytes()
+		key = deriveKey(secret, encapsulated)
+	default:
+		return nil, fmt.Errorf("encrypt: unsupported public key type %T", e.key)
+	}
+	if len(encapsulated) > 0xffff {
+		return nil, errors.New("encrypt: encapsulated key is too long")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, headerSize, headerSize+len(encapsulated)+aead.NonceSize()+len(plain)+aead.Overhead())
+	msg[0], msg[1] = version, scheme
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(encapsulated)))
+	msg = append(msg, encapsulated...)
+	header := msg
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("encrypt: nonce generation failed: %w", err)
+	}
+	msg = append(msg, nonce...)
+	return aead.Seal(msg, nonce, plain, header), nil
+}
+
+// Decryptor opens messages sealed with corresponding public key.
+type Decryptor struct {
+	key crypto.PrivateKey
+}
+
+// Decrypt opens sealed message. Fails if message was sealed for another key or was tampered.
+func (d *Decryptor) Decrypt(msg []byte) ([]byte, error) {
+	if len(msg) < headerSize || msg[0] != version {
+		return nil, ErrMalformed
+	}
+	scheme := msg[1]
+	size := headerSize + int(binary.BigEndian.Uint16(msg[2:]))
+	if len(msg) < size {
+		return nil, ErrMalformed
+	}
+	header, encapsulated := msg[:size], msg[headerSize:size]
+
+	var key []byte
+	switch priv := d.key.(type) {
+	case *rsa.PrivateKey:
+		if scheme != schemeRSA {
+			return nil, fmt.Errorf("decrypt: unexpected scheme %d", scheme)
+		}
+		var err error
+		if key, err = rsa.DecryptOAEP(sha256.New(), nil, priv, encapsulated, nil); err != nil {
+			return nil, fmt.Errorf("decrypt: key decapsulation failed: %w", err)
+		}
+	case *ecdh.PrivateKey:
+		if scheme != schemeECDH {
+			return nil, fmt.Errorf("decrypt: unexpected scheme %d", scheme)
+		}
+		ephemeral, err := priv.Curve().NewPublicKey(encapsulated)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt: invalid ephemeral key: %w", ErrMalformed)
+		}
+		secret, err := priv.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt: key agreement failed: %w", err)
+		}
+		key = deriveKey(secret, encapsulated)
+	default:
+		return nil, fmt.Errorf("decrypt: unsupported private key type %T", d.key)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) < size+aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce := msg[size : size+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, msg[size+aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+// NewEncryptor creates Encryptor for RSA, ECDSA or ECDH public key. ECDSA key is used for ECDH.
+func NewEncryptor(key crypto.PublicKey) (*Encryptor, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey, *ecdh.PublicKey:
+		return &Encryptor{key: key}, nil
+	case *ecdsa.PublicKey:
+		pub, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return &Encryptor{key: pub}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// NewDecryptor creates Decryptor for RSA, ECDSA or ECDH private key. ECDSA key is used for ECDH.
+func NewDecryptor(key crypto.PrivateKey) (*Decryptor, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *ecdh.PrivateKey:
+		return &Decryptor{key: key}, nil
+	case *ecdsa.PrivateKey:
+		priv, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		return &Decryptor{key: priv}, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// deriveKey derives AES key from ECDH shared secret bound to the ephemeral public key.
+func deriveKey(secret, ephemeral []byte) []byte {
+	h := sha256.New()
+	h.Write(secret)
+	h.Write(ephemeral)
+	return h.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
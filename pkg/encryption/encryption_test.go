@@ -0,0 +1,229 @@
+package encryption
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherECKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		public  crypto.PublicKey
+		private crypto.PrivateKey
+		plain   []byte
+		tamper  func([]byte)
+		wantErr bool
+	}{
+		{
+			name:    "RSA",
+			public:  &rsaKey.PublicKey,
+			private: rsaKey,
+			plain:   []byte(`[{"id":"foo","type":"gauge","value":1}]`),
+		},
+		{
+			name:    "ECDH",
+			public:  &ecKey.PublicKey,
+			private: ecKey,
+			plain:   []byte(`[{"id":"foo","type":"gauge","value":1}]`),
+		},
+		{
+			name:    "X25519",
+			public:  x25519Key.PublicKey(),
+			private: x25519Key,
+			plain:   []byte(`[{"id":"foo","type":"gauge","value":1}]`),
+		},
+		{
+			name:    "Curve mismatch",
+			public:  x25519Key.PublicKey(),
+			private: ecKey,
+			plain:   []byte("foo"),
+			wantErr: true,
+		},
+		{
+			name:    "Empty payload",
+			public:  &ecKey.PublicKey,
+			private: ecKey,
+			plain:   []byte{},
+		},
+		{
+			name:    "RSA wrong key",
+			public:  &rsaKey.PublicKey,
+			private: otherRSAKey,
+			plain:   []byte("foo"),
+			wantErr: true,
+		},
+		{
+			name:    "ECDH wrong key",
+			public:  &ecKey.PublicKey,
+			private: otherECKey,
+			plain:   []byte("foo"),
+			wantErr: true,
+		},
+		{
+			name:    "Scheme mismatch",
+			public:  &ecKey.PublicKey,
+			private: rsaKey,
+			plain:   []byte("foo"),
+			wantErr: true,
+		},
+		{
+			name:    "Tampered ciphertext",
+			public:  &rsaKey.PublicKey,
+			private: rsaKey,
+			plain:   []byte("foo"),
+			tamper:  func(msg []byte) { msg[len(msg)-1] ^= 1 },
+			wantErr: true,
+		},
+		{
+			name:    "Tampered header",
+			public:  &ecKey.PublicKey,
+			private: ecKey,
+			plain:   []byte("foo"),
+			tamper:  func(msg []byte) { msg[headerSize+1] ^= 1 },
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported version",
+			public:  &ecKey.PublicKey,
+			private: ecKey,
+			plain:   []byte("foo"),
+			tamper:  func(msg []byte) { msg[0] = 0 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := NewEncryptor(tt.public)
+			require.NoError(t, err)
+			dec, err := NewDecryptor(tt.private)
+			require.NoError(t, err)
+
+			msg, err := enc.Encrypt(tt.plain)
+			require.NoError(t, err)
+			if tt.tamper != nil {
+				tt.tamper(msg)
+			}
+
+			plain, err := dec.Decrypt(msg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.plain), string(plain))
+		})
+	}
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	dec, err := NewDecryptor(key)
+	require.NoError(t, err)
+
+	for _, msg := range [][]byte{nil, {version}, {version, schemeECDH, 0xff, 0xff}, {version, schemeECDH, 0, 1, 4}} {
+		_, err := dec.Decrypt(msg)
+		assert.Error(t, err, "message %v", msg)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pkix := func(key crypto.PublicKey) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		return der
+	}
+	pkcs8 := func(key crypto.PrivateKey) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		return der
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		public  *pem.Block
+		private *pem.Block
+	}{
+		{
+			name:    "RSA PKIX and PKCS8",
+			public:  &pem.Block{Type: "PUBLIC KEY", Bytes: pkix(&rsaKey.PublicKey)},
+			private: &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(rsaKey)},
+		},
+		{
+			name:    "RSA PKCS1",
+			public:  &pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+			private: &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		},
+		{
+			name:    "EC PKIX and SEC1",
+			public:  &pem.Block{Type: "PUBLIC KEY", Bytes: pkix(&ecKey.PublicKey)},
+			private: &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1},
+		},
+		{
+			name:    "X25519 PKIX and PKCS8",
+			public:  &pem.Block{Type: "PUBLIC KEY", Bytes: pkix(x25519Key.PublicKey())},
+			private: &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(x25519Key)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicFile := filepath.Join(dir, "public.pem")
+			privateFile := filepath.Join(dir, "private.pem")
+			require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(tt.public), 0600))
+			require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(tt.private), 0600))
+
+			enc, err := LoadEncryptor(publicFile)
+			require.NoError(t, err)
+			dec, err := LoadDecryptor(privateFile)
+			require.NoError(t, err)
+
+			msg, err := enc.Encrypt([]byte("foo"))
+			require.NoError(t, err)
+			plain, err := dec.Decrypt(msg)
+			require.NoError(t, err)
+			assert.Equal(t, "foo", string(plain))
+		})
+	}
+
+	t.Run("Not a PEM file", func(t *testing.T) {
+		file := filepath.Join(dir, "garbage")
+		require.NoError(t, os.WriteFile(file, []byte("garbage"), 0600))
+		_, err := LoadPublicKey(file)
+		assert.Error(t, err)
+		_, err = LoadPrivateKey(file)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,72 @@
+package encryption
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadPublicKey reads PEM encoded PKIX or PKCS #1 public key from file.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block type %s", path, block.Type)
+}
+
+// LoadPrivateKey reads PEM encoded PKCS #8, PKCS #1 or SEC 1 private key from file.
+func LoadPrivateKey(path string) (crypto.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block type %s", path, block.Type)
+}
+
+// LoadEncryptor creates Encryptor for public key stored in file.
+func LoadEncryptor(path string) (*Encryptor, error) {
+	key, err := LoadPublicKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptor(key)
+}
+
+// LoadDecryptor creates Decryptor for private key stored in file.
+func LoadDecryptor(path string) (*Decryptor, error) {
+	key, err := LoadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewDecryptor(key)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(path + ": no PEM data found")
+	}
+	return block, nil
+}
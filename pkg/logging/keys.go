@@ -15,11 +15,23 @@ const (
 	// MetricIDKey is used to track metrics by name.
 	MetricIDKey = "metric_name"
 
+	// MetricLabelsKey is used to track metrics by its labels.
+	MetricLabelsKey = "metric_labels"
+
 	// MetricTypeKey is used to track metrics by its type.
 	MetricTypeKey = "metric_type"
 
 	// MetricValueKey is used for debug purposes, represents metrics value.
 	MetricValueKey = "metric_value"
+
+	// AlertRuleKey is used to track alerts by rule name.
+	AlertRuleKey = "alert_rule"
+
+	// AlertStateKey is used to track alerts lifecycle.
+	AlertStateKey = "alert_state"
+
+	// AgentIDKey is used to track reporting agents.
+	AgentIDKey = "agent_id"
 )
 
 var _ LogCtxProvider = (LoggerCtxUpdate)(nil)
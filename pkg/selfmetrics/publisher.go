@@ -0,0 +1,65 @@
+package selfmetrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+)
+
+var _ pkg.BackgroundService = (*Publisher)(nil)
+
+type (
+	// Sink accepts published metrics, e.g. monitor service.
+	Sink interface {
+		UpdateBulk(ctx context.Context, list metric.List) error
+	}
+
+	// Publisher periodically flushes recorded metrics to sink.
+	Publisher struct {
+		recorder *Recorder
+		sink     Sink
+		interval time.Duration
+	}
+)
+
+// Publish flushes recorded metrics to sink. Metrics are kept by recorder until next publish if sink fails.
+func (p *Publisher) Publish(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(p), logging.WithCID(ctx))
+
+	list := p.recorder.Flush()
+	if len(list) == 0 {
+		return
+	}
+	if err := p.sink.UpdateBulk(logging.SetLogger(ctx, logger), list); err != nil {
+		logger.Err(err).Msg("failed to publish self-metrics")
+		p.recorder.Restore(list)
+		return
+	}
+	logger.Trace().Msgf("%d self-metrics published", len(list))
+}
+
+func (p *Publisher) BackgroundTask() task.Task {
+	if p.interval == 0 {
+		return task.VoidTask
+	}
+	return task.Task(p.Publish).With(task.PeriodicRun(p.interval))
+}
+
+func (p *Publisher) Name() string {
+	return "Self-metrics publisher"
+}
+
+// NewPublisher creates self-metrics publishing service. Metrics are published every cfg.SelfMetricsInterval.
+func NewPublisher(cfg *config.Config, recorder *Recorder, sink Sink) *Publisher {
+	return &Publisher{
+		recorder: recorder,
+		sink:     sink,
+		interval: cfg.SelfMetricsInterval,
+	}
+}
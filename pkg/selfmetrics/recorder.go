@@ -0,0 +1,82 @@
+// Package selfmetrics accumulates monitor server's own metrics, e.g. storage latency or HTTP requests rate, and
+// publishes them along with metrics reported by agents.
+package selfmetrics
+
+import (
+	"sync"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+// LatencyBounds are bucket bounds of latency histograms in seconds.
+var LatencyBounds = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Recorder accumulates metrics until they are flushed. Counters and histograms hold increments since previous flush,
+// so that published metrics are accumulated by metrics storage.
+type Recorder struct {
+	sync.Mutex
+	metrics map[string]*metric.Metric
+}
+
+// Count increments counter identified by ID and labels.
+func (r *Recorder) Count(id string, labels metric.Labels, n int64) {
+	r.Lock()
+	defer r.Unlock()
+	r.add(metric.NewCounterMetric(id, metric.Counter(n)).WithLabels(labels))
+}
+
+// ObserveLatency registers latency in seconds with histogram identified by ID and labels.
+func (r *Recorder) ObserveLatency(id string, labels metric.Labels, seconds float64) {
+	hist := metric.NewHistogram(LatencyBounds...)
+	hist.Observe(seconds)
+
+	r.Lock()
+	defer r.Unlock()
+	r.add(metric.NewHistogramMetric(id, hist).WithLabels(labels))
+}
+
+// Flush returns metrics accumulated since previous flush and resets them.
+func (r *Recorder) Flush() metric.List {
+	r.Lock()
+	defer r.Unlock()
+
+	list := make(metric.List, 0, len(r.metrics))
+	for _, mtr := range r.metrics {
+		list = append(list, mtr)
+	}
+	r.metrics = make(map[string]*metric.Metric)
+	return list
+}
+
+// Restore returns flushed metrics back to recorder, e.g. if they failed to be published.
+func (r *Recorder) Restore(list metric.List) {
+	r.Lock()
+	defer r.Unlock()
+	for _, mtr := range list {
+		r.add(mtr)
+	}
+}
+
+// add accumulates metric. Thread unsafe, should be locked before update.
+func (r *Recorder) add(mtr *metric.Metric) {
+	key := string(mtr.Type()) + "/" + mtr.Key()
+	stored, ok := r.metrics[key]
+	if !ok {
+		r.metrics[key] = mtr
+		return
+	}
+	switch mtr.Type() {
+	case metric.CounterType:
+		*stored.Value.(*metric.Counter) += *mtr.Value.(*metric.Counter)
+	case metric.HistogramType:
+		// bounds are the same for all observations, merge can't fail
+		_ = stored.Value.(*metric.Histogram).Merge(mtr.Value.(*metric.Histogram))
+	default:
+		r.metrics[key] = mtr
+	}
+}
+
+// NewRecorder creates new Recorder object.
+func NewRecorder() *Recorder {
+	return &Recorder{metrics: make(map[string]*metric.Metric)}
+}
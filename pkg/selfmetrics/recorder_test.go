@@ -0,0 +1,73 @@
+package selfmetrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+type sinkStub struct {
+	err  error
+	list metric.List
+}
+
+func (s *sinkStub) UpdateBulk(_ context.Context, list metric.List) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.list = append(s.list, list...)
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	labels := metric.Labels{"op": "Get"}
+	latency := func(values ...float64) metric.Histogram {
+		h := metric.NewHistogram(LatencyBounds...)
+		for _, v := range values {
+			h.Observe(v)
+		}
+		return h
+	}
+
+	r := NewRecorder()
+	r.Count("Calls", labels, 1)
+	r.Count("Calls", labels, 2)
+	r.Count("Calls", nil, 1)
+	r.ObserveLatency("Latency", labels, 0.002)
+	r.ObserveLatency("Latency", labels, 3)
+
+	assert.ElementsMatch(t, metric.List{
+		metric.NewCounterMetric("Calls", 3).WithLabels(labels),
+		metric.NewCounterMetric("Calls", 1),
+		metric.NewHistogramMetric("Latency", latency(0.002, 3)).WithLabels(labels),
+	}, r.Flush())
+	assert.Empty(t, r.Flush(), "metrics are reset on flush")
+
+	r.Count("Calls", labels, 1)
+	r.Restore(metric.List{metric.NewCounterMetric("Calls", 3).WithLabels(labels)})
+	assert.Equal(t, metric.List{metric.NewCounterMetric("Calls", 4).WithLabels(labels)}, r.Flush())
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	sink := &sinkStub{err: errors.New("sink is unavailable")}
+	p := NewPublisher(&config.Config{}, r, sink)
+
+	r.Count("Calls", nil, 1)
+	p.Publish(ctx)
+	assert.Empty(t, sink.list)
+
+	sink.err = nil
+	r.Count("Calls", nil, 1)
+	p.Publish(ctx)
+	assert.Equal(t, metric.List{metric.NewCounterMetric("Calls", 2)}, sink.list, "failed publish is retried")
+
+	p.Publish(ctx)
+	assert.Len(t, sink.list, 1, "nothing to publish")
+}
@@ -0,0 +1,136 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DefaultBackoff is a moderate retry policy suitable for short-lived transient failures.
+var DefaultBackoff = Backoff{
+	Initial:     100 * time.Millisecond,
+	Max:         5 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+	MaxAttempts: 4,
+}
+
+type (
+	// ErrTask is a Task variant that reports its failure.
+	ErrTask func(ctx context.Context) error
+
+	// ErrOption can define some desired ErrTask side effect.
+	ErrOption func(ErrTask) ErrTask
+
+	// Backoff describes retry policy with exponentially growing delays between attempts.
+	Backoff struct {
+		// Initial is a delay before the second attempt.
+		Initial time.Duration
+
+		// Max limits delay between attempts. Unlimited if not set.
+		Max time.Duration
+
+		// Multiplier is a factor delay grows by after each failed attempt. Delay is constant if less than 1.
+		Multiplier float64
+
+		// Jitter randomizes delays within ±Jitter fraction of delay. Should be within [0, 1].
+		Jitter float64
+
+		// MaxAttempts limits total number of attempts including the first one. Unlimited if not set.
+		MaxAttempts int
+	}
+
+	// PermanentError marks failure that is not expected to be fixed by retrying.
+	PermanentError struct {
+		Err error
+	}
+)
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err to stop Retry from rerunning failed task. Returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether any error in err's chain is marked as permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// With wraps receiver with ErrOption from arguments.
+func (t ErrTask) With(options ...ErrOption) ErrTask {
+	task := t
+	for _, opt := range options {
+		task = opt(task)
+	}
+	return task
+}
+
+// Task converts receiver to Task discarding its error. Receiver is expected to handle its errors itself.
+func (t ErrTask) Task() Task {
+	return func(ctx context.Context) {
+		_ = t(ctx)
+	}
+}
+
+// Retry reruns failed ErrTask with delays specified by backoff policy until it succeeds, fails permanently, attempts are
+// exhausted or context is cancelled. The last task error is returned if all attempts failed. Context error is returned if context
+// is cancelled while waiting for the next attempt.
+func Retry(backoff Backoff) ErrOption {
+	return func(task ErrTask) ErrTask {
+		return func(ctx context.Context) error {
+			var err error
+			for attempt := 1; ; attempt++ {
+				if err = task(ctx); err == nil {
+					return nil
+				}
+				if IsPermanent(err) || backoff.MaxAttempts > 0 && attempt >= backoff.MaxAttempts {
+					return err
+				}
+
+				timer := time.NewTimer(backoff.Delay(attempt))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+// Delay returns randomized delay after specified failed attempt.
+func (b Backoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	switch {
+	case delay < 0:
+		return 0
+	case delay >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
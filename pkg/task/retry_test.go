@@ -0,0 +1,166 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleRetry() {
+	ctx := context.TODO()
+
+	attempts := 0
+	flaky := ErrTask(func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient failure")
+		}
+		return nil
+	}).With(Retry(Backoff{Initial: 10 * time.Millisecond, Multiplier: 2, MaxAttempts: 5}))
+
+	// the task will be rerun after 10ms and 20ms delays until it succeeds
+	fmt.Println(flaky(ctx), attempts)
+
+	// Output:
+	// <nil> 3
+}
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("failure")
+
+	tests := []struct {
+		name         string
+		backoff      Backoff
+		failures     int
+		permanent    bool
+		timeout      time.Duration
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "Succeeded at once",
+			backoff:      Backoff{Initial: time.Millisecond, MaxAttempts: 3},
+			wantAttempts: 1,
+		},
+		{
+			name:         "Succeeded after retries",
+			backoff:      Backoff{Initial: time.Millisecond, Multiplier: 2, Jitter: 0.5, MaxAttempts: 3},
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "Attempts exhausted",
+			backoff:      Backoff{Initial: time.Millisecond, MaxAttempts: 3},
+			failures:     5,
+			wantErr:      failure,
+			wantAttempts: 3,
+		},
+		{
+			name:         "Permanent failure",
+			backoff:      Backoff{Initial: time.Millisecond, MaxAttempts: 3},
+			failures:     5,
+			permanent:    true,
+			wantErr:      failure,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Context cancelled",
+			backoff:      Backoff{Initial: time.Hour},
+			failures:     5,
+			timeout:      10 * time.Millisecond,
+			wantErr:      context.DeadlineExceeded,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			if tt.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			attempts := 0
+			err := ErrTask(func(context.Context) error {
+				attempts++
+				if attempts <= tt.failures {
+					if tt.permanent {
+						return Permanent(failure)
+					}
+					return failure
+				}
+				return nil
+			}).With(Retry(tt.backoff))(ctx)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{
+			name:    "Initial delay",
+			backoff: Backoff{Initial: time.Second, Multiplier: 2},
+			attempt: 1,
+			min:     time.Second,
+			max:     time.Second,
+		},
+		{
+			name:    "Exponential growth",
+			backoff: Backoff{Initial: time.Second, Multiplier: 2},
+			attempt: 4,
+			min:     8 * time.Second,
+			max:     8 * time.Second,
+		},
+		{
+			name:    "Constant delay",
+			backoff: Backoff{Initial: time.Second},
+			attempt: 4,
+			min:     time.Second,
+			max:     time.Second,
+		},
+		{
+			name:    "Max delay",
+			backoff: Backoff{Initial: time.Second, Multiplier: 2, Max: 5 * time.Second},
+			attempt: 10,
+			min:     5 * time.Second,
+			max:     5 * time.Second,
+		},
+		{
+			name:    "Jitter",
+			backoff: Backoff{Initial: time.Second, Multiplier: 2, Jitter: 0.1},
+			attempt: 2,
+			min:     1800 * time.Millisecond,
+			max:     2200 * time.Millisecond,
+		},
+		{
+			name:    "Overflow",
+			backoff: Backoff{Initial: time.Second, Multiplier: 10},
+			attempt: 100,
+			min:     time.Duration(1<<63 - 1),
+			max:     time.Duration(1<<63 - 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				delay := tt.backoff.Delay(tt.attempt)
+				assert.GreaterOrEqual(t, delay, tt.min)
+				assert.LessOrEqual(t, delay, tt.max)
+			}
+		})
+	}
+}
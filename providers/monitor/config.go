@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
 )
 
 const DefaultTimeout = 30 * time.Second
@@ -11,6 +12,9 @@ const DefaultTimeout = 30 * time.Second
 type Config struct {
 	*config.Config
 	Timeout time.Duration
+
+	// Agent identifies reporting agent to monitor server. Reports are anonymous if not set.
+	Agent *agent.Info
 }
 
 func NewConfig(cfg *config.Config) *Config {
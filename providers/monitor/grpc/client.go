@@ -0,0 +1,222 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
+)
+
+const clientName = "Monitor gRPC Client"
+
+var (
+	_ monitor.Provider = (*grpcClient)(nil)
+	_ monitor.Pusher   = (*grpcClient)(nil)
+	_ io.Closer        = (*grpcClient)(nil)
+)
+
+type grpcClient struct {
+	pb.MonitorClient
+	conn    *grpc.ClientConn
+	key     string
+	timeout time.Duration
+}
+
+func (c *grpcClient) Update(ctx context.Context, mtr *metric.Metric) error {
+	m, err := c.newMetric(mtr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	_, err = c.MonitorClient.Update(ctx, &pb.UpdateRequest{Metric: m})
+	return err
+}
+
+func (c *grpcClient) UpdateBulk(ctx context.Context, list metric.List) error {
+	req, err := c.newUpdateBulkRequest(list)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	_, err = c.MonitorClient.UpdateBulk(ctx, req)
+	return err
+}
+
+func (c *grpcClient) Value(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (metric.Value, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := c.MonitorClient.Value(ctx, &pb.ValueRequest{
+		Id:       id,
+		Type:     string(typ),
+		Instance: labels.Instance(),
+		Labels:   labels.WithInstance(""),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m := resp.Metric.ToModel()
+	if m == nil {
+		return nil, errors.New("response has no metric")
+	}
+	if len(c.key) != 0 {
+		if err := m.Verify(c.key); err != nil {
+			return nil, fmt.Errorf("response verification failed: %w", err)
+		}
+	}
+	mtr := m.ToCanonical()
+	if mtr == nil {
+		return nil, fmt.Errorf("response has metric of unknown type %s", m.MType)
+	}
+	return mtr.Value, nil
+}
+
+// Push streams metrics batches to monitor server within single call. Batches are applied by server one by one
+// as they arrive, so batches sent before failure remain applied.
+func (c *grpcClient) Push(ctx context.Context, batches ...metric.List) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	stream, err := c.MonitorClient.Push(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, list := range batches {
+		req, err := c.newUpdateBulkRequest(list)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				// actual error will be returned by CloseAndRecv
+				break
+			}
+			return err
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	if resp.Batches != uint64(len(batches)) {
+		return fmt.Errorf("server has accepted %d of %d batches", resp.Batches, len(batches))
+	}
+	return nil
+}
+
+// Close closes underlying client connection.
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *grpcClient) newMetric(mtr *metric.Metric) (*pb.Metric, error) {
+	m := model.NewFromCanonical(mtr)
+	if m == nil {
+		return nil, fmt.Errorf("unsupported metric: %v", mtr)
+	}
+	if len(c.key) != 0 {
+		if err := m.Sign(c.key); err != nil {
+			return nil, err
+		}
+	}
+	return pb.NewMetric(m), nil
+}
+
+func (c *grpcClient) newUpdateBulkRequest(list metric.List) (*pb.UpdateBulkRequest, error) {
+	req := &pb.UpdateBulkRequest{Metrics: make([]*pb.Metric, 0, len(list))}
+	for _, mtr := range list {
+		m, err := c.newMetric(mtr)
+		if err != nil {
+			return nil, err
+		}
+		req.Metrics = append(req.Metrics, m)
+	}
+	return req, nil
+}
+
+// NewClient creates gRPC monitor provider connected to monitor server gRPC address. Connection is established lazily.
+// Payloads are not encrypted, so client refuses to be created if public key is set not to send metrics in plain text.
+func NewClient(cfg *monitor.Config, opts ...grpc.DialOption) (monitor.Provider, error) {
+	if len(cfg.GRPCAddress) == 0 {
+		return nil, errors.New("gRPC address is not specified")
+	}
+	if len(cfg.CryptoKey) != 0 {
+		return nil, errors.New("gRPC payloads can't be encrypted, public key is set along with gRPC address")
+	}
+
+	defaults := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(unaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(streamClientInterceptor),
+	}
+	if cfg.Agent != nil {
+		defaults = append(defaults, identityInterceptors(cfg.Agent)...)
+	}
+	opts = append(defaults, opts...)
+	conn, err := grpc.Dial(cfg.GRPCAddress, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client connection: %w", err)
+	}
+	return &grpcClient{
+		MonitorClient: pb.NewMonitorClient(conn),
+		conn:          conn,
+		key:           cfg.Key,
+		timeout:       cfg.Timeout,
+	}, nil
+}
+
+func unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, logger := outgoingContext(ctx)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	logger.Trace().Err(err).Msg(method)
+	return err
+}
+
+func streamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, logger := outgoingContext(ctx)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	logger.Trace().Err(err).Msg(method)
+	return stream, err
+}
+
+// outgoingContext passes correlation ID to server within call metadata.
+func outgoingContext(ctx context.Context) (context.Context, zerolog.Logger) {
+	ctx, cid := logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(clientName), logging.WithCID(ctx))
+	return metadata.AppendToOutgoingContext(ctx, logging.CorrelationIDHeader, cid), logger
+}
+
+// identityInterceptors attach agent identity to metadata of every call.
+func identityInterceptors(info *agent.Info) []grpc.DialOption {
+	kv := make([]string, 0)
+	for name, values := range model.NewAgentHeader(info) {
+		kv = append(kv, strings.ToLower(name), values[0])
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, kv...), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, kv...), desc, cc, method, opts...)
+		}),
+	}
+}
@@ -0,0 +1,241 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+)
+
+const serverKey = "secret"
+
+func TestGrpcClientUpdateWithSignVerification(t *testing.T) {
+	listener := newTestServer(t, &mockServer{key: serverKey})
+
+	tests := []struct {
+		name    string
+		key     string
+		metric  *metric.Metric
+		wantErr bool
+	}{
+		{
+			name:   "Basic test",
+			metric: metric.NewGaugeMetric("foo", 0),
+			key:    serverKey,
+		},
+		{
+			name:   "Labeled metric",
+			metric: metric.NewCounterMetric("foo", 1).WithLabels(metric.Labels{"cpu": "1"}),
+			key:    serverKey,
+		},
+		{
+			name:    "Verification failed",
+			metric:  metric.NewGaugeMetric("foo", 0),
+			key:     "unknown",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, listener, tt.key)
+
+			err := client.Update(context.TODO(), tt.metric)
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGrpcClientValueWithSignVerification(t *testing.T) {
+	listener := newTestServer(t, &mockServer{key: serverKey})
+
+	tests := []struct {
+		name    string
+		key     string
+		id      string
+		typ     metric.Type
+		want    metric.Value
+		wantErr bool
+	}{
+		{
+			name: "Basic test",
+			id:   "foo",
+			typ:  metric.CounterType,
+			key:  serverKey,
+			want: new(metric.Counter),
+		},
+		{
+			name:    "Verification failed",
+			id:      "foo",
+			typ:     metric.CounterType,
+			key:     "unknown",
+			wantErr: true,
+		},
+		{
+			name:    "Not found",
+			id:      "",
+			typ:     metric.CounterType,
+			key:     serverKey,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, listener, tt.key)
+
+			value, err := client.Value(context.TODO(), tt.id, tt.typ, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, value)
+			}
+		})
+	}
+}
+
+func TestGrpcClientPush(t *testing.T) {
+	srv := &mockServer{key: serverKey}
+	listener := newTestServer(t, srv)
+
+	client := newTestClient(t, listener, serverKey)
+	pusher, ok := client.(monitor.Pusher)
+	require.True(t, ok, "client must implement pusher")
+
+	ctx, _ := logging.SetCID(context.TODO(), "test-cid")
+	err := pusher.Push(ctx,
+		metric.List{metric.NewGaugeMetric("foo", 1), metric.NewCounterMetric("bar", 2)},
+		metric.List{metric.NewGaugeMetric("baz", 3)})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, srv.pushed)
+	assert.Equal(t, "test-cid", srv.cid)
+}
+
+type mockServer struct {
+	pb.UnimplementedMonitorServer
+	key    string
+	pushed int
+	cid    string
+}
+
+func (s *mockServer) Update(_ context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	if err := req.Metric.ToModel().Verify(s.key); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.UpdateResponse{}, nil
+}
+
+func (s *mockServer) Value(_ context.Context, req *pb.ValueRequest) (*pb.ValueResponse, error) {
+	if len(req.Id) == 0 {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+	var delta int64
+	m := &pb.Metric{Id: req.Id, Type: req.Type, Labels: req.Labels, Delta: &delta}
+	body := m.ToModel()
+	if err := body.Sign(s.key); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.ValueResponse{Metric: pb.NewMetric(body)}, nil
+}
+
+func (s *mockServer) Push(stream pb.Monitor_PushServer) error {
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if values := md.Get(logging.CorrelationIDHeader); len(values) != 0 {
+			s.cid = values[0]
+		}
+	}
+
+	resp := &pb.PushResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		for _, m := range req.Metrics {
+			if err := m.ToModel().Verify(s.key); err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+		s.pushed += len(req.Metrics)
+		resp.Batches++
+		resp.Metrics += uint64(len(req.Metrics))
+	}
+}
+
+func newTestServer(t *testing.T, srv pb.MonitorServer) *bufconn.Listener {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterMonitorServer(server, srv)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener
+}
+
+func newTestClient(t *testing.T, listener *bufconn.Listener, key string) monitor.Provider {
+	client, err := NewClient(&monitor.Config{
+		Config:  &config.Config{GRPCAddress: "bufnet", Key: key},
+		Timeout: 1 * time.Second,
+	}, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	require.NoError(t, err, "failed to create client")
+	t.Cleanup(func() { _ = client.(io.Closer).Close() })
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "Basic test",
+			cfg:  &config.Config{GRPCAddress: "localhost:3200"},
+		},
+		{
+			name:    "Address is not specified",
+			cfg:     &config.Config{},
+			wantErr: true,
+		},
+		{
+			name:    "Encryption is not supported",
+			cfg:     &config.Config{GRPCAddress: "localhost:3200", CryptoKey: "public.pem"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&monitor.Config{Config: tt.cfg, Timeout: time.Second})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, client.(io.Closer).Close())
+		})
+	}
+}
@@ -0,0 +1,4 @@
+// Package pb contains protocol buffers schema of monitor service and its generated gRPC bindings.
+package pb
+
+//go:generate buf generate --template buf.gen.yaml
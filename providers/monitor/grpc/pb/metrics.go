@@ -0,0 +1,55 @@
+package pb
+
+import (
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
+)
+
+// ToModel converts protobuf message to transport model. Sign verification and validation is done by model.
+func (m *Metric) ToModel() *model.Metrics {
+	if m == nil {
+		return nil
+	}
+	mtr := &model.Metrics{
+		ID:       m.Id,
+		MType:    m.Type,
+		Instance: m.Instance,
+		Labels:   m.Labels,
+		Delta:    m.Delta,
+		Value:    m.Value,
+		Hash:     m.Hash,
+	}
+	if h := m.Histogram; h != nil {
+		mtr.Histogram = &model.Histogram{
+			Bounds: h.Bounds,
+			Counts: h.Counts,
+			Sum:    h.Sum,
+			Count:  h.Count,
+		}
+	}
+	return mtr
+}
+
+// NewMetric converts transport model to protobuf message.
+func NewMetric(m *model.Metrics) *Metric {
+	if m == nil {
+		return nil
+	}
+	mtr := &Metric{
+		Id:       m.ID,
+		Type:     m.MType,
+		Instance: m.Instance,
+		Labels:   m.Labels,
+		Delta:    m.Delta,
+		Value:    m.Value,
+		Hash:     m.Hash,
+	}
+	if h := m.Histogram; h != nil {
+		mtr.Histogram = &Histogram{
+			Bounds: h.Bounds,
+			Counts: h.Counts,
+			Sum:    h.Sum,
+			Count:  h.Count,
+		}
+	}
+	return mtr
+}
@@ -0,0 +1,256 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: monitor.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// MonitorClient is the client API for Monitor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MonitorClient interface {
+	// Update registers or updates single metric.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
+	// UpdateBulk registers or updates several metrics at once.
+	UpdateBulk(ctx context.Context, in *UpdateBulkRequest, opts ...grpc.CallOption) (*UpdateBulkResponse, error)
+	// Value queries actual metric value.
+	Value(ctx context.Context, in *ValueRequest, opts ...grpc.CallOption) (*ValueResponse, error)
+	// Push accepts stream of metrics batches. Every batch is applied as soon as it is received.
+	Push(ctx context.Context, opts ...grpc.CallOption) (Monitor_PushClient, error)
+}
+
+type monitorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMonitorClient(cc grpc.ClientConnInterface) MonitorClient {
+	return &monitorClient{cc}
+}
+
+func (c *monitorClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error) {
+	out := new(UpdateResponse)
+	err := c.cc.Invoke(ctx, "/monitor.Monitor/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitorClient) UpdateBulk(ctx context.Context, in *UpdateBulkRequest, opts ...grpc.CallOption) (*UpdateBulkResponse, error) {
+	out := new(UpdateBulkResponse)
+	err := c.cc.Invoke(ctx, "/monitor.Monitor/UpdateBulk", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitorClient) Value(ctx context.Context, in *ValueRequest, opts ...grpc.CallOption) (*ValueResponse, error) {
+	out := new(ValueResponse)
+	err := c.cc.Invoke(ctx, "/monitor.Monitor/Value", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *monitorClient) Push(ctx context.Context, opts ...grpc.CallOption) (Monitor_PushClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Monitor_ServiceDesc.Streams[0], "/monitor.Monitor/Push", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &monitorPushClient{stream}
+	return x, nil
+}
+
+type Monitor_PushClient interface {
+	Send(*UpdateBulkRequest) error
+	CloseAndRecv() (*PushResponse, error)
+	grpc.ClientStream
+}
+
+type monitorPushClient struct {
+	grpc.ClientStream
+}
+
+func (x *monitorPushClient) Send(m *UpdateBulkRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *monitorPushClient) CloseAndRecv() (*PushResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PushResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MonitorServer is the server API for Monitor service.
+// All implementations must embed UnimplementedMonitorServer
+// for forward compatibility
+type MonitorServer interface {
+	// Update registers or updates single metric.
+	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
+	// UpdateBulk registers or updates several metrics at once.
+	UpdateBulk(context.Context, *UpdateBulkRequest) (*UpdateBulkResponse, error)
+	// Value queries actual metric value.
+	Value(context.Context, *ValueRequest) (*ValueResponse, error)
+	// Push accepts stream of metrics batches. Every batch is applied as soon as it is received.
+	Push(Monitor_PushServer) error
+	mustEmbedUnimplementedMonitorServer()
+}
+
+// UnimplementedMonitorServer must be embedded to have forward compatible implementations.
+type UnimplementedMonitorServer struct {
+}
+
+func (UnimplementedMonitorServer) Update(context.Context, *UpdateRequest) (*UpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedMonitorServer) UpdateBulk(context.Context, *UpdateBulkRequest) (*UpdateBulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBulk not implemented")
+}
+func (UnimplementedMonitorServer) Value(context.Context, *ValueRequest) (*ValueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Value not implemented")
+}
+func (UnimplementedMonitorServer) Push(Monitor_PushServer) error {
+	return status.Errorf(codes.Unimplemented, "method Push not implemented")
+}
+func (UnimplementedMonitorServer) mustEmbedUnimplementedMonitorServer() {}
+
+// UnsafeMonitorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MonitorServer will
+// result in compilation errors.
+type UnsafeMonitorServer interface {
+	mustEmbedUnimplementedMonitorServer()
+}
+
+func RegisterMonitorServer(s grpc.ServiceRegistrar, srv MonitorServer) {
+	s.RegisterService(&Monitor_ServiceDesc, srv)
+}
+
+func _Monitor_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/monitor.Monitor/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Monitor_UpdateBulk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBulkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServer).UpdateBulk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/monitor.Monitor/UpdateBulk",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServer).UpdateBulk(ctx, req.(*UpdateBulkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Monitor_Value_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServer).Value(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/monitor.Monitor/Value",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServer).Value(ctx, req.(*ValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Monitor_Push_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MonitorServer).Push(&monitorPushServer{stream})
+}
+
+type Monitor_PushServer interface {
+	SendAndClose(*PushResponse) error
+	Recv() (*UpdateBulkRequest, error)
+	grpc.ServerStream
+}
+
+type monitorPushServer struct {
+	grpc.ServerStream
+}
+
+func (x *monitorPushServer) SendAndClose(m *PushResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *monitorPushServer) Recv() (*UpdateBulkRequest, error) {
+	m := new(UpdateBulkRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Monitor_ServiceDesc is the grpc.ServiceDesc for Monitor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Monitor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "monitor.Monitor",
+	HandlerType: (*MonitorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Update",
+			Handler:    _Monitor_Update_Handler,
+		},
+		{
+			MethodName: "UpdateBulk",
+			Handler:    _Monitor_UpdateBulk_Handler,
+		},
+		{
+			MethodName: "Value",
+			Handler:    _Monitor_Value_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Push",
+			Handler:       _Monitor_Push_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "monitor.proto",
+}
@@ -1,12 +1,18 @@
 package http
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 
 	"github.com/go-resty/resty/v2"
 
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/encryption"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
 )
 
@@ -21,9 +27,63 @@ func NewClient(cfg *monitor.Config, name string) (*resty.Client, error) {
 	client.SetTimeout(cfg.Timeout)
 	client.OnBeforeRequest(requestHandler(cfg, name))
 	client.OnAfterResponse(responseHandler(cfg, name))
+
+	if len(cfg.CryptoKey) != 0 {
+		encryptor, err := encryption.LoadEncryptor(cfg.CryptoKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %w", err)
+		}
+		client.SetTransport(&encryptingTransport{
+			RoundTripper: client.GetClient().Transport,
+			encryptor:    encryptor,
+		})
+	}
 	return client, err
 }
 
+// CheckStatus returns error if monitor responded with status other than OK. Client errors are permanent since monitor
+// rejects the same request again, except of timeout and throttling responses.
+func CheckStatus(code int) error {
+	err := httplib.MustBeOK(code)
+	if err != nil && code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests {
+		return task.Permanent(err)
+	}
+	return err
+}
+
+// encryptingTransport seals request bodies with monitor server's public key.
+type encryptingTransport struct {
+	http.RoundTripper
+	encryptor *encryption.Encryptor
+}
+
+func (t *encryptingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	plain, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if err := req.Body.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close request body: %w", err)
+	}
+	msg, err := t.encryptor.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := req.Clone(req.Context())
+	sealed.Header.Set(encryption.Header, encryption.Scheme)
+	sealed.Body = io.NopCloser(bytes.NewReader(msg))
+	sealed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(msg)), nil
+	}
+	sealed.ContentLength = int64(len(msg))
+	return t.RoundTripper.RoundTrip(sealed)
+}
+
 func getURL(cfg *monitor.Config) (*url.URL, error) {
 	u, err := url.Parse(cfg.Address)
 	if err != nil {
@@ -8,7 +8,6 @@ import (
 	"github.com/go-resty/resty/v2"
 
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
-	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/http"
 )
@@ -22,13 +21,15 @@ type httpClient struct {
 }
 
 func (c httpClient) Update(ctx context.Context, mtr *metric.Metric) error {
-	resp, err := c.R().
-		SetContext(ctx).
-		Post(path.Join("update", mtr.Type().String(), mtr.ID, mtr.Value.String()))
+	req := c.R().SetContext(ctx)
+	if len(mtr.Labels) != 0 {
+		req.SetQueryParam("labels", mtr.Labels.String())
+	}
+	resp, err := req.Post(path.Join("update", mtr.Type().String(), mtr.ID, mtr.Value.String()))
 	if err != nil {
 		return err
 	}
-	if err := httplib.MustBeOK(resp.StatusCode()); err != nil {
+	if err := http.CheckStatus(resp.StatusCode()); err != nil {
 		return err
 	}
 	return nil
@@ -38,14 +39,16 @@ func (c httpClient) UpdateBulk(context.Context, metric.List) error {
 	return errors.New("unsupported operation")
 }
 
-func (c httpClient) Value(ctx context.Context, id string, typ metric.Type) (metric.Value, error) {
-	resp, err := c.R().
-		SetContext(ctx).
-		Get(path.Join("value", typ.String(), id))
+func (c httpClient) Value(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (metric.Value, error) {
+	req := c.R().SetContext(ctx)
+	if len(labels) != 0 {
+		req.SetQueryParam("labels", labels.String())
+	}
+	resp, err := req.Get(path.Join("value", typ.String(), id))
 	if err != nil {
 		return nil, err
 	}
-	if err := httplib.MustBeOK(resp.StatusCode()); err != nil {
+	if err := http.CheckStatus(resp.StatusCode()); err != nil {
 		return nil, err
 	}
 	return typ.Parse(string(resp.Body()))
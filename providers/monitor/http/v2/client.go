@@ -9,7 +9,6 @@ import (
 	"github.com/go-resty/resty/v2"
 
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
-	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/http"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
@@ -35,7 +34,7 @@ func (c httpClient) Update(ctx context.Context, mtr *metric.Metric) error {
 	if err != nil {
 		return err
 	}
-	if err = httplib.MustBeOK(resp.StatusCode()); err != nil {
+	if err = http.CheckStatus(resp.StatusCode()); err != nil {
 		return err
 	}
 	return nil
@@ -56,23 +55,20 @@ func (c httpClient) UpdateBulk(ctx context.Context, list metric.List) error {
 	if err != nil {
 		return err
 	}
-	if err = httplib.MustBeOK(resp.StatusCode()); err != nil {
+	if err = http.CheckStatus(resp.StatusCode()); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c httpClient) Value(ctx context.Context, id string, typ metric.Type) (value metric.Value, err error) {
-	mtr := &model.Metrics{
-		ID:    id,
-		MType: string(typ),
-	}
+func (c httpClient) Value(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (value metric.Value, err error) {
+	mtr := model.NewMetricsKey(id, typ, labels)
 
 	var resp *resty.Response
 	if resp, err = c.R().SetContext(ctx).SetBody(mtr).Post("value"); err != nil {
 		return
 	}
-	if err = httplib.MustBeOK(resp.StatusCode()); err != nil {
+	if err = http.CheckStatus(resp.StatusCode()); err != nil {
 		return
 	}
 
@@ -94,8 +90,14 @@ func NewClient(cfg *monitor.Config) (monitor.Provider, error) {
 	if err != nil {
 		return nil, err
 	}
+	c.SetHeader("Content-Type", "application/json")
+	if cfg.Agent != nil {
+		for name, values := range model.NewAgentHeader(cfg.Agent) {
+			c.SetHeader(name, values[0])
+		}
+	}
 	return &httpClient{
-		Client: c.SetHeader("Content-Type", "application/json"),
+		Client: c,
 		key:    cfg.Key,
 	}, nil
 }
@@ -2,9 +2,17 @@ package v2
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -12,7 +20,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/encryption"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/model"
 )
@@ -94,7 +105,7 @@ func TestHttpClientValueWithSignVerification(t *testing.T) {
 			client, err := newTestClient(server.URL, tt.key)
 			require.NoError(t, err, "failed to create client")
 
-			_, err = client.Value(context.TODO(), tt.id, tt.typ)
+			_, err = client.Value(context.TODO(), tt.id, tt.typ, nil)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -139,3 +150,103 @@ func mockValueHandler(t *testing.T, key string) http.HandlerFunc {
 		require.NoError(t, json.NewEncoder(writer).Encode(m), "failed to marshall response")
 	}
 }
+
+func TestHttpClientEncryption(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKeyFile := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	decryptor, err := encryption.NewDecryptor(key)
+	require.NoError(t, err)
+
+	var got []*model.Metrics
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		defer request.Body.Close()
+		require.Equal(t, encryption.Scheme, request.Header.Get(encryption.Header))
+
+		msg, err := io.ReadAll(request.Body)
+		require.NoError(t, err)
+		plain, err := decryptor.Decrypt(msg)
+		require.NoError(t, err, "failed to decrypt body")
+		require.NoError(t, json.Unmarshal(plain, &got), "failed to decode body")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&monitor.Config{
+		Config:  &config.Config{Address: server.URL, CryptoKey: publicKeyFile},
+		Timeout: 1 * time.Second,
+	})
+	require.NoError(t, err, "failed to create client")
+
+	require.NoError(t, client.UpdateBulk(context.TODO(), metric.List{metric.NewGaugeMetric("foo", 1)}))
+	require.Len(t, got, 1)
+	assert.Equal(t, "foo", got[0].ID)
+}
+
+func TestHttpClientAgentIdentity(t *testing.T) {
+	info := &agent.Info{
+		ID:             "foo",
+		Hostname:       "host",
+		Version:        "1.0",
+		StartedAt:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		ReportInterval: 10 * time.Second,
+	}
+
+	var got *agent.Info
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var err error
+		got, err = model.AgentFromHeader(request.Header)
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&monitor.Config{
+		Config:  &config.Config{Address: server.URL},
+		Timeout: 1 * time.Second,
+		Agent:   info,
+	})
+	require.NoError(t, err, "failed to create client")
+
+	require.NoError(t, client.UpdateBulk(context.TODO(), metric.List{metric.NewGaugeMetric("foo", 1)}))
+	assert.Equal(t, info, got)
+}
+
+func TestHttpClientPermanentFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantPermanent bool
+	}{
+		{
+			name:          "Bad request",
+			status:        http.StatusBadRequest,
+			wantPermanent: true,
+		},
+		{
+			name:   "Too many requests",
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "Internal server error",
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client, err := newTestClient(server.URL, "")
+			require.NoError(t, err, "failed to create client")
+
+			err = client.UpdateBulk(context.TODO(), metric.List{metric.NewGaugeMetric("foo", 1)})
+			require.Error(t, err)
+			assert.Equal(t, tt.wantPermanent, task.IsPermanent(err))
+		})
+	}
+}
@@ -10,5 +10,10 @@ type Provider interface {
 	Update(ctx context.Context, mtr *metric.Metric) error
 	UpdateBulk(ctx context.Context, list metric.List) error
 
-	Value(ctx context.Context, id string, typ metric.Type) (metric.Value, error)
+	Value(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (metric.Value, error)
+}
+
+// Pusher is implemented by providers that are able to stream several metrics batches within single call.
+type Pusher interface {
+	Push(ctx context.Context, batches ...metric.List) error
 }
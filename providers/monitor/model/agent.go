@@ -0,0 +1,69 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+)
+
+// Agent identity is transported within request headers (gRPC metadata) along with reported metrics.
+const (
+	AgentIDHeader             = "X-Agent-ID"
+	AgentHostnameHeader       = "X-Agent-Hostname"
+	AgentVersionHeader        = "X-Agent-Version"
+	AgentStartedAtHeader      = "X-Agent-Started-At"
+	AgentReportIntervalHeader = "X-Agent-Report-Interval"
+)
+
+// NewAgentHeader encodes agent identity to headers.
+func NewAgentHeader(info *agent.Info) http.Header {
+	header := make(http.Header)
+	header.Set(AgentIDHeader, info.ID)
+	if len(info.Hostname) != 0 {
+		header.Set(AgentHostnameHeader, info.Hostname)
+	}
+	if len(info.Version) != 0 {
+		header.Set(AgentVersionHeader, info.Version)
+	}
+	if !info.StartedAt.IsZero() {
+		header.Set(AgentStartedAtHeader, info.StartedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if info.ReportInterval != 0 {
+		header.Set(AgentReportIntervalHeader, info.ReportInterval.String())
+	}
+	return header
+}
+
+// AgentFromHeader decodes agent identity from headers. Returns nil if headers have no agent identity.
+func AgentFromHeader(header http.Header) (*agent.Info, error) {
+	id := header.Get(AgentIDHeader)
+	if len(id) == 0 {
+		return nil, nil
+	}
+
+	info := &agent.Info{
+		ID:       id,
+		Hostname: header.Get(AgentHostnameHeader),
+		Version:  header.Get(AgentVersionHeader),
+	}
+	if s := header.Get(AgentStartedAtHeader); len(s) != 0 {
+		startedAt, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agent start time: %w", err)
+		}
+		info.StartedAt = startedAt
+	}
+	if s := header.Get(AgentReportIntervalHeader); len(s) != 0 {
+		interval, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agent report interval: %w", err)
+		}
+		info.ReportInterval = interval
+	}
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
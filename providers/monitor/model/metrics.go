@@ -13,13 +13,25 @@ import (
 
 var _ json.Unmarshaler = (*Metrics)(nil)
 
-type Metrics struct {
-	ID    string   `json:"id"`              // metric name
-	MType string   `json:"type"`            // metric type is enum value {"counter", "gauge"}
-	Delta *int64   `json:"delta,omitempty"` // metric measure if MType is "counter"
-	Value *float64 `json:"value,omitempty"` // metric measure if MType is "gauge"
-	Hash  string   `json:"hash,omitempty"`  // packet hash sum
-}
+type (
+	Metrics struct {
+		ID        string            `json:"id"`                  // metric name
+		MType     string            `json:"type"`                // metric type is enum value {"counter", "gauge", "histogram"}
+		Instance  string            `json:"instance,omitempty"`  // metric source instance, part of metric identity
+		Labels    map[string]string `json:"labels,omitempty"`    // metric dimensions, part of metric identity
+		Delta     *int64            `json:"delta,omitempty"`     // metric measure if MType is "counter"
+		Value     *float64          `json:"value,omitempty"`     // metric measure if MType is "gauge"
+		Histogram *Histogram        `json:"histogram,omitempty"` // metric measure if MType is "histogram"
+		Hash      string            `json:"hash,omitempty"`      // packet hash sum
+	}
+
+	Histogram struct {
+		Bounds []float64 `json:"bounds"` // buckets upper bounds (+Inf bucket is implied)
+		Counts []uint64  `json:"counts"` // buckets observations counts including +Inf bucket
+		Sum    float64   `json:"sum"`    // sum of observations
+		Count  uint64    `json:"count"`  // total count of observations
+	}
+)
 
 func (m *Metrics) Sign(key string) error {
 	hash, err := m.calcHash([]byte(key))
@@ -46,13 +58,16 @@ func (m Metrics) Verify(key string) error {
 }
 
 func (m Metrics) String() string {
+	key := m.key()
 	switch metric.Type(m.MType) {
 	case metric.GaugeType:
-		return fmt.Sprintf("%s/%s/%v", m.ID, m.MType, m.Value)
+		return fmt.Sprintf("%s/%s/%v", key, m.MType, m.Value)
 	case metric.CounterType:
-		return fmt.Sprintf("%s/%s/%v", m.ID, m.MType, m.Delta)
+		return fmt.Sprintf("%s/%s/%v", key, m.MType, m.Delta)
+	case metric.HistogramType:
+		return fmt.Sprintf("%s/%s/%v", key, m.MType, m.Histogram)
 	}
-	return fmt.Sprintf("unknown:%s/%s", m.ID, m.MType)
+	return fmt.Sprintf("unknown:%s/%s", key, m.MType)
 }
 
 func (m *Metrics) UnmarshalJSON(bytes []byte) error {
@@ -88,26 +103,34 @@ func (m *Metrics) UnmarshalJSON(bytes []byte) error {
 }
 
 func (m Metrics) ToCanonical() *metric.Metric {
+	var mtr *metric.Metric
 	switch metric.Type(m.MType) {
 	case metric.GaugeType:
 		if m.Value == nil {
-			return metric.NewGaugeMetric(m.ID, metric.Gauge(0))
+			mtr = metric.NewGaugeMetric(m.ID, metric.Gauge(0))
+		} else {
+			mtr = metric.NewGaugeMetric(m.ID, metric.Gauge(*m.Value))
 		}
-		return metric.NewGaugeMetric(m.ID, metric.Gauge(*m.Value))
 	case metric.CounterType:
 		if m.Delta == nil {
-			return metric.NewCounterMetric(m.ID, metric.Counter(0))
+			mtr = metric.NewCounterMetric(m.ID, metric.Counter(0))
+		} else {
+			mtr = metric.NewCounterMetric(m.ID, metric.Counter(*m.Delta))
 		}
-		return metric.NewCounterMetric(m.ID, metric.Counter(*m.Delta))
+	case metric.HistogramType:
+		if m.Histogram == nil {
+			mtr = metric.NewHistogramMetric(m.ID, metric.NewHistogram())
+		} else {
+			mtr = metric.NewHistogramMetric(m.ID, m.Histogram.ToCanonical())
+		}
+	default:
+		return nil
 	}
-	return nil
+	return mtr.WithLabels(m.labels())
 }
 
 func NewFromCanonical(mtr *metric.Metric) *Metrics {
-	m := &Metrics{
-		ID:    mtr.ID,
-		MType: string(mtr.Type()),
-	}
+	m := NewMetricsKey(mtr.ID, mtr.Type(), mtr.Labels)
 
 	switch mtr.Type() {
 	case metric.GaugeType:
@@ -122,17 +145,74 @@ func NewFromCanonical(mtr *metric.Metric) *Metrics {
 			m.Delta = &delta
 			return m
 		}
+	case metric.HistogramType:
+		if hist, ok := mtr.Value.(*metric.Histogram); ok {
+			m.Histogram = NewHistogramFromCanonical(hist)
+			return m
+		}
 	}
 	return nil
 }
 
+func (h Histogram) ToCanonical() metric.Histogram {
+	return metric.Histogram{
+		Bounds: h.Bounds,
+		Counts: h.Counts,
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}.Copy()
+}
+
+func (h *Histogram) String() string {
+	if h == nil {
+		return "<nil>"
+	}
+	return h.ToCanonical().String()
+}
+
+func NewHistogramFromCanonical(hist *metric.Histogram) *Histogram {
+	c := hist.Copy()
+	return &Histogram{
+		Bounds: c.Bounds,
+		Counts: c.Counts,
+		Sum:    c.Sum,
+		Count:  c.Count,
+	}
+}
+
+// NewMetricsKey creates metrics identified by ID, type and labels without value. Instance label is moved to Instance.
+func NewMetricsKey(id string, typ metric.Type, labels metric.Labels) *Metrics {
+	return &Metrics{
+		ID:       id,
+		MType:    string(typ),
+		Instance: labels.Instance(),
+		Labels:   labels.WithInstance(""),
+	}
+}
+
+// labels returns canonical metric labels including instance label.
+func (m Metrics) labels() metric.Labels {
+	return metric.Labels(m.Labels).WithInstance(m.Instance)
+}
+
+// key returns metric ID combined with canonical representation of its labels. Unlabeled metric key is its ID.
+func (m Metrics) key() string {
+	return m.ID + m.labels().String()
+}
+
 func (m Metrics) calcHash(key []byte) ([]byte, error) {
 	var data string
 	switch metric.Type(m.MType) {
 	case metric.CounterType:
-		data = fmt.Sprintf("%s:counter:%d", m.ID, *m.Delta)
+		data = fmt.Sprintf("%s:counter:%d", m.key(), *m.Delta)
 	case metric.GaugeType:
-		data = fmt.Sprintf("%s:gauge:%f", m.ID, *m.Value)
+		data = fmt.Sprintf("%s:gauge:%f", m.key(), *m.Value)
+	case metric.HistogramType:
+		h := m.Histogram
+		if h == nil {
+			return nil, errors.New("hash calc: histogram is not specified")
+		}
+		data = fmt.Sprintf("%s:histogram:%v:%v:%f:%d", m.key(), h.Bounds, h.Counts, h.Sum, h.Count)
 	default:
 		return nil, fmt.Errorf("hash calc: can't calc for unknown type %s", m.MType)
 	}
@@ -161,6 +241,14 @@ func CheckValue(m *Metrics) error {
 		if m.Delta == nil {
 			return fmt.Errorf("metrics validate: for type %v [Delta] must not be empty", m.MType)
 		}
+	case metric.HistogramType:
+		if m.Histogram == nil {
+			return fmt.Errorf("metrics validate: for type %v [Histogram] must not be empty", m.MType)
+		}
+		hist := m.Histogram.ToCanonical()
+		if err := hist.Validate(); err != nil {
+			return fmt.Errorf("metrics validate: %w", err)
+		}
 	}
 	return nil
 }
@@ -172,6 +260,16 @@ func CheckID(m *Metrics) error {
 	return nil
 }
 
+func CheckLabels(m *Metrics) error {
+	if err := metric.Labels(m.Labels).Validate(); err != nil {
+		return fmt.Errorf("metrics validate: %w", err)
+	}
+	if _, ok := m.Labels[metric.InstanceLabel]; ok {
+		return fmt.Errorf("metrics validate: label '%s' is reserved, use instance field", metric.InstanceLabel)
+	}
+	return nil
+}
+
 func CheckType(m *Metrics) error {
 	if err := metric.Type(m.MType).Validate(); err != nil {
 		return fmt.Errorf("metrics validate: type assertion: %w", err)
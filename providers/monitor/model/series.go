@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+type Series struct {
+	ID     string  `json:"id"`     // metric name
+	MType  string  `json:"type"`   // metric type is enum value {"counter", "gauge"}
+	Points []Point `json:"points"` // chronologically ordered metric values
+}
+
+type Point struct {
+	Timestamp time.Time `json:"timestamp"` // point time (start of step interval if aggregated)
+	Value     float64   `json:"value"`     // metric measure (aggregated within step interval if aggregated)
+}
+
+func NewSeries(query *metric.Query, series metric.Series) *Series {
+	s := &Series{
+		ID:     query.ID,
+		MType:  string(query.Type),
+		Points: make([]Point, 0, len(series)),
+	}
+	for _, p := range series {
+		s.Points = append(s.Points, Point{Timestamp: p.Timestamp, Value: p.Value})
+	}
+	return s
+}
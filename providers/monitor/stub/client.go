@@ -19,7 +19,7 @@ func (c *client) UpdateBulk(context.Context, metric.List) error {
 	return nil
 }
 
-func (c *client) Value(_ context.Context, _ string, typ metric.Type) (metric.Value, error) {
+func (c *client) Value(_ context.Context, _ string, typ metric.Type, _ metric.Labels) (metric.Value, error) {
 	return typ.New()
 }
 
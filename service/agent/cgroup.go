@@ -0,0 +1,338 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// cgroupV1Unlimited is a threshold of cgroup v1 limits meaning no limit, e.g. memory.limit_in_bytes is set to
+// page aligned max int64 if memory is unlimited.
+const cgroupV1Unlimited = 1 << 62
+
+var ErrNoCgroup = errors.New("cgroup hierarchy not found")
+
+// Cgroup collects resource usage and limits of container from cgroup hierarchy mounted at root, e.g. /sys/fs/cgroup.
+// Both cgroup v1 and v2 (unified) hierarchies are supported. Metrics of disabled controllers and unset limits are
+// omitted.
+func Cgroup(root string) Collector {
+	return func(ctx context.Context, froze *Froze) error {
+		_, logger := logging.GetOrCreateLogger(ctx)
+
+		var collect func(root string, froze *Froze) error
+		switch {
+		case exists(filepath.Join(root, "cgroup.controllers")):
+			collect = cgroupV2
+		case exists(filepath.Join(root, "memory")), exists(filepath.Join(root, "cpu")), exists(filepath.Join(root, "pids")):
+			collect = cgroupV1
+		default:
+			err := fmt.Errorf("%w at %s", ErrNoCgroup, root)
+			logger.Err(err).Msg("failed to collect metrics")
+			return err
+		}
+
+		if err := collect(root, froze); err != nil {
+			logger.Err(err).Msg("failed to collect metrics")
+			return err
+		}
+		return nil
+	}
+}
+
+func cgroupV2(root string, froze *Froze) error {
+	if usage, ok, err := readCgroupValue(filepath.Join(root, "memory.current")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupMemoryUsage", float64(usage))
+	}
+	if limit, ok, err := readCgroupValue(filepath.Join(root, "memory.max")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupMemoryLimit", float64(limit))
+	}
+
+	stat, ok, err := readCgroupStat(filepath.Join(root, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	if ok {
+		froze.UpdateGauge("CgroupCPUUsage", float64(stat["usage_usec"])/1e6)
+		froze.UpdateGauge("CgroupCPUPeriods", float64(stat["nr_periods"]))
+		froze.UpdateGauge("CgroupCPUThrottledPeriods", float64(stat["nr_throttled"]))
+		froze.UpdateGauge("CgroupCPUThrottledTime", float64(stat["throttled_usec"])/1e6)
+	}
+	if err = cgroupV2CPULimit(filepath.Join(root, "cpu.max"), froze); err != nil {
+		return err
+	}
+
+	if pids, ok, err := readCgroupValue(filepath.Join(root, "pids.current")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupPids", float64(pids))
+	}
+	if limit, ok, err := readCgroupValue(filepath.Join(root, "pids.max")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupPidsLimit", float64(limit))
+	}
+
+	return cgroupV2IO(filepath.Join(root, "io.stat"), froze)
+}
+
+// cgroupV2CPULimit reads CPU bandwidth limit in cores from cpu.max, e.g. "150000 100000" is 1.5 cores.
+func cgroupV2CPULimit(name string, froze *Froze) error {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return fmt.Errorf("%s: unexpected format: %q", name, data)
+	}
+	if fields[0] == "max" {
+		return nil
+	}
+	quota, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	period, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || period == 0 {
+		return fmt.Errorf("%s: invalid period %q", name, fields[1])
+	}
+	froze.UpdateGauge("CgroupCPULimit", float64(quota)/float64(period))
+	return nil
+}
+
+// cgroupV2IO reads per device statistics from io.stat, e.g. "8:0 rbytes=1024 wbytes=512 rios=2 wios=1 ...".
+func cgroupV2IO(name string, froze *Froze) error {
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	ids := map[string]string{
+		"rbytes": "CgroupIOReadBytes",
+		"wbytes": "CgroupIOWriteBytes",
+		"rios":   "CgroupIOReads",
+		"wios":   "CgroupIOWrites",
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		labels := metric.Labels{DeviceLabel: fields[0]}
+		for _, field := range fields[1:] {
+			key, value, ok := cut(field, "=")
+			if !ok {
+				return fmt.Errorf("%s: unexpected field %q", name, field)
+			}
+			id, ok := ids[key]
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			froze.UpdateLabeledGauge(id, labels, float64(v))
+		}
+	}
+	return scanner.Err()
+}
+
+func cgroupV1(root string, froze *Froze) error {
+	if usage, ok, err := readCgroupValue(filepath.Join(root, "memory", "memory.usage_in_bytes")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupMemoryUsage", float64(usage))
+	}
+	if limit, ok, err := readCgroupValue(filepath.Join(root, "memory", "memory.limit_in_bytes")); err != nil {
+		return err
+	} else if ok && limit < cgroupV1Unlimited {
+		froze.UpdateGauge("CgroupMemoryLimit", float64(limit))
+	}
+
+	if usage, ok, err := readCgroupValue(filepath.Join(root, "cpuacct", "cpuacct.usage")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupCPUUsage", float64(usage)/1e9)
+	}
+	stat, ok, err := readCgroupStat(filepath.Join(root, "cpu", "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	if ok {
+		froze.UpdateGauge("CgroupCPUPeriods", float64(stat["nr_periods"]))
+		froze.UpdateGauge("CgroupCPUThrottledPeriods", float64(stat["nr_throttled"]))
+		froze.UpdateGauge("CgroupCPUThrottledTime", float64(stat["throttled_time"])/1e9)
+	}
+	if err = cgroupV1CPULimit(filepath.Join(root, "cpu"), froze); err != nil {
+		return err
+	}
+
+	if pids, ok, err := readCgroupValue(filepath.Join(root, "pids", "pids.current")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupPids", float64(pids))
+	}
+	if limit, ok, err := readCgroupValue(filepath.Join(root, "pids", "pids.max")); err != nil {
+		return err
+	} else if ok {
+		froze.UpdateGauge("CgroupPidsLimit", float64(limit))
+	}
+
+	if err = cgroupV1IO(filepath.Join(root, "blkio", "blkio.throttle.io_service_bytes"), froze,
+		"CgroupIOReadBytes", "CgroupIOWriteBytes"); err != nil {
+		return err
+	}
+	return cgroupV1IO(filepath.Join(root, "blkio", "blkio.throttle.io_serviced"), froze,
+		"CgroupIOReads", "CgroupIOWrites")
+}
+
+// cgroupV1CPULimit reads CPU bandwidth limit in cores from cpu.cfs_quota_us and cpu.cfs_period_us. Negative quota
+// means no limit.
+func cgroupV1CPULimit(dir string, froze *Froze) error {
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	quota, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("cpu.cfs_quota_us: %w", err)
+	}
+	if quota < 0 {
+		return nil
+	}
+	period, ok, err := readCgroupValue(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return err
+	}
+	if !ok || period == 0 {
+		return errors.New("cpu.cfs_period_us: period is not set")
+	}
+	froze.UpdateGauge("CgroupCPULimit", float64(quota)/float64(period))
+	return nil
+}
+
+// cgroupV1IO reads per device statistics of blkio controller, e.g. "8:0 Read 1024". Totals are skipped.
+func cgroupV1IO(name string, froze *Froze, readID, writeID string) error {
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		var id string
+		switch fields[1] {
+		case "Read":
+			id = readID
+		case "Write":
+			id = writeID
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		froze.UpdateLabeledGauge(id, metric.Labels{DeviceLabel: fields[0]}, float64(v))
+	}
+	return scanner.Err()
+}
+
+// readCgroupValue reads single value file. Returns false if file doesn't exist or value is "max", i.e. limit is not set.
+func readCgroupValue(name string) (uint64, bool, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, false, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, true, nil
+}
+
+// readCgroupStat reads flat keyed file, e.g. cpu.stat. Returns false if file doesn't exist.
+func readCgroupStat(name string) (map[string]uint64, bool, error) {
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	stat := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", name, err)
+		}
+		stat[fields[0]] = v
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, false, err
+	}
+	return stat, true, nil
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// cut slices s around the first instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
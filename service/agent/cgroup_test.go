@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestCgroup(t *testing.T) {
+	device := metric.Labels{"device": "8:0"}
+
+	tests := []struct {
+		name    string
+		root    string
+		want    metric.List
+		wantErr bool
+	}{
+		{
+			name: "cgroup v2",
+			root: "v2",
+			want: metric.List{
+				metric.NewGaugeMetric("CgroupMemoryUsage", 104857600),
+				metric.NewGaugeMetric("CgroupMemoryLimit", 268435456),
+				metric.NewGaugeMetric("CgroupCPUUsage", 2.5),
+				metric.NewGaugeMetric("CgroupCPULimit", 0.5),
+				metric.NewGaugeMetric("CgroupCPUPeriods", 100),
+				metric.NewGaugeMetric("CgroupCPUThrottledPeriods", 10),
+				metric.NewGaugeMetric("CgroupCPUThrottledTime", 1.5),
+				metric.NewGaugeMetric("CgroupPids", 12),
+				metric.NewGaugeMetric("CgroupPidsLimit", 100),
+				metric.NewGaugeMetric("CgroupIOReadBytes", 4096).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOWriteBytes", 8192).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOReads", 1).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOWrites", 2).WithLabels(device),
+			},
+		},
+		{
+			name: "cgroup v2 without limits",
+			root: "v2-unlimited",
+			want: metric.List{
+				metric.NewGaugeMetric("CgroupMemoryUsage", 1024),
+			},
+		},
+		{
+			name: "cgroup v1",
+			root: "v1",
+			want: metric.List{
+				metric.NewGaugeMetric("CgroupMemoryUsage", 52428800),
+				metric.NewGaugeMetric("CgroupCPUUsage", 5),
+				metric.NewGaugeMetric("CgroupCPULimit", 2),
+				metric.NewGaugeMetric("CgroupCPUPeriods", 200),
+				metric.NewGaugeMetric("CgroupCPUThrottledPeriods", 20),
+				metric.NewGaugeMetric("CgroupCPUThrottledTime", 3),
+				metric.NewGaugeMetric("CgroupPids", 7),
+				metric.NewGaugeMetric("CgroupIOReadBytes", 1024).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOWriteBytes", 2048).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOReads", 3).WithLabels(device),
+				metric.NewGaugeMetric("CgroupIOWrites", 4).WithLabels(device),
+			},
+		},
+		{
+			name:    "Malformed file",
+			root:    "broken",
+			wantErr: true,
+		},
+		{
+			name:    "No cgroup hierarchy",
+			root:    "missing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			froze := NewFroze()
+			err := Cgroup(filepath.Join("testdata", "cgroup", tt.root))(context.TODO(), froze)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.ElementsMatch(t, tt.want, froze.List())
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/disk"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+const (
+	// DeviceLabel identifies block device in metrics labels, e.g. {device="sda"}.
+	DeviceLabel = "device"
+
+	// MountLabel identifies filesystem by its mount point in metrics labels, e.g. {mount="/var"}.
+	MountLabel = "mount"
+)
+
+// Disk collects I/O counters of block devices selected by devices filter and usage of physical filesystems which mount
+// points are selected by mounts filter. Counters are incremented by values accumulated since previous poll.
+func Disk(devices, mounts *Filter) Collector {
+	counters := make(deltas)
+
+	return func(ctx context.Context, froze *Froze) error {
+		_, logger := logging.GetOrCreateLogger(ctx)
+
+		stats, err := disk.IOCountersWithContext(ctx)
+		if err != nil {
+			logger.Err(err).Msg("failed to collect metrics")
+			return err
+		}
+		for name, s := range stats {
+			if !devices.Match(name) {
+				continue
+			}
+			labels := metric.Labels{DeviceLabel: name}
+			update := func(id string, v uint64) {
+				froze.UpdateLabeledCounter(id, labels, counters.delta(id+labels.String(), v))
+			}
+			update("DiskReadBytes", s.ReadBytes)
+			update("DiskWriteBytes", s.WriteBytes)
+			update("DiskReads", s.ReadCount)
+			update("DiskWrites", s.WriteCount)
+		}
+
+		partitions, err := disk.PartitionsWithContext(ctx, false)
+		if err != nil {
+			logger.Err(err).Msg("failed to collect metrics")
+			return err
+		}
+		for _, p := range partitions {
+			if !mounts.Match(p.Mountpoint) {
+				continue
+			}
+			usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+			if err != nil {
+				// filesystem may be inaccessible, e.g. mounted on host but not in container
+				logger.Err(err).Msgf("failed to collect %s usage", p.Mountpoint)
+				continue
+			}
+			labels := metric.Labels{MountLabel: p.Mountpoint}
+			froze.UpdateLabeledGauge("FilesystemTotal", labels, float64(usage.Total))
+			froze.UpdateLabeledGauge("FilesystemUsed", labels, float64(usage.Used))
+			froze.UpdateLabeledGauge("FilesystemFree", labels, float64(usage.Free))
+			froze.UpdateLabeledGauge("FilesystemUsedPercent", labels, usage.UsedPercent)
+		}
+		return nil
+	}
+}
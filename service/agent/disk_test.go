@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestDisk(t *testing.T) {
+	t.Run("All devices and mounts", func(t *testing.T) {
+		froze := NewFroze()
+		require.NoError(t, Disk(nil, nil)(context.TODO(), froze))
+
+		for _, mtr := range froze.List() {
+			switch mtr.Type() {
+			case metric.CounterType:
+				assert.Contains(t, mtr.Labels, DeviceLabel)
+			case metric.GaugeType:
+				assert.Contains(t, mtr.Labels, MountLabel)
+			}
+		}
+	})
+
+	t.Run("Everything excluded", func(t *testing.T) {
+		filter, err := NewFilter("", ".")
+		require.NoError(t, err)
+
+		froze := NewFroze()
+		require.NoError(t, Disk(filter, filter)(context.TODO(), froze))
+		assert.Empty(t, froze.List())
+	})
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Filter selects names, e.g. network interfaces or mount points, matched by include pattern and not matched by exclude
+// pattern. Empty pattern is not applied. Nil Filter matches any name.
+type Filter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// Match reports whether name is selected by filter.
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// NewFilter creates Filter from include and exclude regular expressions.
+func NewFilter(include, exclude string) (*Filter, error) {
+	f := &Filter{}
+	if include != "" {
+		re, err := regexp.Compile(include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+		f.include = re
+	}
+	if exclude != "" {
+		re, err := regexp.Compile(exclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		f.exclude = re
+	}
+	return f, nil
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		matched []string
+		skipped []string
+	}{
+		{
+			name:    "Empty filter",
+			matched: []string{"eth0", "lo"},
+		},
+		{
+			name:    "Include",
+			include: "^eth",
+			matched: []string{"eth0", "eth1"},
+			skipped: []string{"lo", "veth0"},
+		},
+		{
+			name:    "Exclude",
+			exclude: "^(lo|veth)",
+			matched: []string{"eth0", "wlan0"},
+			skipped: []string{"lo", "veth0"},
+		},
+		{
+			name:    "Exclude takes precedence",
+			include: "^/",
+			exclude: "^/boot",
+			matched: []string{"/", "/var"},
+			skipped: []string{"/boot", "/boot/efi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			for _, name := range tt.matched {
+				assert.True(t, filter.Match(name), name)
+			}
+			for _, name := range tt.skipped {
+				assert.False(t, filter.Match(name), name)
+			}
+		})
+	}
+
+	t.Run("Nil filter", func(t *testing.T) {
+		var filter *Filter
+		assert.True(t, filter.Match("eth0"))
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := NewFilter("(", "")
+		assert.Error(t, err)
+		_, err = NewFilter("", "(")
+		assert.Error(t, err)
+	})
+}
@@ -6,42 +6,163 @@ import (
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 )
 
-// Froze object is used to hold collected metrics. Another point is to synchronize read and write access of several
-// application components to operate consistent metrics data.
-type Froze struct {
-	sync.Mutex
-	gauges   map[string]float64
-	counters map[string]int64
-}
+type (
+	// Froze object is used to hold collected metrics. Another point is to synchronize read and write access of several
+	// application components to operate consistent metrics data.
+	Froze struct {
+		sync.Mutex
+		gauges     map[string]float64
+		counters   map[string]int64
+		histograms map[string]*metric.Histogram
+		series     map[string]series
+	}
+
+	// series is metric identity held by Froze.
+	series struct {
+		id     string
+		labels metric.Labels
+	}
+)
 
 // UpdateGauge updates single gauge metrics measure. Update will override previously stored value. Thread unsafe, should
 // be locked before update.
 func (f *Froze) UpdateGauge(id string, gauge float64) {
-	f.gauges[id] = gauge
+	f.UpdateLabeledGauge(id, nil, gauge)
+}
+
+// UpdateLabeledGauge updates single gauge metrics measure identified by ID and labels. Update will override previously
+// stored value. Thread unsafe, should be locked before update.
+func (f *Froze) UpdateLabeledGauge(id string, labels metric.Labels, gauge float64) {
+	f.gauges[f.key(id, labels)] = gauge
+}
+
+// AddGauge adds delta to single gauge metrics measure. Thread unsafe, should be locked before update.
+func (f *Froze) AddGauge(id string, delta float64) {
+	f.AddLabeledGauge(id, nil, delta)
+}
+
+// AddLabeledGauge adds delta to single gauge metrics measure identified by ID and labels. Thread unsafe, should be locked
+// before update.
+func (f *Froze) AddLabeledGauge(id string, labels metric.Labels, delta float64) {
+	f.gauges[f.key(id, labels)] += delta
 }
 
 // UpdateCounter updates single gauge metrics measure. Update will increment previously stored value. Thread unsafe, should
 // be locked before update.
 func (f *Froze) UpdateCounter(id string, counter int64) {
-	f.counters[id] += counter
+	f.UpdateLabeledCounter(id, nil, counter)
+}
+
+// UpdateLabeledCounter updates single counter metrics measure identified by ID and labels. Update will increment
+// previously stored value. Thread unsafe, should be locked before update.
+func (f *Froze) UpdateLabeledCounter(id string, labels metric.Labels, counter int64) {
+	f.counters[f.key(id, labels)] += counter
+}
+
+// ObserveHistogram registers n observations of value with histogram metrics measure. Histogram is created with
+// specified bounds on first observation. Thread unsafe, should be locked before update.
+func (f *Froze) ObserveHistogram(id string, bounds []float64, v float64, n uint64) {
+	f.ObserveLabeledHistogram(id, nil, bounds, v, n)
+}
+
+// ObserveLabeledHistogram registers n observations of value with histogram metrics measure identified by ID and labels.
+// Histogram is created with specified bounds on first observation. Thread unsafe, should be locked before update.
+func (f *Froze) ObserveLabeledHistogram(id string, labels metric.Labels, bounds []float64, v float64, n uint64) {
+	key := f.key(id, labels)
+	hist, ok := f.histograms[key]
+	if !ok {
+		h := metric.NewHistogram(bounds...)
+		hist = &h
+		f.histograms[key] = hist
+	}
+	hist.ObserveN(v, n)
 }
 
 // List entirely reads metrics measures copy into list. Thread unsafe, should be locked before read.
 func (f *Froze) List() metric.List {
-	list := make(metric.List, 0, len(f.gauges)+len(f.counters))
-	for id, gauge := range f.gauges {
-		list = append(list, metric.NewGaugeMetric(id, metric.Gauge(gauge)))
+	list := make(metric.List, 0, len(f.gauges)+len(f.counters)+len(f.histograms))
+	for key, gauge := range f.gauges {
+		s := f.series[key]
+		list = append(list, metric.NewGaugeMetric(s.id, metric.Gauge(gauge)).WithLabels(s.labels))
 	}
-	for id, counter := range f.counters {
-		list = append(list, metric.NewCounterMetric(id, metric.Counter(counter)))
+	for key, counter := range f.counters {
+		s := f.series[key]
+		list = append(list, metric.NewCounterMetric(s.id, metric.Counter(counter)).WithLabels(s.labels))
+	}
+	for key, hist := range f.histograms {
+		s := f.series[key]
+		list = append(list, metric.NewHistogramMetric(s.id, hist.Copy()).WithLabels(s.labels))
 	}
 	return list
 }
 
+// Take reads metrics measures copy into list like List does and resets counters and histograms, so they accumulate
+// increments since the last take. Thread unsafe, should be locked before read.
+func (f *Froze) Take() metric.List {
+	list := f.List()
+	for key := range f.counters {
+		f.counters[key] = 0
+	}
+	for key, hist := range f.histograms {
+		h := metric.NewHistogram(hist.Bounds...)
+		f.histograms[key] = &h
+	}
+	return list
+}
+
+// Restore returns counters and histograms of taken list back to Froze, e.g. if list failed to be reported. Gauges are
+// not restored since Froze holds the same or newer values. Thread unsafe, should be locked before update.
+func (f *Froze) Restore(list metric.List) {
+	for _, mtr := range list {
+		switch value := mtr.Value.(type) {
+		case *metric.Counter:
+			f.UpdateLabeledCounter(mtr.ID, mtr.Labels, int64(*value))
+		case *metric.Histogram:
+			key := f.key(mtr.ID, mtr.Labels)
+			if hist, ok := f.histograms[key]; ok {
+				// observations of histogram with outdated bounds are dropped
+				_ = hist.Merge(value)
+				continue
+			}
+			h := value.Copy()
+			f.histograms[key] = &h
+		}
+	}
+}
+
+// key returns key of metric identified by ID and labels and registers its identity.
+func (f *Froze) key(id string, labels metric.Labels) string {
+	key := id + labels.String()
+	if _, ok := f.series[key]; !ok {
+		f.series[key] = series{id: id, labels: labels.Copy()}
+	}
+	return key
+}
+
 // NewFroze creates new Froze object.
 func NewFroze() *Froze {
 	return &Froze{
-		gauges:   make(map[string]float64),
-		counters: make(map[string]int64),
+		gauges:     make(map[string]float64),
+		counters:   make(map[string]int64),
+		histograms: make(map[string]*metric.Histogram),
+		series:     make(map[string]series),
+	}
+}
+
+// deltas keeps last values of cumulative counters, e.g. bytes sent by network interface, to report their increments
+// since previous poll.
+type deltas map[string]uint64
+
+// delta returns increment of counter identified by key. The first value only seeds counter, so increment is zero.
+// Counter is considered restarted if its value decreased.
+func (d deltas) delta(key string, v uint64) int64 {
+	prev, ok := d[key]
+	d[key] = v
+	if !ok {
+		return 0
+	}
+	if v < prev {
+		return int64(v)
 	}
+	return int64(v - prev)
 }
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestFroze(t *testing.T) {
+	labels := metric.Labels{"host": "foo"}
+	hist := metric.NewHistogram(1, 10)
+	hist.ObserveN(5, 3)
+	hist.Observe(20)
+
+	froze := NewFroze()
+	froze.UpdateGauge("Alloc", 1)
+	froze.AddGauge("Alloc", 2)
+	froze.AddLabeledGauge("Alloc", labels, -1)
+	froze.UpdateCounter("PollCount", 1)
+	froze.UpdateLabeledCounter("PollCount", labels, 2)
+	froze.ObserveHistogram("Latency", []float64{10, 1}, 5, 3)
+	froze.ObserveHistogram("Latency", []float64{1, 10}, 20, 1)
+
+	assert.ElementsMatch(t, metric.List{
+		metric.NewGaugeMetric("Alloc", 3),
+		metric.NewGaugeMetric("Alloc", -1).WithLabels(labels),
+		metric.NewCounterMetric("PollCount", 1),
+		metric.NewCounterMetric("PollCount", 2).WithLabels(labels),
+		metric.NewHistogramMetric("Latency", hist),
+	}, froze.List())
+}
+
+func TestFroze_Take(t *testing.T) {
+	froze := NewFroze()
+	froze.UpdateGauge("Alloc", 1)
+	froze.UpdateCounter("PollCount", 2)
+	froze.ObserveHistogram("Latency", []float64{1, 10}, 5, 3)
+
+	taken := froze.Take()
+	assert.Len(t, taken, 3)
+
+	empty := metric.NewHistogram(1, 10)
+	assert.ElementsMatch(t, metric.List{
+		metric.NewGaugeMetric("Alloc", 1),
+		metric.NewCounterMetric("PollCount", 0),
+		metric.NewHistogramMetric("Latency", empty),
+	}, froze.List(), "counters and histograms are reset, gauges are kept")
+
+	froze.UpdateGauge("Alloc", 2)
+	froze.UpdateCounter("PollCount", 1)
+	froze.ObserveHistogram("Latency", []float64{1, 10}, 20, 1)
+	froze.Restore(taken)
+
+	hist := metric.NewHistogram(1, 10)
+	hist.ObserveN(5, 3)
+	hist.Observe(20)
+	assert.ElementsMatch(t, metric.List{
+		metric.NewGaugeMetric("Alloc", 2),
+		metric.NewCounterMetric("PollCount", 3),
+		metric.NewHistogramMetric("Latency", hist),
+	}, froze.List(), "restored increments are added to new ones")
+}
+
+func Test_deltas(t *testing.T) {
+	d := make(deltas)
+	assert.Equal(t, int64(0), d.delta("foo", 10), "first value seeds counter")
+	assert.Equal(t, int64(5), d.delta("foo", 15))
+	assert.Equal(t, int64(0), d.delta("foo", 15))
+	assert.Equal(t, int64(3), d.delta("foo", 3), "restarted counter")
+	assert.Equal(t, int64(0), d.delta("bar", 7))
+}
@@ -25,4 +25,12 @@ type (
 		// Report transmits metrics to server
 		Report(context.Context)
 	}
+
+	// ListenerService is an application service receiving metrics pushed by applications and publishing them.
+	ListenerService interface {
+		pkg.BackgroundService
+
+		// Listen receives pushed metrics until context is cancelled
+		Listen(context.Context)
+	}
 )
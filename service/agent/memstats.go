@@ -1,54 +1,129 @@
 package agent
 
 import (
-	"context"
 	"math/rand"
-	"runtime"
+	"runtime/metrics"
 	"time"
 )
 
-func MemStats() Collector {
-	var counter int64
-	var stats runtime.MemStats
-
-	rand.Seed(time.Now().UnixNano())
-
-	return func(ctx context.Context, froze *Froze) error {
-		counter++
-		runtime.ReadMemStats(&stats)
-
-		froze.UpdateGauge("Alloc", float64(stats.Alloc))
-		froze.UpdateGauge("BuckHashSys", float64(stats.BuckHashSys))
-		froze.UpdateGauge("GCCPUFraction", stats.GCCPUFraction)
-		froze.UpdateGauge("GCSys", float64(stats.GCSys))
-		froze.UpdateGauge("HeapAlloc", float64(stats.HeapAlloc))
-		froze.UpdateGauge("HeapIdle", float64(stats.HeapIdle))
-		froze.UpdateGauge("HeapInuse", float64(stats.HeapInuse))
-		froze.UpdateGauge("HeapObjects", float64(stats.HeapObjects))
-		froze.UpdateGauge("HeapSys", float64(stats.HeapSys))
-		froze.UpdateGauge("HeapReleased", float64(stats.HeapReleased))
-		froze.UpdateGauge("LastGC", float64(stats.LastGC))
-		froze.UpdateGauge("Lookups", float64(stats.Lookups))
-		froze.UpdateGauge("MCacheSys", float64(stats.MCacheSys))
-		froze.UpdateGauge("MCacheInuse", float64(stats.MCacheInuse))
-		froze.UpdateGauge("MSpanInuse", float64(stats.MSpanInuse))
-		froze.UpdateGauge("MSpanSys", float64(stats.MSpanSys))
-		froze.UpdateGauge("Mallocs", float64(stats.Mallocs))
-		froze.UpdateGauge("NextGC", float64(stats.NextGC))
-		froze.UpdateGauge("NumForcedGC", float64(stats.NumForcedGC))
-		froze.UpdateGauge("NumGC", float64(stats.NumGC))
-		froze.UpdateGauge("OtherSys", float64(stats.OtherSys))
-		froze.UpdateGauge("PauseTotalNs", float64(stats.PauseTotalNs))
-		froze.UpdateGauge("StackInuse", float64(stats.StackInuse))
-		froze.UpdateGauge("StackSys", float64(stats.StackSys))
-		froze.UpdateGauge("Sys", float64(stats.Sys))
-
-		froze.UpdateGauge("RandomValue", rand.Float64())
-
-		froze.UpdateGauge("Frees", float64(stats.Frees))
-		froze.UpdateGauge("TotalAlloc", float64(stats.TotalAlloc))
-
-		froze.UpdateCounter("PollCount", counter)
-		return nil
+// memStatsFields maps legacy runtime.MemStats field names to runtime/metrics keys. Field value is a sum of values of
+// listed metrics, e.g. HeapInuse consists of memory occupied by heap objects and unused memory of heap spans.
+var memStatsFields = []struct {
+	name string
+	keys []string
+}{
+	{name: "Alloc", keys: []string{"/memory/classes/heap/objects:bytes"}},
+	{name: "BuckHashSys", keys: []string{"/memory/classes/profiling/buckets:bytes"}},
+	{name: "Frees", keys: []string{"/gc/heap/frees:objects", "/gc/heap/tiny/allocs:objects"}},
+	{name: "GCSys", keys: []string{"/memory/classes/metadata/other:bytes"}},
+	{name: "HeapAlloc", keys: []string{"/memory/classes/heap/objects:bytes"}},
+	{name: "HeapIdle", keys: []string{"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes"}},
+	{name: "HeapInuse", keys: []string{"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"}},
+	{name: "HeapObjects", keys: []string{"/gc/heap/objects:objects"}},
+	{name: "HeapReleased", keys: []string{"/memory/classes/heap/released:bytes"}},
+	{name: "HeapSys", keys: []string{
+		"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes"}},
+	{name: "Lookups"},
+	{name: "MCacheInuse", keys: []string{"/memory/classes/metadata/mcache/inuse:bytes"}},
+	{name: "MCacheSys", keys: []string{"/memory/classes/metadata/mcache/inuse:bytes", "/memory/classes/metadata/mcache/free:bytes"}},
+	{name: "MSpanInuse", keys: []string{"/memory/classes/metadata/mspan/inuse:bytes"}},
+	{name: "MSpanSys", keys: []string{"/memory/classes/metadata/mspan/inuse:bytes", "/memory/classes/metadata/mspan/free:bytes"}},
+	{name: "Mallocs", keys: []string{"/gc/heap/allocs:objects", "/gc/heap/tiny/allocs:objects"}},
+	{name: "NextGC", keys: []string{"/gc/heap/goal:bytes"}},
+	{name: "NumForcedGC", keys: []string{"/gc/cycles/forced:gc-cycles"}},
+	{name: "NumGC", keys: []string{"/gc/cycles/total:gc-cycles"}},
+	{name: "OtherSys", keys: []string{"/memory/classes/other:bytes"}},
+	{name: "StackInuse", keys: []string{"/memory/classes/heap/stacks:bytes"}},
+	{name: "StackSys", keys: []string{"/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"}},
+	{name: "Sys", keys: []string{"/memory/classes/total:bytes"}},
+	{name: "TotalAlloc", keys: []string{"/gc/heap/allocs:bytes"}},
+}
+
+// memStatsPausesKeys are keys of GC pauses histogram in order of preference, the latter is deprecated since Go 1.22.
+var memStatsPausesKeys = []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}
+
+// memStats publishes runtime/metrics samples under legacy names of runtime.MemStats fields along with PollCount and
+// RandomValue, so runtime.ReadMemStats which stops the world is not called. Fields which have no runtime/metrics
+// counterpart are derived: GCCPUFraction is a share of GC in available CPU time, PauseTotalNs is estimated with GC
+// pauses histogram and LastGC is time of poll which observed new GC cycle.
+type memStats struct {
+	index  map[string]int
+	random *rand.Rand
+	numGC  float64
+	lastGC time.Time
+}
+
+func newMemStats(samples []metrics.Sample) *memStats {
+	index := make(map[string]int, len(samples))
+	for i, sample := range samples {
+		index[sample.Name] = i
+	}
+	return &memStats{
+		index:  index,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// publish updates legacy metrics with read samples. Thread unsafe, Froze should be locked.
+func (m *memStats) publish(froze *Froze, samples []metrics.Sample) {
+	for _, field := range memStatsFields {
+		var v float64
+		for _, key := range field.keys {
+			v += m.value(samples, key)
+		}
+		froze.UpdateGauge(field.name, v)
+	}
+
+	var fraction float64
+	if total := m.value(samples, "/cpu/classes/total:cpu-seconds"); total > 0 {
+		fraction = m.value(samples, "/cpu/classes/gc/total:cpu-seconds") / total
+	}
+	froze.UpdateGauge("GCCPUFraction", fraction)
+	froze.UpdateGauge("PauseTotalNs", m.pauseTotal(samples)*float64(time.Second))
+
+	if numGC := m.value(samples, "/gc/cycles/total:gc-cycles"); numGC > m.numGC {
+		m.numGC = numGC
+		m.lastGC = time.Now()
+	}
+	var lastGC float64
+	if !m.lastGC.IsZero() {
+		lastGC = float64(m.lastGC.UnixNano())
+	}
+	froze.UpdateGauge("LastGC", lastGC)
+
+	froze.UpdateGauge("RandomValue", m.random.Float64())
+	froze.UpdateCounter("PollCount", 1)
+}
+
+// value returns scalar value of sample with specified key. Zero is returned if runtime doesn't support the key.
+func (m *memStats) value(samples []metrics.Sample, key string) float64 {
+	i, ok := m.index[key]
+	if !ok {
+		return 0
+	}
+	switch v := samples[i].Value; v.Kind() {
+	case metrics.KindUint64:
+		return float64(v.Uint64())
+	case metrics.KindFloat64:
+		return v.Float64()
+	}
+	return 0
+}
+
+// pauseTotal estimates total time of GC pauses in seconds with GC pauses histogram.
+func (m *memStats) pauseTotal(samples []metrics.Sample) float64 {
+	for _, key := range memStatsPausesKeys {
+		i, ok := m.index[key]
+		if !ok || samples[i].Value.Kind() != metrics.KindFloat64Histogram {
+			continue
+		}
+		hist := samples[i].Value.Float64Histogram()
+		var total float64
+		for j, c := range hist.Counts {
+			total += float64(c) * runtimeBucketValue(hist.Buckets[j], hist.Buckets[j+1])
+		}
+		return total
 	}
+	return 0
 }
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,64 +13,84 @@ import (
 
 func TestMemStats(t *testing.T) {
 	froze := NewFroze()
-	expected := metric.List{
-		metric.NewGaugeMetric("Alloc", metric.Gauge(0)),
-		metric.NewGaugeMetric("BuckHashSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("GCCPUFraction", metric.Gauge(0)),
-		metric.NewGaugeMetric("GCSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapAlloc", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapIdle", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapInuse", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapObjects", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapReleased", metric.Gauge(0)),
-		metric.NewGaugeMetric("HeapSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("LastGC", metric.Gauge(0)),
-		metric.NewGaugeMetric("Lookups", metric.Gauge(0)),
-		metric.NewGaugeMetric("MCacheInuse", metric.Gauge(0)),
-		metric.NewGaugeMetric("MCacheSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("MSpanInuse", metric.Gauge(0)),
-		metric.NewGaugeMetric("MSpanSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("Mallocs", metric.Gauge(0)),
-		metric.NewGaugeMetric("NextGC", metric.Gauge(0)),
-		metric.NewGaugeMetric("NumForcedGC", metric.Gauge(0)),
-		metric.NewGaugeMetric("NumGC", metric.Gauge(0)),
-		metric.NewGaugeMetric("OtherSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("PauseTotalNs", metric.Gauge(0)),
-		metric.NewGaugeMetric("StackInuse", metric.Gauge(0)),
-		metric.NewGaugeMetric("StackSys", metric.Gauge(0)),
-		metric.NewGaugeMetric("Sys", metric.Gauge(0)),
-		metric.NewGaugeMetric("RandomValue", metric.Gauge(0)),
-		metric.NewGaugeMetric("Frees", metric.Gauge(0)),
-		metric.NewGaugeMetric("TotalAlloc", metric.Gauge(0)),
-		metric.NewCounterMetric("PollCount", metric.Counter(0)),
-	}
+	collector := RuntimeMetrics(true)
+
+	require.NoError(t, collector(context.TODO(), froze))
+	runtime.GC()
+	require.NoError(t, collector(context.TODO(), froze))
 
-	t.Run("Basic test", func(t *testing.T) {
-		err := MemStats()(context.TODO(), froze)
+	values := make(map[string]*metric.Metric)
+	for _, mtr := range froze.List() {
+		values[mtr.ID] = mtr
+	}
 
-		list := froze.List()
-		list0 := make(metric.List, 0, len(list))
-		for _, m := range froze.List() {
-			v, err := m.Type().New()
-			require.NoError(t, err)
-			list0 = append(list0, &metric.Metric{ID: m.ID, Value: v})
+	expected := []string{"Alloc", "BuckHashSys", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle", "HeapInuse",
+		"HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups", "MCacheInuse", "MCacheSys", "MSpanInuse",
+		"MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC", "OtherSys", "PauseTotalNs", "StackInuse", "StackSys",
+		"Sys", "RandomValue", "Frees", "TotalAlloc"}
+	t.Run("Legacy gauges", func(t *testing.T) {
+		for _, id := range expected {
+			if mtr, ok := values[id]; assert.True(t, ok, "metric %s is not collected", id) {
+				assert.Equal(t, metric.GaugeType, mtr.Type(), id)
+			}
 		}
-		if assert.NoError(t, err) {
-			assert.ElementsMatch(t, list0, expected)
+	})
+	t.Run("Poll count", func(t *testing.T) {
+		if mtr, ok := values["PollCount"]; assert.True(t, ok) {
+			assert.Equal(t, metric.NewCounterMetric("PollCount", 2), mtr)
 		}
 	})
-}
 
-func BenchmarkMemStats(b *testing.B) {
-	b.Run("Mem Stats polling", func(b *testing.B) {
-		b.StopTimer()
-		froze := NewFroze()
-		collector := MemStats()
-		ctx := context.TODO()
-		b.StartTimer()
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "Heap allocated", id: "HeapAlloc"},
+		{name: "Heap in use", id: "HeapInuse"},
+		{name: "Memory obtained from OS", id: "Sys"},
+		{name: "GC cycles", id: "NumGC"},
+		{name: "Forced GC cycles", id: "NumForcedGC"},
+		{name: "Last GC observed", id: "LastGC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if mtr, ok := values[tt.id]; assert.True(t, ok) {
+				assert.NotZero(t, mtr.Value)
+			}
+		})
+	}
+	t.Run("Heap breakdown", func(t *testing.T) {
+		gauge := func(id string) float64 { return float64(*values[id].Value.(*metric.Gauge)) }
+		assert.GreaterOrEqual(t, gauge("HeapInuse"), gauge("HeapAlloc"))
+		assert.GreaterOrEqual(t, gauge("HeapSys"), gauge("HeapInuse"))
+	})
 
-		for i := 0; i < b.N; i++ {
-			require.NoError(b, collector(ctx, froze))
+	t.Run("Legacy names are not reported by default", func(t *testing.T) {
+		froze := NewFroze()
+		require.NoError(t, RuntimeMetrics(false)(context.TODO(), froze))
+		for _, mtr := range froze.List() {
+			assert.NotContains(t, expected, mtr.ID)
+			assert.NotEqual(t, "PollCount", mtr.ID)
 		}
 	})
 }
+
+func BenchmarkMemStats(b *testing.B) {
+	for _, legacy := range []bool{false, true} {
+		name := "runtime metrics"
+		if legacy {
+			name = "runtime metrics with legacy names"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.StopTimer()
+			froze := NewFroze()
+			collector := RuntimeMetrics(legacy)
+			ctx := context.TODO()
+			b.StartTimer()
+
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, collector(ctx, froze))
+			}
+		})
+	}
+}
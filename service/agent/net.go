@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/net"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// InterfaceLabel identifies network interface in metrics labels, e.g. {interface="eth0"}.
+const InterfaceLabel = "interface"
+
+// Net collects traffic, packets and errors counters of network interfaces selected by filter. Counters are incremented
+// by values accumulated since previous poll.
+func Net(filter *Filter) Collector {
+	counters := make(deltas)
+
+	return func(ctx context.Context, froze *Froze) error {
+		_, logger := logging.GetOrCreateLogger(ctx)
+
+		stats, err := net.IOCountersWithContext(ctx, true)
+		if err != nil {
+			logger.Err(err).Msg("failed to collect metrics")
+			return err
+		}
+
+		for _, s := range stats {
+			if !filter.Match(s.Name) {
+				continue
+			}
+			labels := metric.Labels{InterfaceLabel: s.Name}
+			update := func(id string, v uint64) {
+				froze.UpdateLabeledCounter(id, labels, counters.delta(id+labels.String(), v))
+			}
+			update("NetBytesSent", s.BytesSent)
+			update("NetBytesRecv", s.BytesRecv)
+			update("NetPacketsSent", s.PacketsSent)
+			update("NetPacketsRecv", s.PacketsRecv)
+			update("NetErrorsIn", s.Errin)
+			update("NetErrorsOut", s.Errout)
+			update("NetDropsIn", s.Dropin)
+			update("NetDropsOut", s.Dropout)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestNet(t *testing.T) {
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		want    []string
+	}{
+		{
+			name:    "Included interface",
+			include: "^lo$",
+			want: []string{
+				"NetBytesSent", "NetBytesRecv", "NetPacketsSent", "NetPacketsRecv",
+				"NetErrorsIn", "NetErrorsOut", "NetDropsIn", "NetDropsOut",
+			},
+		},
+		{
+			name:    "Excluded interface",
+			include: "^lo$",
+			exclude: "lo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+
+			froze := NewFroze()
+			require.NoError(t, Net(filter)(context.TODO(), froze))
+
+			var got []string
+			for _, mtr := range froze.List() {
+				assert.Equal(t, metric.CounterType, mtr.Type())
+				assert.Equal(t, metric.Labels{InterfaceLabel: "lo"}, mtr.Labels)
+				got = append(got, mtr.ID)
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
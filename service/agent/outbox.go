@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+)
+
+const (
+	outboxName = "Agent outbox"
+
+	outboxExt    = ".json"
+	outboxTmpExt = ".tmp"
+)
+
+type (
+	// Outbox is a bounded file-backed FIFO queue of metrics batches that monitor failed to accept. Every batch is stored
+	// in a separate file named by its sequence number, so queue survives agent restarts. Oldest batches are evicted when
+	// queue exceeds size limit, batches older than age limit are discarded.
+	Outbox struct {
+		sync.Mutex
+		dir     string
+		maxSize int64
+		maxAge  time.Duration
+		seq     uint64
+		batches []outboxEntry
+		stats   OutboxStats
+	}
+
+	// OutboxStats describes Outbox state and its lifetime activity.
+	OutboxStats struct {
+		Pending  int    // batches waiting to be replayed
+		Size     int64  // total size of pending batches in bytes
+		Stored   uint64 // batches stored since start
+		Replayed uint64 // batches successfully replayed since start
+		Rejected uint64 // batches discarded due to permanent send failure since start
+		Evicted  uint64 // batches evicted due to size limit since start
+		Expired  uint64 // batches discarded due to age limit since start
+	}
+
+	outboxEntry struct {
+		seq  uint64
+		size int64
+	}
+
+	outboxBatch struct {
+		Timestamp time.Time   `json:"timestamp"`
+		Metrics   metric.List `json:"metrics"`
+	}
+)
+
+// Push persists metrics batch at the tail of the queue. Oldest batches are evicted if queue exceeds size limit.
+func (o *Outbox) Push(ctx context.Context, list metric.List) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(outboxName), logging.WithCID(ctx))
+
+	data, err := json.Marshal(&outboxBatch{Timestamp: time.Now(), Metrics: list})
+	if err != nil {
+		logger.Err(err).Msg("failed to encode batch")
+		return err
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	seq := o.seq + 1
+	if err := o.write(seq, data); err != nil {
+		logger.Err(err).Msg("failed to store batch")
+		return err
+	}
+	o.seq = seq
+	o.batches = append(o.batches, outboxEntry{seq: seq, size: int64(len(data))})
+	o.stats.Pending++
+	o.stats.Size += int64(len(data))
+	o.stats.Stored++
+
+	for o.maxSize > 0 && o.stats.Size > o.maxSize && len(o.batches) != 0 {
+		if err := o.remove(); err != nil {
+			logger.Err(err).Msg("failed to evict batch")
+			return err
+		}
+		o.stats.Evicted++
+	}
+	logger.Trace().Msgf("batch of %d metrics stored: %+v", len(list), o.stats)
+	return nil
+}
+
+// Replay sends pending batches in order they were pushed. Replay stops on the first failure keeping the failed batch
+// at the head of the queue, unless it failed permanently. Batches exceeding age limit are discarded without sending.
+func (o *Outbox) Replay(ctx context.Context, send func(context.Context, metric.List) error) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(outboxName), logging.WithCID(ctx))
+
+	o.Lock()
+	defer o.Unlock()
+
+	for len(o.batches) != 0 {
+		batch, err := o.read(o.batches[0].seq)
+		if err != nil {
+			logger.Err(err).Msgf("discarding unreadable batch #%d", o.batches[0].seq)
+			if err := o.remove(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if o.maxAge > 0 && time.Since(batch.Timestamp) > o.maxAge {
+			if err := o.remove(); err != nil {
+				logger.Err(err).Msg("failed to discard expired batch")
+				return err
+			}
+			o.stats.Expired++
+			continue
+		}
+
+		if err := send(ctx, batch.Metrics); err != nil {
+			if !task.IsPermanent(err) {
+				return err
+			}
+			logger.Err(err).Msgf("discarding rejected batch #%d", o.batches[0].seq)
+			if err := o.remove(); err != nil {
+				return err
+			}
+			o.stats.Rejected++
+			continue
+		}
+		if err := o.remove(); err != nil {
+			logger.Err(err).Msg("failed to remove replayed batch")
+			return err
+		}
+		o.stats.Replayed++
+	}
+	logger.Trace().Msgf("outbox replayed: %+v", o.stats)
+	return nil
+}
+
+// Stats returns outbox statistics snapshot.
+func (o *Outbox) Stats() OutboxStats {
+	o.Lock()
+	defer o.Unlock()
+	return o.stats
+}
+
+// Len returns number of pending batches.
+func (o *Outbox) Len() int {
+	o.Lock()
+	defer o.Unlock()
+	return len(o.batches)
+}
+
+func (o *Outbox) write(seq uint64, data []byte) error {
+	name := o.filename(seq)
+	tmp := name + outboxTmpExt
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func (o *Outbox) read(seq uint64) (*outboxBatch, error) {
+	data, err := os.ReadFile(o.filename(seq))
+	if err != nil {
+		return nil, err
+	}
+	batch := &outboxBatch{}
+	if err := json.Unmarshal(data, batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// remove deletes batch at the head of the queue.
+func (o *Outbox) remove() error {
+	head := o.batches[0]
+	if err := os.Remove(o.filename(head.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	o.batches = o.batches[1:]
+	o.stats.Pending--
+	o.stats.Size -= head.size
+	return nil
+}
+
+func (o *Outbox) filename(seq uint64) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", seq, outboxExt))
+}
+
+// load restores queue state from outbox directory.
+func (o *Outbox) load() error {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, outboxTmpExt) {
+			// incomplete write
+			if err := os.Remove(filepath.Join(o.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, outboxExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, outboxExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		o.batches = append(o.batches, outboxEntry{seq: seq, size: info.Size()})
+		o.stats.Size += info.Size()
+	}
+	sort.Slice(o.batches, func(i, j int) bool { return o.batches[i].seq < o.batches[j].seq })
+	if n := len(o.batches); n != 0 {
+		o.seq = o.batches[n-1].seq
+	}
+	o.stats.Pending = len(o.batches)
+	return nil
+}
+
+// NewOutbox creates outbox stored in cfg.OutboxDir. Batches left by previous agent run are restored. Returns nil if
+// outbox directory is not configured.
+func NewOutbox(cfg *config.Config) (*Outbox, error) {
+	if len(cfg.OutboxDir) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.OutboxDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	o := &Outbox{
+		dir:     cfg.OutboxDir,
+		maxSize: cfg.OutboxMaxSize,
+		maxAge:  cfg.OutboxMaxAge,
+	}
+	if err := o.load(); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+	return o, nil
+}
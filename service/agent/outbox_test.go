@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/stub"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.TODO()
+	batch := func(id string) metric.List {
+		return metric.List{metric.NewGaugeMetric(id, 1)}
+	}
+
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		push         []string
+		sendErr      error
+		wantErr      bool
+		wantSent     []string
+		wantPending  int
+		wantEvicted  uint64
+		wantExpired  uint64
+		wantReplayed uint64
+	}{
+		{
+			name:         "Replay in order",
+			push:         []string{"foo", "bar", "baz"},
+			wantSent:     []string{"foo", "bar", "baz"},
+			wantReplayed: 3,
+		},
+		{
+			name:        "Replay failed",
+			push:        []string{"foo", "bar"},
+			sendErr:     errors.New("unavailable"),
+			wantErr:     true,
+			wantPending: 2,
+		},
+		{
+			name:         "Size limit",
+			cfg:          config.Config{OutboxMaxSize: 250},
+			push:         []string{"foo", "bar", "baz"},
+			wantSent:     []string{"bar", "baz"},
+			wantEvicted:  1,
+			wantReplayed: 2,
+		},
+		{
+			name:        "Age limit",
+			cfg:         config.Config{OutboxMaxAge: time.Nanosecond},
+			push:        []string{"foo", "bar"},
+			wantExpired: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.OutboxDir = t.TempDir()
+			outbox, err := NewOutbox(&cfg)
+			require.NoError(t, err)
+
+			for _, id := range tt.push {
+				require.NoError(t, outbox.Push(ctx, batch(id)))
+			}
+
+			var sent []string
+			err = outbox.Replay(ctx, func(_ context.Context, list metric.List) error {
+				if tt.sendErr != nil {
+					return tt.sendErr
+				}
+				for _, mtr := range list {
+					sent = append(sent, mtr.ID)
+				}
+				return nil
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSent, sent)
+
+			stats := outbox.Stats()
+			assert.Equal(t, tt.wantPending, stats.Pending)
+			assert.Equal(t, uint64(len(tt.push)), stats.Stored)
+			assert.Equal(t, tt.wantReplayed, stats.Replayed)
+			assert.Equal(t, tt.wantEvicted, stats.Evicted)
+			assert.Equal(t, tt.wantExpired, stats.Expired)
+
+			files, err := filepath.Glob(filepath.Join(cfg.OutboxDir, "*"+outboxExt))
+			require.NoError(t, err)
+			assert.Len(t, files, tt.wantPending)
+		})
+	}
+}
+
+func TestOutboxRestore(t *testing.T) {
+	ctx := context.TODO()
+	cfg := &config.Config{OutboxDir: t.TempDir()}
+
+	outbox, err := NewOutbox(cfg)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Push(ctx, metric.List{metric.NewGaugeMetric("foo", 1)}))
+	require.NoError(t, outbox.Push(ctx, metric.List{metric.NewCounterMetric("bar", 2).WithLabels(metric.Labels{"cpu": "1"})}))
+
+	// leftover of interrupted write
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.OutboxDir, "00000000000000000003.json.tmp"), []byte("{"), 0600))
+
+	restored, err := NewOutbox(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restored.Len())
+	assert.Equal(t, outbox.Stats().Size, restored.Stats().Size)
+
+	require.NoError(t, restored.Push(ctx, metric.List{metric.NewGaugeMetric("baz", 3)}))
+
+	var sent metric.List
+	require.NoError(t, restored.Replay(ctx, func(_ context.Context, list metric.List) error {
+		sent = append(sent, list...)
+		return nil
+	}))
+	require.Len(t, sent, 3)
+	assert.Equal(t, "gauge/foo/1.000", sent[0].String())
+	assert.Equal(t, `counter/bar{cpu="1"}/2`, sent[1].String())
+	assert.Equal(t, "gauge/baz/3.000", sent[2].String())
+
+	_, err = os.Stat(filepath.Join(cfg.OutboxDir, "00000000000000000003.json.tmp"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestMetricsReporterOutbox(t *testing.T) {
+	ctx := context.TODO()
+	outbox, err := NewOutbox(&config.Config{OutboxDir: t.TempDir()})
+	require.NoError(t, err)
+
+	froze := NewFroze()
+	froze.UpdateGauge("foo", 1)
+
+	provider := &failingProvider{Provider: stub.New(), fail: true}
+	rep := NewMetricsReporter(&config.Config{}, froze, provider, outbox)
+	rep.(*metricsReporter).backoff = task.Backoff{MaxAttempts: 2}
+
+	rep.Report(ctx)
+	rep.Report(ctx)
+	assert.Equal(t, 2, outbox.Len())
+	assert.Equal(t, 0, provider.sent)
+	assert.Equal(t, 4, provider.attempts)
+
+	provider.fail = false
+	rep.Report(ctx)
+	assert.Equal(t, 0, outbox.Len())
+	assert.Equal(t, 3, provider.sent)
+	assert.Equal(t, uint64(2), outbox.Stats().Replayed)
+}
+
+func TestMetricsReporterFailure(t *testing.T) {
+	timeout := fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name         string
+		counter      bool
+		err          error
+		wantAttempts int
+		wantPending  int
+	}{
+		{
+			name:         "Gauges are resent after timeout",
+			err:          timeout,
+			wantAttempts: 2,
+			wantPending:  1,
+		},
+		{
+			name:         "Counters are not resent after timeout",
+			counter:      true,
+			err:          timeout,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Counters are resent after failure",
+			counter:      true,
+			err:          errors.New("unavailable"),
+			wantAttempts: 2,
+			wantPending:  1,
+		},
+		{
+			name:         "Rejected metrics are dropped",
+			err:          task.Permanent(errors.New("bad request")),
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			outbox, err := NewOutbox(&config.Config{OutboxDir: t.TempDir()})
+			require.NoError(t, err)
+
+			froze := NewFroze()
+			froze.UpdateGauge("foo", 1)
+			if tt.counter {
+				froze.UpdateCounter("bar", 1)
+			}
+
+			provider := &failingProvider{Provider: stub.New(), fail: true, err: tt.err}
+			rep := NewMetricsReporter(&config.Config{}, froze, provider, outbox)
+			rep.(*metricsReporter).backoff = task.Backoff{MaxAttempts: 2}
+
+			rep.Report(ctx)
+			assert.Equal(t, tt.wantAttempts, provider.attempts)
+			assert.Equal(t, tt.wantPending, outbox.Len())
+		})
+	}
+}
+
+func TestOutboxReplayRejected(t *testing.T) {
+	ctx := context.TODO()
+	outbox, err := NewOutbox(&config.Config{OutboxDir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Push(ctx, metric.List{metric.NewGaugeMetric("foo", 1)}))
+	require.NoError(t, outbox.Push(ctx, metric.List{metric.NewGaugeMetric("bar", 2)}))
+
+	var sent metric.List
+	require.NoError(t, outbox.Replay(ctx, func(_ context.Context, list metric.List) error {
+		if list[0].ID == "foo" {
+			return task.Permanent(errors.New("bad request"))
+		}
+		sent = append(sent, list...)
+		return nil
+	}))
+	assert.Equal(t, 0, outbox.Len())
+	assert.Len(t, sent, 1)
+	assert.Equal(t, uint64(1), outbox.Stats().Rejected)
+	assert.Equal(t, uint64(1), outbox.Stats().Replayed)
+}
+
+type failingProvider struct {
+	monitor.Provider
+	fail     bool
+	err      error
+	sent     int
+	attempts int
+}
+
+func (p *failingProvider) UpdateBulk(context.Context, metric.List) error {
+	p.attempts++
+	if p.fail {
+		if p.err != nil {
+			return p.err
+		}
+		return errors.New("unavailable")
+	}
+	p.sent++
+	return nil
+}
@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// clockTicks is USER_HZ, the unit of process CPU times in /proc/<pid>/stat. It is 100 on all supported platforms.
+const clockTicks = 100
+
+// ProcessLabel identifies monitored process target in metrics labels, e.g. {process="name:nginx"}.
+const ProcessLabel = "process"
+
+type (
+	// processTarget resolves monitored process target into PIDs.
+	processTarget struct {
+		spec    string
+		pid     int
+		pidFile string
+		name    *regexp.Regexp
+		cmdline *regexp.Regexp
+	}
+
+	// processStats is resource usage of process.
+	processStats struct {
+		rss        uint64
+		cpuTime    float64
+		threads    uint64
+		fds        uint64
+		readBytes  uint64
+		writeBytes uint64
+	}
+)
+
+// Processes collects resource usage of processes from procfs mounted at root, e.g. /proc. Each target is one of:
+//   - PID, e.g. 1234 or pid:1234;
+//   - pidfile, e.g. pidfile:/run/nginx.pid;
+//   - regular expression matched against process name or executable base name, e.g. name:^java$;
+//   - regular expression matched against command line with arguments separated by spaces, e.g. cmdline:app\.jar.
+//
+// Usage of all processes matched by target is summed up and labeled by target. Agent itself is never matched by
+// regular expression, so it is not counted even if its arguments mention target.
+func Processes(root string, targets ...string) (Collector, error) {
+	parsed := make([]*processTarget, 0, len(targets))
+	for _, spec := range targets {
+		target, err := parseProcessTarget(spec)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, target)
+	}
+
+	return func(ctx context.Context, froze *Froze) error {
+		_, logger := logging.GetOrCreateLogger(ctx)
+
+		var pids []int
+		for _, target := range parsed {
+			if (target.name != nil || target.cmdline != nil) && pids == nil {
+				var err error
+				if pids, err = listPIDs(root); err != nil {
+					logger.Err(err).Msg("failed to collect metrics")
+					return err
+				}
+			}
+
+			matched, err := target.resolve(root, pids)
+			if err != nil {
+				logger.Err(err).Msgf("failed to resolve process %s", target.spec)
+			}
+
+			var total processStats
+			var count int
+			for _, pid := range matched {
+				stats, err := readProcessStats(filepath.Join(root, strconv.Itoa(pid)))
+				if errors.Is(err, fs.ErrNotExist) {
+					// process has exited
+					continue
+				}
+				if err != nil {
+					logger.Err(err).Msg("failed to collect metrics")
+					return err
+				}
+				total.add(stats)
+				count++
+			}
+
+			labels := metric.Labels{ProcessLabel: target.spec}
+			froze.UpdateLabeledGauge("ProcessCount", labels, float64(count))
+			froze.UpdateLabeledGauge("ProcessRSS", labels, float64(total.rss))
+			froze.UpdateLabeledGauge("ProcessCPUTime", labels, total.cpuTime)
+			froze.UpdateLabeledGauge("ProcessThreads", labels, float64(total.threads))
+			froze.UpdateLabeledGauge("ProcessOpenFDs", labels, float64(total.fds))
+			froze.UpdateLabeledGauge("ProcessReadBytes", labels, float64(total.readBytes))
+			froze.UpdateLabeledGauge("ProcessWriteBytes", labels, float64(total.writeBytes))
+		}
+		return nil
+	}, nil
+}
+
+// resolve returns PIDs of target processes. Running processes PIDs are required to match process by name or command
+// line.
+func (t *processTarget) resolve(root string, pids []int) ([]int, error) {
+	switch {
+	case t.pidFile != "":
+		data, err := os.ReadFile(t.pidFile)
+		if err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.pidFile, err)
+		}
+		return []int{pid}, nil
+	case t.name != nil || t.cmdline != nil:
+		self := os.Getpid()
+		var matched []int
+		for _, pid := range pids {
+			if pid == self {
+				continue
+			}
+			ok, err := t.match(filepath.Join(root, strconv.Itoa(pid)))
+			if err != nil {
+				// process has exited or is not accessible
+				continue
+			}
+			if ok {
+				matched = append(matched, pid)
+			}
+		}
+		return matched, nil
+	default:
+		return []int{t.pid}, nil
+	}
+}
+
+// match reports whether process with procfs directory dir matches target regular expression. Process name is
+// truncated by kernel to 15 characters, so name is also matched against base name of executable from command line.
+func (t *processTarget) match(dir string) (bool, error) {
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return false, err
+	}
+	args := bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0})
+	if t.cmdline != nil {
+		return t.cmdline.Match(bytes.Join(args, []byte{' '})), nil
+	}
+
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return false, err
+	}
+	if t.name.Match(bytes.TrimSpace(comm)) {
+		return true, nil
+	}
+	// command line of kernel threads is empty
+	return len(args[0]) != 0 && t.name.Match([]byte(filepath.Base(string(args[0])))), nil
+}
+
+func (s *processStats) add(other processStats) {
+	s.rss += other.rss
+	s.cpuTime += other.cpuTime
+	s.threads += other.threads
+	s.fds += other.fds
+	s.readBytes += other.readBytes
+	s.writeBytes += other.writeBytes
+}
+
+func parseProcessTarget(spec string) (*processTarget, error) {
+	kind, value, ok := cut(spec, ":")
+	if !ok {
+		kind, value = "pid", spec
+	}
+	target := &processTarget{spec: spec}
+	switch kind {
+	case "pid":
+		pid, err := strconv.Atoi(value)
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("invalid process PID: %s", spec)
+		}
+		target.pid = pid
+	case "pidfile":
+		if value == "" {
+			return nil, fmt.Errorf("invalid process pidfile: %s", spec)
+		}
+		target.pidFile = value
+	case "name":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process name pattern: %w", err)
+		}
+		target.name = re
+	case "cmdline":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process command line pattern: %w", err)
+		}
+		target.cmdline = re
+	default:
+		return nil, fmt.Errorf("unknown process target: %s", spec)
+	}
+	return target, nil
+}
+
+// listPIDs returns PIDs of running processes.
+func listPIDs(root string) ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// readProcessStats reads resource usage of process from its procfs directory. I/O and descriptors statistics are
+// omitted if agent has no permission to read them.
+func readProcessStats(dir string) (processStats, error) {
+	var stats processStats
+
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return stats, err
+	}
+	// process name in parentheses may contain spaces, fields are counted from the last parenthesis
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return stats, fmt.Errorf("%s: unexpected format", filepath.Join(dir, "stat"))
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	// utime and stime are 14th and 15th fields, state is the 3rd one
+	if len(fields) < 13 {
+		return stats, fmt.Errorf("%s: unexpected format", filepath.Join(dir, "stat"))
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", filepath.Join(dir, "stat"), err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", filepath.Join(dir, "stat"), err)
+	}
+	stats.cpuTime = float64(utime+stime) / clockTicks
+
+	status, err := readProcFields(filepath.Join(dir, "status"))
+	if err != nil {
+		return stats, err
+	}
+	// VmRSS is absent for kernel threads and zombies
+	if rss, ok := status["VmRSS"]; ok {
+		stats.rss = rss * 1024
+	}
+	stats.threads = status["Threads"]
+
+	io, err := readProcFields(filepath.Join(dir, "io"))
+	if err != nil && !errors.Is(err, fs.ErrPermission) {
+		return stats, err
+	}
+	stats.readBytes = io["read_bytes"]
+	stats.writeBytes = io["write_bytes"]
+
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil && !errors.Is(err, fs.ErrPermission) {
+		return stats, err
+	}
+	stats.fds = uint64(len(fds))
+
+	return stats, nil
+}
+
+// readProcFields reads numeric fields of "Key: value [unit]" formatted file, e.g. /proc/<pid>/status. Non-numeric
+// fields are skipped.
+func readProcFields(name string) (map[string]uint64, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	fields := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		values := strings.Fields(value)
+		if len(values) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(values[0], 10, 64); err == nil {
+			fields[key] = v
+		}
+	}
+	return fields, scanner.Err()
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestProcesses(t *testing.T) {
+	root := filepath.Join("testdata", "proc")
+
+	stats := func(target string, count, rss, cpu, threads, fds, read, write float64) metric.List {
+		labels := metric.Labels{ProcessLabel: target}
+		return metric.List{
+			metric.NewGaugeMetric("ProcessCount", metric.Gauge(count)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessRSS", metric.Gauge(rss)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessCPUTime", metric.Gauge(cpu)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessThreads", metric.Gauge(threads)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessOpenFDs", metric.Gauge(fds)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessReadBytes", metric.Gauge(read)).WithLabels(labels),
+			metric.NewGaugeMetric("ProcessWriteBytes", metric.Gauge(write)).WithLabels(labels),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   metric.List
+	}{
+		{
+			name:   "PID",
+			target: "200",
+			want:   stats("200", 1, 4<<20, 15, 30, 10, 0, 512),
+		},
+		{
+			name:   "Prefixed PID",
+			target: "pid:100",
+			want:   stats("pid:100", 1, 1<<20, 2, 1, 3, 4096, 8192),
+		},
+		{
+			name:   "Pidfile",
+			target: "pidfile:" + filepath.Join("testdata", "app.pid"),
+			want:   stats("pidfile:"+filepath.Join("testdata", "app.pid"), 1, 2<<20, 3, 2, 5, 1024, 0),
+		},
+		{
+			name:   "Name matches all processes",
+			target: "name:^nginx$",
+			want:   stats("name:^nginx$", 2, 3<<20, 5, 3, 8, 5120, 8192),
+		},
+		{
+			name:   "Name doesn't match command line",
+			target: "name:app\\.jar",
+			want:   stats("name:app\\.jar", 0, 0, 0, 0, 0, 0, 0),
+		},
+		{
+			name:   "Name matches executable",
+			target: "name:^java$",
+			want:   stats("name:^java$", 1, 4<<20, 15, 30, 10, 0, 512),
+		},
+		{
+			name:   "Command line",
+			target: "cmdline:-jar app\\.jar$",
+			want:   stats("cmdline:-jar app\\.jar$", 1, 4<<20, 15, 30, 10, 0, 512),
+		},
+		{
+			name:   "Process is not running",
+			target: "999",
+			want:   stats("999", 0, 0, 0, 0, 0, 0, 0),
+		},
+		{
+			name:   "Pidfile is missing",
+			target: "pidfile:missing.pid",
+			want:   stats("pidfile:missing.pid", 0, 0, 0, 0, 0, 0, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector, err := Processes(root, tt.target)
+			require.NoError(t, err)
+
+			froze := NewFroze()
+			if assert.NoError(t, collector(context.TODO(), froze)) {
+				assert.ElementsMatch(t, tt.want, froze.List())
+			}
+		})
+	}
+
+	t.Run("Invalid target", func(t *testing.T) {
+		for _, target := range []string{"pid:abc", "-1", "pidfile:", "name:(", "cmdline:(", "foo:bar"} {
+			_, err := Processes(root, target)
+			assert.Error(t, err, target)
+		}
+	})
+
+	t.Run("Agent itself is not matched", func(t *testing.T) {
+		self := t.TempDir()
+		copyDir(t, filepath.Join(root, "100"), filepath.Join(self, "100"))
+		copyDir(t, filepath.Join(root, "100"), filepath.Join(self, strconv.Itoa(os.Getpid())))
+
+		collector, err := Processes(self, "name:^nginx$", "cmdline:^nginx", "pid:100")
+		require.NoError(t, err)
+
+		froze := NewFroze()
+		if assert.NoError(t, collector(context.TODO(), froze)) {
+			var want metric.List
+			for _, target := range []string{"name:^nginx$", "cmdline:^nginx", "pid:100"} {
+				want = append(want, stats(target, 1, 1<<20, 2, 1, 3, 4096, 8192)...)
+			}
+			assert.ElementsMatch(t, want, froze.List())
+		}
+	})
+}
+
+// copyDir copies procfs fixture of process.
+func copyDir(t *testing.T, src, dst string) {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+	require.NoError(t, err)
+}
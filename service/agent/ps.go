@@ -2,11 +2,12 @@ package agent
 
 import (
 	"context"
-	"fmt"
+	"strconv"
 
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/mem"
 
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
 )
 
@@ -29,7 +30,7 @@ func PS() Collector {
 			return err
 		}
 		for i := 0; i < len(usage); i++ {
-			froze.UpdateGauge(fmt.Sprintf("CPUutilization%d", i+1), usage[i])
+			froze.UpdateLabeledGauge("CPUutilization", metric.Labels{"cpu": strconv.Itoa(i + 1)}, usage[i])
 		}
 		return nil
 	}
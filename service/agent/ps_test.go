@@ -2,8 +2,8 @@ package agent
 
 import (
 	"context"
-	"fmt"
 	"runtime"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,7 +18,8 @@ func TestPS(t *testing.T) {
 		metric.NewGaugeMetric("FreeMemory", metric.Gauge(0)),
 	}
 	for i := 0; i < runtime.NumCPU(); i++ {
-		expected = append(expected, metric.NewGaugeMetric(fmt.Sprintf("CPUutilization%d", i+1), metric.Gauge(0)))
+		expected = append(expected, metric.NewGaugeMetric("CPUutilization", metric.Gauge(0)).
+			WithLabels(metric.Labels{"cpu": strconv.Itoa(i + 1)}))
 	}
 
 	froze := NewFroze()
@@ -31,7 +32,7 @@ func TestPS(t *testing.T) {
 		for _, m := range froze.List() {
 			v, err := m.Type().New()
 			require.NoError(t, err)
-			list0 = append(list0, &metric.Metric{ID: m.ID, Value: v})
+			list0 = append(list0, &metric.Metric{ID: m.ID, Value: v, Labels: m.Labels})
 		}
 
 		if assert.NoError(t, err) {
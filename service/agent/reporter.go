@@ -2,8 +2,13 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"net"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
@@ -16,6 +21,8 @@ var _ ReporterService = (*metricsReporter)(nil)
 type metricsReporter struct {
 	monitor.Provider
 	froze    *Froze
+	outbox   *Outbox
+	backoff  task.Backoff
 	interval time.Duration
 }
 
@@ -25,17 +32,84 @@ func (r *metricsReporter) Report(ctx context.Context) {
 	logger.Info().Msg("reporting metrics to monitor")
 
 	list := r.read()
-	if err := r.UpdateBulk(ctx, list); err != nil {
+	if r.outbox != nil {
+		if err := r.outbox.Replay(ctx, r.send); err != nil {
+			logger.Err(err).Msg("unsent metrics replay failed")
+			r.keep(ctx, list)
+			return
+		}
+	}
+	if err := r.send(ctx, list); err != nil {
+		if task.IsPermanent(err) {
+			logger.Err(err).Msgf("metrics dropped (%d events)", len(list))
+			return
+		}
 		logger.Err(err).Msg("metrics not sent")
+		r.keep(ctx, list)
 		return
 	}
 	logger.Info().Msgf("reporting completed (%d events)", len(list))
 }
 
+// send sends metrics to monitor retrying on failures. Monitor adds up counters and histograms, so metrics having any
+// of them are not resent after timeout: monitor may have applied them before the response was lost. Such failure
+// is permanent as well as monitor rejection.
+func (r *metricsReporter) send(ctx context.Context, list metric.List) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(r), logging.WithCID(ctx))
+	idempotent := isIdempotent(list)
+	return task.ErrTask(func(ctx context.Context) error {
+		err := r.UpdateBulk(ctx, list)
+		if err != nil {
+			logger.Warn().Err(err).Msg("send attempt failed")
+			if !idempotent && isTimeout(err) {
+				return task.Permanent(err)
+			}
+		}
+		return err
+	}).With(task.Retry(r.backoff))(ctx)
+}
+
+// keep saves unsent metrics to outbox to be replayed on next report. Counters and histograms are returned to Froze
+// to be reported along with next increments if outbox is not set.
+func (r *metricsReporter) keep(ctx context.Context, list metric.List) {
+	if r.outbox == nil {
+		r.froze.Lock()
+		defer r.froze.Unlock()
+		r.froze.Restore(list)
+		return
+	}
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(r), logging.WithCID(ctx))
+	if err := r.outbox.Push(ctx, list); err != nil {
+		logger.Err(err).Msg("failed to keep unsent metrics")
+		return
+	}
+	logger.Info().Msgf("unsent metrics kept (%+v)", r.outbox.Stats())
+}
+
+// isIdempotent reports whether list may be sent several times without changing the result, i.e. it has gauges only.
+func isIdempotent(list metric.List) bool {
+	for _, mtr := range list {
+		if mtr.Type() != metric.GaugeType {
+			return false
+		}
+	}
+	return true
+}
+
+// isTimeout reports whether err is a timeout that leaves request outcome unknown.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) && netErr.Timeout() ||
+		status.Code(err) == codes.DeadlineExceeded
+}
+
+// read takes metrics from Froze. Monitor adds up counters and histograms, so only their increments since previous
+// report are read.
 func (r metricsReporter) read() metric.List {
 	r.froze.Lock()
 	defer r.froze.Unlock()
-	return r.froze.List()
+	return r.froze.Take()
 }
 
 func (r *metricsReporter) BackgroundTask() task.Task {
@@ -46,11 +120,14 @@ func (r *metricsReporter) Name() string {
 	return "Agent metrics reporter"
 }
 
-// NewMetricsReporter creates new metrics reporting service. Each time the service is called to report it will read entirely
-// metrics from Froze and send it to monitor.Provider.
-func NewMetricsReporter(cfg *config.Config, froze *Froze, provider monitor.Provider) ReporterService {
+// NewMetricsReporter creates new metrics reporting service. Each time the service is called to report it will read
+// gauges and increments of counters and histograms from Froze and send them to monitor.Provider. Unsent metrics are kept
+// in outbox if it is specified and sent in order before actual metrics on subsequent reports.
+func NewMetricsReporter(cfg *config.Config, froze *Froze, provider monitor.Provider, outbox *Outbox) ReporterService {
 	return &metricsReporter{
 		froze:    froze,
+		outbox:   outbox,
+		backoff:  task.DefaultBackoff,
 		Provider: provider,
 		interval: cfg.ReportInterval,
 	}
@@ -2,12 +2,17 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor"
 	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/stub"
 )
 
@@ -29,7 +34,7 @@ func BenchmarkMetricsReporter(b *testing.B) {
 	b.Run("Report bulk", func(b *testing.B) {
 		froze := NewFroze()
 		publish(froze, count)
-		rep := NewMetricsReporter(&config.Config{}, froze, stub.New())
+		rep := NewMetricsReporter(&config.Config{}, froze, stub.New(), nil)
 		b.ResetTimer()
 
 		for i := 0; i < b.N; i++ {
@@ -39,6 +44,59 @@ func BenchmarkMetricsReporter(b *testing.B) {
 
 }
 
+func TestMetricsReporterIncrements(t *testing.T) {
+	ctx := context.TODO()
+	froze := NewFroze()
+	server := &summingProvider{Provider: stub.New(), counters: make(map[string]int64)}
+	rep := NewMetricsReporter(&config.Config{}, froze, server, nil)
+	rep.(*metricsReporter).backoff = task.Backoff{MaxAttempts: 1}
+
+	// cumulative source counter, e.g. bytes sent by network interface
+	counters := make(deltas)
+	poll := func(v uint64) {
+		froze.Lock()
+		defer froze.Unlock()
+		froze.UpdateCounter("NetBytesSent", counters.delta("NetBytesSent", v))
+		froze.UpdateCounter("PollCount", 1)
+	}
+
+	poll(1000)
+	poll(1010)
+	rep.Report(ctx)
+	poll(1025)
+	rep.Report(ctx)
+	assert.Equal(t, int64(25), server.counters["NetBytesSent"], "server value equals real increase")
+	assert.Equal(t, int64(3), server.counters["PollCount"])
+
+	server.fail = true
+	poll(1030)
+	rep.Report(ctx)
+	server.fail = false
+	poll(1040)
+	rep.Report(ctx)
+	assert.Equal(t, int64(40), server.counters["NetBytesSent"], "unsent increments are reported later")
+	assert.Equal(t, int64(5), server.counters["PollCount"])
+}
+
+// summingProvider adds up reported counters like monitor does.
+type summingProvider struct {
+	monitor.Provider
+	fail     bool
+	counters map[string]int64
+}
+
+func (p *summingProvider) UpdateBulk(_ context.Context, list metric.List) error {
+	if p.fail {
+		return errors.New("unavailable")
+	}
+	for _, mtr := range list {
+		if counter, ok := mtr.Value.(*metric.Counter); ok {
+			p.counters[mtr.ID] += int64(*counter)
+		}
+	}
+	return nil
+}
+
 func publish(froze *Froze, count int) {
 	for j := 0; j < count; j++ {
 		froze.UpdateGauge(fmt.Sprintf("foo%d", j+1), float64(j))
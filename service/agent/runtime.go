@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"strings"
+)
+
+// runtimeSecondsBounds are bucket bounds of exported runtime time histograms, e.g. GCPausesSeconds. Runtime keeps them
+// in fine grained buckets, hundreds of which are not worth reporting.
+var runtimeSecondsBounds = []float64{1e-6, 5e-6, 1e-5, 5e-5, 1e-4, 5e-4, 1e-3, 5e-3, 0.01, 0.05, 0.1, 0.5, 1}
+
+// runtimeAcronyms keeps acronyms in upper case in exported metrics names.
+var runtimeAcronyms = map[string]string{
+	"gc":  "GC",
+	"cpu": "CPU",
+	"os":  "OS",
+}
+
+// RuntimeMetrics collects every metric supported by runtime/metrics without stopping the world. Metric names are
+// mapped from keys, e.g. /sched/goroutines:goroutines is exported as SchedGoroutinesGoroutines. Cumulative integer
+// metrics are exported as counters incremented since previous poll, other scalar metrics are exported as gauges.
+// Histograms are exported as histograms which are merged with values observed since previous poll. Time histograms are
+// rebucketed to runtimeSecondsBounds, others keep runtime bucket bounds. If legacy is set, metrics are also reported
+// under legacy names of runtime.MemStats fields, see memStats.
+func RuntimeMetrics(legacy bool) Collector {
+	descriptions := metrics.All()
+	samples := make([]metrics.Sample, 0, len(descriptions))
+	names := make([]string, 0, len(descriptions))
+	cumulative := make([]bool, 0, len(descriptions))
+	for _, desc := range descriptions {
+		if desc.Kind == metrics.KindBad {
+			continue
+		}
+		samples = append(samples, metrics.Sample{Name: desc.Name})
+		names = append(names, runtimeMetricName(desc.Name))
+		cumulative = append(cumulative, desc.Cumulative)
+	}
+
+	counters := make(deltas)
+	prevHistograms := make(map[string][]uint64)
+	bounds := make(map[string][]float64)
+	var legacyStats *memStats
+	if legacy {
+		legacyStats = newMemStats(samples)
+	}
+
+	return func(ctx context.Context, froze *Froze) error {
+		metrics.Read(samples)
+
+		for i, sample := range samples {
+			name := names[i]
+			switch sample.Value.Kind() {
+			case metrics.KindUint64:
+				v := sample.Value.Uint64()
+				if !cumulative[i] {
+					froze.UpdateGauge(name, float64(v))
+					continue
+				}
+				froze.UpdateCounter(name, counters.delta(name, v))
+			case metrics.KindFloat64:
+				froze.UpdateGauge(name, sample.Value.Float64())
+			case metrics.KindFloat64Histogram:
+				hist := sample.Value.Float64Histogram()
+				prev := prevHistograms[name]
+				if _, ok := bounds[name]; !ok {
+					bounds[name] = runtimeHistogramBounds(sample.Name, hist.Buckets)
+					// histogram is reported even if nothing is observed yet, the first poll only seeds counts
+					froze.ObserveHistogram(name, bounds[name], 0, 0)
+					prevHistograms[name] = append(prev[:0], hist.Counts...)
+					continue
+				}
+				for j, c := range hist.Counts {
+					if j < len(prev) {
+						c -= prev[j]
+					}
+					if c != 0 {
+						froze.ObserveHistogram(name, bounds[name], runtimeBucketValue(hist.Buckets[j], hist.Buckets[j+1]), c)
+					}
+				}
+				prevHistograms[name] = append(prev[:0], hist.Counts...)
+			}
+		}
+		if legacyStats != nil {
+			legacyStats.publish(froze, samples)
+		}
+		return nil
+	}
+}
+
+// runtimeMetricName maps runtime/metrics key to camel case metric name, e.g. /gc/heap/allocs-by-size:bytes is mapped
+// to GCHeapAllocsBySizeBytes.
+func runtimeMetricName(key string) string {
+	var sb strings.Builder
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '_' || r == '.' || r == '*'
+	})
+	for _, word := range words {
+		if acronym, ok := runtimeAcronyms[word]; ok {
+			sb.WriteString(acronym)
+			continue
+		}
+		sb.WriteString(strings.ToUpper(word[:1]))
+		sb.WriteString(word[1:])
+	}
+	return sb.String()
+}
+
+// runtimeHistogramBounds returns bounds of exported histogram of runtime metric with specified key and buckets.
+func runtimeHistogramBounds(key string, buckets []float64) []float64 {
+	if strings.HasSuffix(key, ":seconds") {
+		return runtimeSecondsBounds
+	}
+	bounds := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b, 0) {
+			bounds = append(bounds, b)
+		}
+	}
+	return bounds
+}
+
+// runtimeBucketValue returns value representing observations of runtime histogram bucket [lower, upper). Exported
+// histogram sum is estimated with these values.
+func runtimeBucketValue(lower, upper float64) float64 {
+	switch {
+	case math.IsInf(lower, -1):
+		return upper
+	case math.IsInf(upper, 1):
+		return lower
+	}
+	return (lower + upper) / 2
+}
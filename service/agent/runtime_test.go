@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestRuntimeMetrics(t *testing.T) {
+	froze := NewFroze()
+	collector := RuntimeMetrics(false)
+
+	require.NoError(t, collector(context.TODO(), froze))
+	runtime.GC()
+	require.NoError(t, collector(context.TODO(), froze))
+
+	values := make(map[string]*metric.Metric)
+	for _, mtr := range froze.List() {
+		values[mtr.ID] = mtr
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		typ  metric.Type
+	}{
+		{
+			name: "Gauge",
+			id:   "SchedGoroutinesGoroutines",
+			typ:  metric.GaugeType,
+		},
+		{
+			name: "Cumulative counter",
+			id:   "GCHeapAllocsBytes",
+			typ:  metric.CounterType,
+		},
+		{
+			name: "Time histogram",
+			id:   "GCPausesSeconds",
+			typ:  metric.HistogramType,
+		},
+		{
+			name: "Histogram",
+			id:   "GCHeapAllocsBySizeBytes",
+			typ:  metric.HistogramType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mtr, ok := values[tt.id]
+			if assert.True(t, ok, "metric %s is not collected", tt.id) {
+				assert.Equal(t, tt.typ, mtr.Type())
+				assert.NotZero(t, mtr.Value)
+			}
+		})
+	}
+
+	if pauses, ok := values["GCPausesSeconds"]; ok {
+		hist := pauses.Value.(*metric.Histogram)
+		assert.Equal(t, runtimeSecondsBounds, hist.Bounds)
+		assert.NotZero(t, hist.Count, "GC pauses are observed")
+		assert.NoError(t, hist.Validate())
+	}
+}
+
+func Test_runtimeMetricName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "/sched/goroutines:goroutines", want: "SchedGoroutinesGoroutines"},
+		{key: "/gc/heap/allocs-by-size:bytes", want: "GCHeapAllocsBySizeBytes"},
+		{key: "/cpu/classes/gc/total:cpu-seconds", want: "CPUClassesGCTotalCPUSeconds"},
+		{key: "/memory/classes/os-stacks:bytes", want: "MemoryClassesOSStacksBytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, runtimeMetricName(tt.key))
+		})
+	}
+}
+
+func Test_runtimeHistogramBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		buckets []float64
+		want    []float64
+	}{
+		{
+			name:    "Time histogram",
+			key:     "/gc/pauses:seconds",
+			buckets: []float64{math.Inf(-1), 0, 1e-9, 2e-9, math.Inf(1)},
+			want:    runtimeSecondsBounds,
+		},
+		{
+			name:    "Runtime bounds",
+			key:     "/gc/heap/allocs-by-size:bytes",
+			buckets: []float64{1, 9, 17, math.Inf(1)},
+			want:    []float64{1, 9, 17},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, runtimeHistogramBounds(tt.key, tt.buckets))
+		})
+	}
+}
+
+func Test_runtimeBucketValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		lower, upper float64
+		want         float64
+	}{
+		{name: "Bounded bucket", lower: 1, upper: 3, want: 2},
+		{name: "Underflow bucket", lower: math.Inf(-1), upper: 0, want: 0},
+		{name: "Unbounded bucket", lower: 5, upper: math.Inf(1), want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, runtimeBucketValue(tt.lower, tt.upper))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+)
+
+// statsdMaxPacket is the maximum size of UDP datagram.
+const statsdMaxPacket = 65535
+
+// StatsDTimerBounds are bucket bounds of timer histograms in milliseconds.
+var StatsDTimerBounds = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+var _ ListenerService = (*statsdListener)(nil)
+
+type (
+	statsdListener struct {
+		froze   *Froze
+		address string
+	}
+
+	// statsdLine is a single metric update of StatsD line protocol, e.g. "requests:1|c|@0.1".
+	statsdLine struct {
+		name     string
+		typ      string
+		value    float64
+		relative bool
+		rate     float64
+	}
+)
+
+func (l *statsdListener) Listen(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	ctx, logger := logging.GetOrCreateLogger(ctx, logging.WithService(l), logging.WithCID(ctx))
+
+	conn, err := net.ListenPacket("udp", l.address)
+	if err != nil {
+		logger.Err(err).Msg("failed to listen")
+		return
+	}
+	logger.Info().Msgf("listening on %s", conn.LocalAddr())
+	l.serve(ctx, conn)
+	logger.Info().Msg("listener stopped")
+}
+
+// serve receives packets until context is cancelled. Connection is closed on return.
+func (l *statsdListener) serve(ctx context.Context, conn net.PacketConn) {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Err(err).Msg("failed to close listener")
+		}
+	}()
+
+	buf := make([]byte, statsdMaxPacket)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				logger.Err(err).Msg("failed to receive packet")
+			}
+			return
+		}
+		l.handle(ctx, buf[:n])
+	}
+}
+
+// handle parses packet of newline separated metric updates and publishes them on Froze. Malformed lines are skipped.
+func (l *statsdListener) handle(ctx context.Context, packet []byte) {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	lines := make([]*statsdLine, 0, 1)
+	for _, s := range strings.Split(string(packet), "\n") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		line, err := parseStatsDLine(s)
+		if err != nil {
+			logger.Warn().Err(err).Msgf("skipped line %q", s)
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	l.froze.Lock()
+	defer l.froze.Unlock()
+	for _, line := range lines {
+		line.publish(l.froze)
+	}
+}
+
+func (l *statsdListener) BackgroundTask() task.Task {
+	if l.address == "" {
+		return task.VoidTask
+	}
+	return l.Listen
+}
+
+func (l *statsdListener) Name() string {
+	return "StatsD listener"
+}
+
+// publish applies update to Froze. Counters and timers are scaled by sample rate. Thread unsafe, Froze should be locked.
+func (s *statsdLine) publish(froze *Froze) {
+	switch s.typ {
+	case "c":
+		froze.UpdateCounter(s.name, int64(math.Round(s.value/s.rate)))
+	case "g":
+		if s.relative {
+			froze.AddGauge(s.name, s.value)
+			return
+		}
+		froze.UpdateGauge(s.name, s.value)
+	case "ms", "h":
+		froze.ObserveHistogram(s.name, StatsDTimerBounds, s.value, uint64(math.Round(1/s.rate)))
+	}
+}
+
+// parseStatsDLine parses line of format <name>:<value>|<type>[|@<sample rate>][|#<tags>]. Counters (c), gauges (g),
+// timers (ms) and histograms (h) are supported. Counter value must be integer. Gauge value prefixed with sign is added
+// to the current one. Tags are ignored.
+func parseStatsDLine(s string) (*statsdLine, error) {
+	parts := strings.Split(s, "|")
+	if len(parts) < 2 {
+		return nil, errors.New("statsd: metric type expected")
+	}
+
+	i := strings.LastIndexByte(parts[0], ':')
+	if i <= 0 {
+		return nil, errors.New("statsd: metric name and value expected")
+	}
+	line := &statsdLine{name: parts[0][:i], typ: parts[1], rate: 1}
+
+	raw := parts[0][i+1:]
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("statsd: invalid value %q", raw)
+	}
+	line.value = value
+
+	switch line.typ {
+	case "c":
+		if value != math.Trunc(value) {
+			return nil, fmt.Errorf("statsd: invalid counter value %q", raw)
+		}
+	case "ms", "h":
+	case "g":
+		line.relative = strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "-")
+	default:
+		return nil, fmt.Errorf("statsd: unsupported metric type %q", line.typ)
+	}
+
+	for _, part := range parts[2:] {
+		if !strings.HasPrefix(part, "@") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(part[1:], 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("statsd: invalid sample rate %q", part)
+		}
+		line.rate = rate
+	}
+	if line.typ == "c" && math.Abs(line.value/line.rate) >= math.MaxInt64 {
+		return nil, fmt.Errorf("statsd: counter value %q is out of range", raw)
+	}
+	return line, nil
+}
+
+// NewStatsDListener creates service receiving metrics pushed by applications over StatsD line protocol on UDP
+// cfg.StatsDAddress. Received metrics are published on Froze and reported along with collected ones.
+func NewStatsDListener(cfg *config.Config, froze *Froze) ListenerService {
+	return &statsdListener{
+		froze:   froze,
+		address: cfg.StatsDAddress,
+	}
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestStatsDListener_handle(t *testing.T) {
+	timer := func(v float64, n uint64) *metric.Histogram {
+		h := metric.NewHistogram(StatsDTimerBounds...)
+		h.ObserveN(v, n)
+		return &h
+	}
+
+	tests := []struct {
+		name    string
+		packets []string
+		want    metric.List
+	}{
+		{
+			name:    "Counter",
+			packets: []string{"requests:1|c", "requests:2|c"},
+			want:    metric.List{metric.NewCounterMetric("requests", 3)},
+		},
+		{
+			name:    "Sampled counter",
+			packets: []string{"requests:1|c|@0.1"},
+			want:    metric.List{metric.NewCounterMetric("requests", 10)},
+		},
+		{
+			name:    "Gauge",
+			packets: []string{"queue.size:10|g", "queue.size:5|g"},
+			want:    metric.List{metric.NewGaugeMetric("queue.size", 5)},
+		},
+		{
+			name:    "Relative gauge",
+			packets: []string{"queue.size:10|g", "queue.size:+5|g", "queue.size:-3|g"},
+			want:    metric.List{metric.NewGaugeMetric("queue.size", 12)},
+		},
+		{
+			name:    "Relative gauge goes negative",
+			packets: []string{"queue.size:3|g", "queue.size:-5|g"},
+			want:    metric.List{metric.NewGaugeMetric("queue.size", -2)},
+		},
+		{
+			name:    "Timer",
+			packets: []string{"latency:42|ms", "latency:7|ms|@0.5"},
+			want: metric.List{func() *metric.Metric {
+				h := timer(42, 1)
+				h.ObserveN(7, 2)
+				return metric.NewHistogramMetric("latency", *h)
+			}()},
+		},
+		{
+			name:    "Histogram with tags",
+			packets: []string{"latency:300|h|#env:prod"},
+			want:    metric.List{metric.NewHistogramMetric("latency", *timer(300, 1))},
+		},
+		{
+			name:    "Multiple lines",
+			packets: []string{"requests:1|c\nqueue.size:1|g\n"},
+			want: metric.List{
+				metric.NewCounterMetric("requests", 1),
+				metric.NewGaugeMetric("queue.size", 1),
+			},
+		},
+		{
+			name: "Malformed lines are skipped",
+			packets: []string{
+				"requests:1|c\nrequests\nrequests:1\n:1|c\nrequests:foo|c\nrequests:1|s\nrequests:1|c|@0\nrequests:1|c|@2" +
+					"\nrequests:0.5|c\nrequests:1e100|c",
+			},
+			want: metric.List{metric.NewCounterMetric("requests", 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			froze := NewFroze()
+			l := NewStatsDListener(&config.Config{}, froze).(*statsdListener)
+			for _, packet := range tt.packets {
+				l.handle(context.TODO(), []byte(packet))
+			}
+			assert.ElementsMatch(t, tt.want, froze.List())
+		})
+	}
+}
+
+func TestStatsDListener_serve(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	froze := NewFroze()
+	l := NewStatsDListener(&config.Config{}, froze).(*statsdListener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.serve(ctx, conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("requests:5|c"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		froze.Lock()
+		defer froze.Unlock()
+		list := froze.List()
+		return len(list) == 1 && assert.ObjectsAreEqual(metric.NewCounterMetric("requests", 5), list[0])
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listener is not stopped")
+	}
+}
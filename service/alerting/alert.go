@@ -0,0 +1,48 @@
+package alerting
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+const (
+	// StatePending means rule condition is met but not long enough to fire.
+	StatePending State = "pending"
+
+	// StateFiring means rule condition is met for at least rule duration.
+	StateFiring State = "firing"
+
+	// StateResolved means rule condition of fired alert is not met anymore.
+	StateResolved State = "resolved"
+)
+
+var _ logging.LogCtxProvider = (*Alert)(nil)
+
+type (
+	// State is alert lifecycle state.
+	State string
+
+	// Alert is a rule triggered by particular metric series.
+	Alert struct {
+		Rule       string        `json:"rule"`
+		Expr       string        `json:"expr"`
+		Summary    string        `json:"summary,omitempty"`
+		MetricID   string        `json:"metric_id"`
+		Labels     metric.Labels `json:"labels,omitempty"`
+		State      State         `json:"state"`
+		Value      float64       `json:"value"`
+		ActiveAt   time.Time     `json:"active_at"`
+		FiredAt    *time.Time    `json:"fired_at,omitempty"`
+		ResolvedAt *time.Time    `json:"resolved_at,omitempty"`
+	}
+)
+
+func (a *Alert) LoggerCtx(ctx zerolog.Context) zerolog.Context {
+	ctx = ctx.Str(logging.AlertRuleKey, a.Rule).Str(logging.AlertStateKey, string(a.State))
+	ctx = ctx.Str(logging.MetricIDKey, a.MetricID).Float64(logging.MetricValueKey, a.Value)
+	return logging.LogCtxUpdateWith(ctx, a.Labels)
+}
@@ -0,0 +1,266 @@
+// Package alerting is service responsible for evaluation of alerting rules against monitored metrics.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+)
+
+// ResolvedRetention is a period resolved alerts are kept queryable.
+const ResolvedRetention = 15 * time.Minute
+
+var _ Alerting = (*alerting)(nil)
+
+type (
+	// Alerting application service periodically evaluates alerting rules and tracks alerts lifecycle.
+	Alerting interface {
+		pkg.BackgroundService
+
+		// Evaluate evaluates all rules once updating alerts states and notifying of alerts state changes.
+		Evaluate(ctx context.Context)
+
+		// Alerts returns actual alerts in specified state. Empty state matches all alerts.
+		Alerts(ctx context.Context, state State) []Alert
+	}
+
+	alerting struct {
+		sync.RWMutex
+		monitor   monitor.Monitor
+		rules     []*Rule
+		notifiers []Notifier
+		interval  time.Duration
+		alerts    map[string]*Alert
+		now       func() time.Time
+	}
+)
+
+func (a *alerting) Evaluate(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(a), logging.WithCID(ctx))
+	logger.Info().Msg("evaluating rules")
+	ctx = logging.SetLogger(ctx, logger)
+
+	now := a.now()
+	values := make(map[string]*Alert)
+	failed := make(map[string]bool)
+	for _, rule := range a.rules {
+		alerts, err := a.evaluate(ctx, rule, now)
+		if err != nil {
+			logger.Err(err).Msgf("failed to evaluate rule %v", rule)
+			failed[rule.Name] = true
+			continue
+		}
+		for _, alert := range alerts {
+			values[alertKey(alert)] = alert
+		}
+	}
+
+	a.Lock()
+	changed := a.update(values, failed, now)
+	a.Unlock()
+
+	for _, alert := range changed {
+		for _, notifier := range a.notifiers {
+			if err := notifier.Notify(ctx, alert); err != nil {
+				logger.Err(err).Msg("failed to notify")
+			}
+		}
+	}
+	logger.Info().Msgf("evaluation completed (%d state changes)", len(changed))
+}
+
+// evaluate returns candidate alerts for every metric series matching rule selector. Candidate state is pending if rule
+// condition is met, otherwise state is empty.
+func (a *alerting) evaluate(ctx context.Context, rule *Rule, now time.Time) ([]*Alert, error) {
+	list, err := a.monitor.GetAll(ctx, rule.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*Alert, 0)
+	for _, mtr := range list {
+		if mtr.ID != rule.ID {
+			continue
+		}
+		if typ := mtr.Type(); typ != metric.GaugeType && typ != metric.CounterType {
+			continue
+		}
+
+		value := metric.ToFloat(mtr.Value)
+		if rule.Func == FuncRate {
+			if value, err = a.rate(ctx, mtr, rule.Window, now); err != nil {
+				return nil, err
+			}
+		}
+
+		alert := &Alert{
+			Rule:     rule.Name,
+			Expr:     rule.Expr,
+			Summary:  rule.Summary,
+			MetricID: mtr.ID,
+			Labels:   mtr.Labels.Copy(),
+			Value:    value,
+			ActiveAt: now,
+		}
+		if rule.Check(value) {
+			alert.State = StatePending
+			if rule.For == 0 {
+				alert.State = StateFiring
+			}
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// rate calculates per-second increase of metric value within window. Rate is zero if metric was not updated within
+// window, so silent series still present in storage is evaluated as stale.
+func (a *alerting) rate(ctx context.Context, mtr *metric.Metric, window time.Duration, now time.Time) (float64, error) {
+	series, err := a.monitor.Query(ctx, &metric.Query{
+		ID:     mtr.ID,
+		Type:   mtr.Type(),
+		Labels: mtr.Labels,
+		From:   now.Add(-window),
+		To:     now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(series) < 2 {
+		return 0, nil
+	}
+	first, last := series[0], series[len(series)-1]
+	elapsed := last.Timestamp.Sub(first.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	return (last.Value - first.Value) / elapsed, nil
+}
+
+// update applies evaluated candidates to actual alerts and returns copies of alerts which have been fired or resolved.
+// Alerts of failed rules are left untouched.
+func (a *alerting) update(values map[string]*Alert, failed map[string]bool, now time.Time) []Alert {
+	rules := make(map[string]*Rule, len(a.rules))
+	for _, rule := range a.rules {
+		rules[rule.Name] = rule
+	}
+
+	changed := make([]Alert, 0)
+	for key, value := range values {
+		alert, ok := a.alerts[key]
+		if value.State == "" {
+			if ok {
+				alert.Value = value.Value
+			}
+			continue
+		}
+		if !ok || alert.State == StateResolved {
+			alert = value
+			alert.State = StatePending
+			a.alerts[key] = alert
+		}
+		alert.Value = value.Value
+		if alert.State == StatePending && now.Sub(alert.ActiveAt) >= rules[alert.Rule].For {
+			alert.State = StateFiring
+			alert.FiredAt = timeRef(now)
+			changed = append(changed, *alert)
+		}
+	}
+
+	for key, alert := range a.alerts {
+		if failed[alert.Rule] {
+			continue
+		}
+		if value, ok := values[key]; ok && value.State != "" {
+			continue
+		}
+		switch alert.State {
+		case StatePending:
+			delete(a.alerts, key)
+		case StateFiring:
+			alert.State = StateResolved
+			alert.ResolvedAt = timeRef(now)
+			changed = append(changed, *alert)
+		case StateResolved:
+			if now.Sub(*alert.ResolvedAt) > ResolvedRetention {
+				delete(a.alerts, key)
+			}
+		}
+	}
+	return changed
+}
+
+func (a *alerting) Alerts(ctx context.Context, state State) []Alert {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(a), logging.WithCID(ctx))
+
+	a.RLock()
+	alerts := make([]Alert, 0, len(a.alerts))
+	for _, alert := range a.alerts {
+		if state == "" || alert.State == state {
+			alerts = append(alerts, *alert)
+		}
+	}
+	a.RUnlock()
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Labels.String() < alerts[j].Labels.String()
+	})
+	logger.Trace().Msgf("%d alerts queried", len(alerts))
+	return alerts
+}
+
+func (a *alerting) BackgroundTask() task.Task {
+	if len(a.rules) == 0 {
+		return task.VoidTask
+	}
+	return task.Task(a.Evaluate).With(task.PeriodicRun(a.interval))
+}
+
+func (a *alerting) Name() string {
+	return "Alerting service"
+}
+
+func alertKey(alert *Alert) string {
+	return fmt.Sprintf("%s\x00%s%s", alert.Rule, alert.MetricID, alert.Labels)
+}
+
+func timeRef(t time.Time) *time.Time {
+	return &t
+}
+
+// NewAlerting creates Alerting application service. Rules are loaded from cfg.AlertRules file. Service is idle if rules
+// file is not specified.
+func NewAlerting(cfg *config.Config, mon monitor.Monitor, notifiers ...Notifier) (Alerting, error) {
+	var rules []*Rule
+	if len(cfg.AlertRules) != 0 {
+		var err error
+		if rules, err = LoadRules(cfg.AlertRules); err != nil {
+			return nil, fmt.Errorf("failed to load alerting rules: %w", err)
+		}
+	}
+	interval := cfg.AlertInterval
+	if interval <= 0 {
+		interval = config.DefaultAlertInterval
+	}
+	return &alerting{
+		monitor:   mon,
+		rules:     rules,
+		notifiers: notifiers,
+		interval:  interval,
+		alerts:    make(map[string]*Alert),
+		now:       time.Now,
+	}, nil
+}
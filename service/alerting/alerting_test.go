@@ -0,0 +1,258 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/service/monitor"
+)
+
+var _ monitor.Monitor = (*monitorStub)(nil)
+
+type monitorStub struct {
+	list   metric.List
+	series metric.Series
+	err    error
+}
+
+func (s *monitorStub) BackgroundTask() task.Task {
+	return task.VoidTask
+}
+
+func (s *monitorStub) Name() string {
+	return "Stub monitor service"
+}
+
+func (s *monitorStub) Restore(context.Context) error {
+	return nil
+}
+
+func (s *monitorStub) Update(context.Context, *metric.Metric) error {
+	return nil
+}
+
+func (s *monitorStub) UpdateBulk(context.Context, metric.List) error {
+	return nil
+}
+
+func (s *monitorStub) Ping(context.Context) error {
+	return nil
+}
+
+func (s *monitorStub) Get(context.Context, string, metric.Type, metric.Labels) (*metric.Metric, error) {
+	return nil, nil
+}
+
+func (s *monitorStub) GetAll(_ context.Context, filter metric.Labels) (metric.List, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	list := make(metric.List, 0, len(s.list))
+	for _, mtr := range s.list {
+		if mtr.Labels.Matches(filter) {
+			list = append(list, mtr)
+		}
+	}
+	return list, nil
+}
+
+func (s *monitorStub) Query(context.Context, *metric.Query) (metric.Series, error) {
+	return s.series, s.err
+}
+
+type recordingNotifier []Alert
+
+func (n *recordingNotifier) Notify(_ context.Context, alert Alert) error {
+	*n = append(*n, alert)
+	return nil
+}
+
+func newTestAlerting(mon monitor.Monitor, notifier Notifier, now *time.Time, exprs ...string) *alerting {
+	rules := make([]*Rule, 0, len(exprs))
+	for i, expr := range exprs {
+		rule, err := ParseRule(string(rune('a'+i)), expr)
+		if err != nil {
+			panic(err)
+		}
+		rules = append(rules, rule)
+	}
+	return &alerting{
+		monitor:   mon,
+		rules:     rules,
+		notifiers: []Notifier{notifier},
+		alerts:    make(map[string]*Alert),
+		now:       func() time.Time { return *now },
+	}
+}
+
+func TestAlerting_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mon := &monitorStub{}
+	notifier := &recordingNotifier{}
+	svc := newTestAlerting(mon, notifier, &now, "HeapAlloc > 1K for 1m")
+
+	step := func(d time.Duration, value float64) {
+		now = now.Add(d)
+		mon.list = metric.List{metric.NewGaugeMetric("HeapAlloc", metric.Gauge(value))}
+		svc.Evaluate(ctx)
+	}
+
+	step(0, 10)
+	assert.Empty(t, svc.Alerts(ctx, ""))
+
+	step(time.Second, 2000)
+	alerts := svc.Alerts(ctx, "")
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatePending, alerts[0].State)
+	assert.Empty(t, *notifier)
+
+	step(30*time.Second, 10)
+	assert.Empty(t, svc.Alerts(ctx, ""), "pending alert is dropped once condition is not met")
+
+	step(time.Second, 2000)
+	step(time.Minute, 3000)
+	alerts = svc.Alerts(ctx, StateFiring)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 3000.0, alerts[0].Value)
+	require.NotNil(t, alerts[0].FiredAt)
+	require.Len(t, *notifier, 1)
+	assert.Equal(t, StateFiring, (*notifier)[0].State)
+
+	step(time.Second, 4000)
+	assert.Len(t, *notifier, 1, "firing alert is notified once")
+
+	step(time.Second, 10)
+	alerts = svc.Alerts(ctx, StateResolved)
+	require.Len(t, alerts, 1)
+	require.NotNil(t, alerts[0].ResolvedAt)
+	require.Len(t, *notifier, 2)
+	assert.Equal(t, StateResolved, (*notifier)[1].State)
+
+	step(ResolvedRetention+time.Second, 10)
+	assert.Empty(t, svc.Alerts(ctx, ""), "resolved alert is dropped after retention")
+	assert.Len(t, *notifier, 2)
+}
+
+func TestAlerting_EvaluateSeries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mon := &monitorStub{list: metric.List{
+		metric.NewGaugeMetric("CPUutilization", metric.Gauge(95)).WithLabels(metric.Labels{"cpu": "1"}),
+		metric.NewGaugeMetric("CPUutilization", metric.Gauge(50)).WithLabels(metric.Labels{"cpu": "2"}),
+		metric.NewGaugeMetric("CPUutilization", metric.Gauge(99)).WithLabels(metric.Labels{"cpu": "3"}),
+		metric.NewGaugeMetric("Other", metric.Gauge(99)).WithLabels(metric.Labels{"cpu": "1"}),
+	}}
+	notifier := &recordingNotifier{}
+	svc := newTestAlerting(mon, notifier, &now, "CPUutilization >= 90")
+
+	svc.Evaluate(ctx)
+	alerts := svc.Alerts(ctx, StateFiring)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, metric.Labels{"cpu": "1"}, alerts[0].Labels)
+	assert.Equal(t, metric.Labels{"cpu": "3"}, alerts[1].Labels)
+	assert.Len(t, *notifier, 2)
+
+	mon.list = mon.list[:1]
+	now = now.Add(time.Second)
+	svc.Evaluate(ctx)
+	alerts = svc.Alerts(ctx, StateResolved)
+	require.Len(t, alerts, 1, "alert of vanished series is resolved")
+	assert.Equal(t, metric.Labels{"cpu": "3"}, alerts[0].Labels)
+
+	mon.err = errors.New("storage failure")
+	now = now.Add(time.Second)
+	svc.Evaluate(ctx)
+	assert.Len(t, svc.Alerts(ctx, StateFiring), 1, "alerts are kept if rule failed to evaluate")
+	assert.Len(t, *notifier, 3)
+}
+
+func TestAlerting_EvaluateRate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		series    metric.Series
+		wantValue float64
+		wantFire  bool
+	}{
+		{
+			name: "Growing counter",
+			series: metric.Series{
+				{Timestamp: now.Add(-40 * time.Second), Value: 10},
+				{Timestamp: now.Add(-20 * time.Second), Value: 20},
+				{Timestamp: now, Value: 50},
+			},
+			wantValue: 1,
+		},
+		{
+			name: "Stale counter",
+			series: metric.Series{
+				{Timestamp: now.Add(-40 * time.Second), Value: 10},
+				{Timestamp: now, Value: 10},
+			},
+			wantFire: true,
+		},
+		{
+			name:     "Not enough points",
+			series:   metric.Series{{Timestamp: now, Value: 10}},
+			wantFire: true,
+		},
+		{
+			name:     "No points",
+			wantFire: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mon := &monitorStub{
+				list:   metric.List{metric.NewCounterMetric("PollCount", metric.Counter(50))},
+				series: tt.series,
+			}
+			svc := newTestAlerting(mon, &recordingNotifier{}, &now, "rate(PollCount) == 0")
+
+			alerts, err := svc.evaluate(ctx, svc.rules[0], now)
+			require.NoError(t, err)
+			require.Len(t, alerts, 1)
+			assert.Equal(t, tt.wantValue, alerts[0].Value)
+			assert.Equal(t, tt.wantFire, alerts[0].State == StateFiring)
+		})
+	}
+}
+
+func TestAlerting_EvaluateSilentSeries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mon := &monitorStub{
+		list: metric.List{metric.NewCounterMetric("PollCount", metric.Counter(50))},
+		series: metric.Series{
+			{Timestamp: now.Add(-20 * time.Second), Value: 40},
+			{Timestamp: now, Value: 50},
+		},
+	}
+	notifier := &recordingNotifier{}
+	svc := newTestAlerting(mon, notifier, &now, "rate(PollCount) == 0 for 1m")
+
+	svc.Evaluate(ctx)
+	assert.Empty(t, svc.Alerts(ctx, ""))
+
+	// agent goes silent: no samples within window while series is still stored
+	mon.series = nil
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Minute)
+		svc.Evaluate(ctx)
+	}
+	alerts := svc.Alerts(ctx, StateFiring)
+	require.Len(t, alerts, 1, "silent series fires and stays firing")
+	assert.Zero(t, alerts[0].Value)
+	assert.Len(t, *notifier, 1)
+}
@@ -0,0 +1,89 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+const (
+	logNotifierName     = "Alerts log notifier"
+	webhookNotifierName = "Alerts webhook notifier"
+
+	// DefaultWebhookTimeout limits webhook call duration.
+	DefaultWebhookTimeout = 5 * time.Second
+)
+
+var (
+	_ Notifier = (*logNotifier)(nil)
+	_ Notifier = (*webhookNotifier)(nil)
+)
+
+type (
+	// Notifier emits alert state changes. Notifier is called on firing and resolving of alert.
+	Notifier interface {
+		Notify(ctx context.Context, alert Alert) error
+	}
+
+	logNotifier struct{}
+
+	webhookNotifier struct {
+		*resty.Client
+		url string
+	}
+)
+
+func (n *logNotifier) Notify(ctx context.Context, alert Alert) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(logNotifierName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(&alert))
+
+	switch alert.State {
+	case StateFiring:
+		logger.Warn().Msgf("alert is firing: %s", alert.Summary)
+	default:
+		logger.Info().Msgf("alert is %s: %s", alert.State, alert.Summary)
+	}
+	return nil
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	ctx, cid := logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(webhookNotifierName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(&alert))
+
+	resp, err := n.R().
+		SetContext(ctx).
+		SetHeader(logging.CorrelationIDHeader, cid).
+		SetBody(&alert).
+		Post(n.url)
+	if err != nil {
+		logger.Err(err).Msg("webhook call failed")
+		return err
+	}
+	if !resp.IsSuccess() {
+		err := fmt.Errorf("webhook responded with %s", resp.Status())
+		logger.Err(err).Msg("webhook rejected alert")
+		return err
+	}
+	logger.Trace().Msg("alert delivered")
+	return nil
+}
+
+// LogNotifier creates notifier that writes alerts to the log.
+func LogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+// WebhookNotifier creates notifier that posts alerts as JSON to specified URL.
+func WebhookNotifier(url string) Notifier {
+	return &webhookNotifier{
+		Client: resty.New().
+			SetTimeout(DefaultWebhookTimeout).
+			SetHeader("Content-Type", "application/json"),
+		url: url,
+	}
+}
@@ -0,0 +1,229 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+const (
+	// FuncValue evaluates actual metric value.
+	FuncValue = ""
+
+	// FuncRate evaluates per-second increase of metric value within time window.
+	FuncRate = "rate"
+
+	// DefaultRateWindow is a window of rate function if window is not specified by expression.
+	DefaultRateWindow = time.Minute
+)
+
+// units are threshold multipliers. Byte units are binary, others are decimal.
+var units = map[string]float64{
+	"":   1,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+type (
+	// Rule describes condition on metric value which triggers alert if it is held for specified duration.
+	//
+	// Rule expression grammar:
+	//
+	//	expr      = selector op threshold [ "for" duration ]
+	//	selector  = id [ labels ] | "rate(" id [ labels ] [ "[" duration "]" ] ")"
+	//	op        = ">" | ">=" | "<" | "<=" | "==" | "!="
+	//	threshold = number [ "K" | "M" | "G" | "B" | "KB" | "MB" | "GB" ]
+	//
+	// e.g. `HeapAlloc > 500MB for 2m`, `rate(PollCount[30s]) == 0 for 1m`, `CPUutilization{cpu="1"} >= 90`.
+	// Labels are matched as a subset, so each metric series matching the selector is evaluated separately.
+	Rule struct {
+		Name      string
+		Expr      string
+		Summary   string
+		Func      string
+		ID        string
+		Selector  metric.Labels
+		Window    time.Duration
+		Op        string
+		Threshold float64
+		For       time.Duration
+	}
+
+	// RuleSpec is a rule definition in rules file.
+	RuleSpec struct {
+		Name    string `json:"name"`
+		Expr    string `json:"expr"`
+		Summary string `json:"summary,omitempty"`
+	}
+
+	// RulesFile is a rules file layout.
+	RulesFile struct {
+		Rules []RuleSpec `json:"rules"`
+	}
+)
+
+// Check returns true if value satisfies rule condition.
+func (r *Rule) Check(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+	return false
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s: %s", r.Name, r.Expr)
+}
+
+// ParseRule parses rule expression.
+func ParseRule(name, expr string) (*Rule, error) {
+	if len(name) == 0 {
+		return nil, errors.New("rule name is empty")
+	}
+	rule := &Rule{Name: name, Expr: strings.TrimSpace(expr)}
+
+	cond := rule.Expr
+	if i := strings.LastIndex(cond, " for "); i != -1 {
+		d, err := time.ParseDuration(strings.TrimSpace(cond[i+len(" for "):]))
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: invalid duration: %w", name, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("rule %s: duration must not be negative", name)
+		}
+		rule.For = d
+		cond = cond[:i]
+	}
+
+	i := strings.LastIndexAny(cond, "<>=!")
+	if i == -1 {
+		return nil, fmt.Errorf("rule %s: comparison operator not found", name)
+	}
+	j := i
+	for j > 0 && strings.ContainsRune("<>=!", rune(cond[j-1])) {
+		j--
+	}
+	rule.Op = cond[j : i+1]
+	switch rule.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("rule %s: unknown operator %s", name, rule.Op)
+	}
+
+	threshold, err := parseThreshold(strings.TrimSpace(cond[i+1:]))
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+	rule.Threshold = threshold
+
+	if err := rule.parseSelector(strings.TrimSpace(cond[:j])); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+	return rule, nil
+}
+
+func (r *Rule) parseSelector(s string) error {
+	r.Func = FuncValue
+	if strings.HasPrefix(s, FuncRate+"(") && strings.HasSuffix(s, ")") {
+		r.Func = FuncRate
+		r.Window = DefaultRateWindow
+		s = strings.TrimSpace(s[len(FuncRate)+1 : len(s)-1])
+		if strings.HasSuffix(s, "]") {
+			i := strings.LastIndex(s, "[")
+			if i == -1 {
+				return errors.New("malformed rate window")
+			}
+			window, err := time.ParseDuration(s[i+1 : len(s)-1])
+			if err != nil {
+				return fmt.Errorf("invalid rate window: %w", err)
+			}
+			if window <= 0 {
+				return errors.New("rate window must be positive")
+			}
+			r.Window = window
+			s = strings.TrimSpace(s[:i])
+		}
+	}
+
+	id := s
+	if i := strings.Index(s, "{"); i != -1 {
+		id = s[:i]
+		labels, err := metric.ParseLabels(s[i:])
+		if err != nil {
+			return err
+		}
+		r.Selector = labels
+	}
+	r.ID = strings.TrimSpace(id)
+	if len(r.ID) == 0 || strings.ContainsAny(r.ID, " ()[]{}") {
+		return fmt.Errorf("invalid metric selector %q", s)
+	}
+	return nil
+}
+
+func parseThreshold(s string) (float64, error) {
+	num := strings.TrimRightFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	unit, ok := units[s[len(num):]]
+	if !ok {
+		return 0, fmt.Errorf("unknown threshold unit %s", s[len(num):])
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold: %w", err)
+	}
+	if math.IsNaN(value) {
+		return 0, errors.New("threshold must be a number")
+	}
+	return value * unit, nil
+}
+
+// LoadRules reads rules from JSON rules file.
+func LoadRules(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &RulesFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to decode rules file: %w", err)
+	}
+
+	rules := make([]*Rule, 0, len(file.Rules))
+	names := make(map[string]struct{}, len(file.Rules))
+	for _, spec := range file.Rules {
+		if _, ok := names[spec.Name]; ok {
+			return nil, fmt.Errorf("rule %s: duplicate name", spec.Name)
+		}
+		names[spec.Name] = struct{}{}
+
+		rule, err := ParseRule(spec.Name, spec.Expr)
+		if err != nil {
+			return nil, err
+		}
+		rule.Summary = spec.Summary
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,155 @@
+package alerting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    *Rule
+		wantErr bool
+	}{
+		{
+			name: "Value with binary unit",
+			expr: "HeapAlloc > 500MB for 2m",
+			want: &Rule{ID: "HeapAlloc", Op: ">", Threshold: 500 << 20, For: 2 * time.Minute},
+		},
+		{
+			name: "Rate",
+			expr: "rate(PollCount) == 0 for 1m",
+			want: &Rule{Func: FuncRate, ID: "PollCount", Window: DefaultRateWindow, Op: "==", For: time.Minute},
+		},
+		{
+			name: "Rate with labels and window",
+			expr: `rate(Requests{host="foo"}[30s]) >= 1.5K`,
+			want: &Rule{Func: FuncRate, ID: "Requests", Selector: metric.Labels{"host": "foo"}, Window: 30 * time.Second, Op: ">=", Threshold: 1500},
+		},
+		{
+			name: "Labels",
+			expr: `CPUutilization{cpu="1"} != 90`,
+			want: &Rule{ID: "CPUutilization", Selector: metric.Labels{"cpu": "1"}, Op: "!=", Threshold: 90},
+		},
+		{
+			name:    "Missing operator",
+			expr:    "HeapAlloc 500",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown operator",
+			expr:    "HeapAlloc => 500",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown unit",
+			expr:    "HeapAlloc > 500TB",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid duration",
+			expr:    "HeapAlloc > 500 for ever",
+			wantErr: true,
+		},
+		{
+			name:    "Missing metric",
+			expr:    "> 500",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid window",
+			expr:    "rate(PollCount[0s]) > 0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule("test", tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tt.want.Name = "test"
+			tt.want.Expr = tt.expr
+			assert.Equal(t, tt.want, rule)
+		})
+	}
+}
+
+func TestRule_Check(t *testing.T) {
+	tests := []struct {
+		op    string
+		value float64
+		want  bool
+	}{
+		{op: ">", value: 11, want: true},
+		{op: ">", value: 10, want: false},
+		{op: ">=", value: 10, want: true},
+		{op: "<", value: 10, want: false},
+		{op: "<=", value: 10, want: true},
+		{op: "==", value: 10, want: true},
+		{op: "!=", value: 10, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			rule := &Rule{Op: tt.op, Threshold: 10}
+			assert.Equal(t, tt.want, rule.Check(tt.value))
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Basic test",
+			content: `{"rules":[{"name":"heap","expr":"HeapAlloc > 500MB"},{"name":"stale","expr":"rate(PollCount) == 0 for 1m"}]}`,
+			want:    2,
+		},
+		{
+			name:    "Duplicate name",
+			content: `{"rules":[{"name":"heap","expr":"HeapAlloc > 500MB"},{"name":"heap","expr":"HeapAlloc > 1GB"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Malformed rule",
+			content: `{"rules":[{"name":"heap","expr":"HeapAlloc"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Malformed file",
+			content: `rules`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			rules, err := LoadRules(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, rules, tt.want)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
+)
+
+const grpcServerName = "Monitor gRPC Server"
+
+// NewGRPCServer creates gRPC server object serving monitor service.
+func NewGRPCServer(service pb.MonitorServer) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryServerInterceptor),
+		grpc.ChainStreamInterceptor(streamServerInterceptor))
+	pb.RegisterMonitorServer(srv, service)
+	return srv
+}
+
+func unaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	ctx = incomingContext(ctx, info.FullMethod)
+	defer recoverer(ctx, &err)
+	return handler(ctx, req)
+}
+
+func streamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := incomingContext(stream.Context(), info.FullMethod)
+	defer recoverer(ctx, &err)
+	return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+}
+
+// incomingContext sets correlation ID received within call metadata and logs incoming call.
+func incomingContext(ctx context.Context, method string) context.Context {
+	var cid string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(logging.CorrelationIDHeader)); len(values) != 0 {
+			cid = values[0]
+		}
+	}
+	if cid == "" {
+		cid = logging.NewCID()
+	}
+	ctx, _ = logging.SetCID(ctx, cid)
+
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(grpcServerName), logging.WithCID(ctx))
+	if p, ok := peer.FromContext(ctx); ok {
+		logger = logger.With().Stringer("remote_addr", p.Addr).Logger()
+	}
+	logger.Info().Msg(method)
+	return ctx
+}
+
+func recoverer(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(grpcServerName), logging.WithCID(ctx))
+		logger.Error().Msgf("panic: %v", r)
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -14,11 +14,14 @@ type Monitor interface {
 	// Restore restores previously dumped metrics.
 	Restore(ctx context.Context) error
 
-	// Get queries single metric. Will return nil if requested metric not found.
-	Get(ctx context.Context, id string, typ metric.Type) (*metric.Metric, error)
+	// Get queries single metric identified by ID, type and labels. Will return nil if requested metric not found.
+	Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error)
 
-	// GetAll queries all registered metrics.
-	GetAll(ctx context.Context) (metric.List, error)
+	// GetAll queries all registered metrics which labels match the filter. Empty filter matches all metrics.
+	GetAll(ctx context.Context, filter metric.Labels) (metric.List, error)
+
+	// Query queries metric history within time range aggregated by step intervals.
+	Query(ctx context.Context, query *metric.Query) (metric.Series, error)
 
 	// Update registers or updates previously registered metric.
 	Update(ctx context.Context, mtr *metric.Metric) error
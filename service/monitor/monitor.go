@@ -3,6 +3,8 @@ package monitor
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
@@ -15,6 +17,12 @@ import (
 var _ Monitor = (*monitor)(nil)
 
 type monitor struct {
+	// updates counts updates stored, dumped is a number of updates covered by the latest sync dump. Atomically accessed
+	// field goes first to be 64-bit aligned.
+	updates  uint64
+	dumped   uint64
+	dumpLock sync.Mutex
+
 	interval      time.Duration
 	restore       bool
 	dumpStorage   storage.Storage
@@ -34,7 +42,7 @@ func (m *monitor) Restore(ctx context.Context) error {
 		logger.Warn().Msg("restore: dump storage is not set")
 		return nil
 	}
-	metrics, err := m.dumpStorage.GetAll(ctx)
+	samples, err := m.dumpStorage.History(ctx)
 	if err != nil {
 		logger.Err(err).Msg("restore: failed to read from dump")
 		return err
@@ -45,7 +53,7 @@ func (m *monitor) Restore(ctx context.Context) error {
 			return err
 		}
 	}
-	if err := m.metricStorage.UpdateBulk(ctx, metrics); err != nil {
+	if err := m.metricStorage.Append(ctx, samples); err != nil {
 		logger.Err(err).Msg("restore: update storage failed")
 		return err
 	}
@@ -64,7 +72,7 @@ func (m *monitor) Update(ctx context.Context, mtr *metric.Metric) error {
 		return err
 	}
 	if m.isSyncDump() {
-		if err := m.Dump(ctx); err != nil {
+		if err := m.syncDump(ctx); err != nil {
 			logger.Err(err).Msg("update: failed to sync dump")
 			return err
 		}
@@ -83,7 +91,7 @@ func (m *monitor) UpdateBulk(ctx context.Context, list metric.List) error {
 		return err
 	}
 	if m.isSyncDump() {
-		if err := m.Dump(ctx); err != nil {
+		if err := m.syncDump(ctx); err != nil {
 			logger.Err(err).Msg("update bulk: failed to sync dump")
 			return err
 		}
@@ -91,22 +99,32 @@ func (m *monitor) UpdateBulk(ctx context.Context, list metric.List) error {
 	return nil
 }
 
-func (m *monitor) Get(ctx context.Context, id string, typ metric.Type) (*metric.Metric, error) {
+func (m *monitor) Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(m), logging.WithCID(ctx))
 	logger.UpdateContext(logging.LogCtxKeyStr(logging.MetricIDKey, id))
-	logger.UpdateContext(logging.LogCtxFrom(typ))
+	logger.UpdateContext(logging.LogCtxFrom(typ, labels))
 	logger.Info().Msg("serving [Get]")
 
-	return m.metricStorage.Get(logging.SetLogger(ctx, logger), id, typ)
+	return m.metricStorage.Get(logging.SetLogger(ctx, logger), id, typ, labels)
 }
 
-func (m *monitor) GetAll(ctx context.Context) (metric.List, error) {
+func (m *monitor) GetAll(ctx context.Context, filter metric.Labels) (metric.List, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(m), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(filter))
 	logger.Info().Msg("serving [GetAll]")
 
-	return m.metricStorage.GetAll(logging.SetLogger(ctx, logger))
+	return m.metricStorage.GetAll(logging.SetLogger(ctx, logger), filter)
+}
+
+func (m *monitor) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(m), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(query))
+	logger.Info().Msg("serving [Query]")
+
+	return m.metricStorage.Query(logging.SetLogger(ctx, logger), query)
 }
 
 func (m *monitor) Ping(ctx context.Context) error {
@@ -128,13 +146,14 @@ func (m *monitor) Dump(ctx context.Context) error {
 		return nil
 	}
 
-	metrics, err := m.metricStorage.GetAll(ctx)
+	samples, err := m.metricStorage.History(ctx)
 	if err != nil {
 		logger.Err(err).Msg("dump: failed to read metrics")
 		return err
 	}
-	if err = m.dumpStorage.UpdateBulk(ctx, metrics); err != nil {
+	if err = storage.Replace(ctx, m.dumpStorage, samples); err != nil {
 		logger.Err(err).Msg("dump: dump failed")
+		return err
 	}
 	return nil
 }
@@ -143,7 +162,29 @@ func (m *monitor) BackgroundTask() task.Task {
 	if m.isSyncDump() {
 		return task.VoidTask
 	}
-	return task.Task(func(ctx context.Context) { _ = m.Dump(ctx) }).With(task.PeriodicRun(m.interval))
+	return task.ErrTask(m.Dump).
+		With(task.Retry(task.DefaultBackoff)).
+		Task().
+		With(task.PeriodicRun(m.interval))
+}
+
+// syncDump dumps metrics after update. Concurrent updates share dumps: every dump covers all updates stored before it
+// started, so update waiting for running dump returns once the next one is written. Thus dumps are not multiplied by
+// updates rate, but every dump still rewrites whole history.
+func (m *monitor) syncDump(ctx context.Context) error {
+	update := atomic.AddUint64(&m.updates, 1)
+
+	m.dumpLock.Lock()
+	defer m.dumpLock.Unlock()
+	if m.dumped >= update {
+		return nil
+	}
+	updates := atomic.LoadUint64(&m.updates)
+	if err := m.Dump(ctx); err != nil {
+		return err
+	}
+	m.dumped = updates
+	return nil
 }
 
 func (m *monitor) isSyncDump() bool {
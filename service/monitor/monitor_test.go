@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/trivial"
+)
+
+// dumpStub records replaced dumps. Other storage operations are not expected.
+type dumpStub struct {
+	storage.Storage
+	sync.Mutex
+	dumps []metric.Samples
+}
+
+func (d *dumpStub) Replace(_ context.Context, samples metric.Samples) error {
+	time.Sleep(10 * time.Millisecond)
+	d.Lock()
+	defer d.Unlock()
+	d.dumps = append(d.dumps, samples)
+	return nil
+}
+
+func TestMonitorSyncDump(t *testing.T) {
+	const updates = 20
+	ctx := context.Background()
+	cfg := &config.Config{}
+	dump := &dumpStub{}
+	mon := NewMonitor(cfg, dump, trivial.New(cfg))
+
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, mon.Update(ctx, metric.NewGaugeMetric("Gauge"+strconv.Itoa(i), metric.Gauge(i))))
+		}(i)
+	}
+	wg.Wait()
+
+	require.NotEmpty(t, dump.dumps)
+	assert.Less(t, len(dump.dumps), updates, "concurrent updates share dumps")
+	assert.Len(t, dump.dumps[len(dump.dumps)-1], updates, "the latest dump covers all updates")
+}
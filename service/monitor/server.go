@@ -1,26 +1,48 @@
 package monitor
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/encryption"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/httplib"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/selfmetrics"
+	"github.com/zhupanovdm/go-runtime-monitor/providers/monitor/grpc/pb"
 )
 
 const serverName = "Monitor HTTP Server"
 
-// Server is monitor application's HTTP server.
+// maxSealedBodySize limits encrypted request body as it is read into memory before decryption.
+const maxSealedBodySize = 8 << 20
+
+const (
+	RequestsMetric        = "MonitorHTTPRequests"
+	RequestDurationMetric = "MonitorHTTPRequestDuration"
+)
+
+// Server is monitor application's HTTP server. It also serves gRPC API if gRPC address is configured.
 type Server struct {
 	*http.Server
-	wg sync.WaitGroup
+	rpc     *grpc.Server
+	rpcAddr string
+	wg      sync.WaitGroup
 }
 
 // Start will start serving clients requests.
@@ -35,6 +57,26 @@ func (srv *Server) Start(ctx context.Context) {
 			logger.Err(err).Msg("server stopped")
 		}
 	}()
+
+	if srv.rpc == nil {
+		return
+	}
+
+	_, logger = logging.GetOrCreateLogger(ctx, logging.WithServiceName(grpcServerName))
+	listener, err := net.Listen("tcp", srv.rpcAddr)
+	if err != nil {
+		logger.Err(err).Msgf("failed to listen %v", srv.rpcAddr)
+		return
+	}
+	logger.Info().Msgf("running server on %v", srv.rpcAddr)
+
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		if err := srv.rpc.Serve(listener); err != nil {
+			logger.Err(err).Msg("server stopped")
+		}
+	}()
 }
 
 // Stop will close the server.
@@ -43,22 +85,47 @@ func (srv *Server) Stop(ctx context.Context) {
 	if err := srv.Close(); err != nil {
 		logger.Err(err).Msg("server close failed")
 	}
+	if srv.rpc != nil {
+		srv.rpc.Stop()
+	}
 	srv.wg.Wait()
 }
 
-// NewServer creates HTTP server object.
-func NewServer(cfg *config.Config, handler http.Handler) *Server {
+// NewServer creates HTTP server object. Monitor gRPC service will be served on cfg.GRPCAddress if it is set.
+// Encrypted request bodies are decrypted with the private key from cfg.CryptoKey if it is set. Requests rate and
+// duration are recorded if recorder is specified. gRPC payloads can't be encrypted, so gRPC address and private key
+// are mutually exclusive.
+func NewServer(cfg *config.Config, handler http.Handler, service pb.MonitorServer, recorder *selfmetrics.Recorder) (*Server, error) {
+	if len(cfg.GRPCAddress) != 0 && len(cfg.CryptoKey) != 0 {
+		return nil, errors.New("gRPC can't serve encrypted payloads, private key is set along with gRPC address")
+	}
+
+	var decryptor *encryption.Decryptor
+	if len(cfg.CryptoKey) != 0 {
+		var err error
+		if decryptor, err = encryption.LoadDecryptor(cfg.CryptoKey); err != nil {
+			return nil, fmt.Errorf("failed to load private key: %w", err)
+		}
+	}
+
 	srv := &http.Server{
 		Addr: cfg.Address,
 		Handler: entryHandler(handler,
 			middleware.RealIP,
 			cid,
 			serverLogger,
+			instrument(recorder),
 			compress,
+			decrypt(decryptor),
 			decompress,
 			middleware.Recoverer),
 	}
-	return &Server{Server: srv}
+	server := &Server{Server: srv}
+	if len(cfg.GRPCAddress) != 0 && service != nil {
+		server.rpc = NewGRPCServer(service)
+		server.rpcAddr = cfg.GRPCAddress
+	}
+	return server, nil
 }
 
 func entryHandler(h http.Handler, middlewares ...func(http.Handler) http.Handler) *chi.Mux {
@@ -91,6 +158,42 @@ func decompress(next http.Handler) http.Handler {
 	})
 }
 
+// decrypt opens request body sealed with server's public key. Requests with sealed body are rejected if decryptor
+// is not specified.
+func decrypt(decryptor *encryption.Decryptor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(encryption.Header) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if decryptor == nil {
+				handleBadRequest(w, r, nil, "decryptor: encrypted payload is not supported")
+				return
+			}
+			msg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSealedBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					handleError(w, r, err, http.StatusRequestEntityTooLarge, "decryptor: body is too large")
+					return
+				}
+				handleBadRequest(w, r, err, "decryptor: failed to read body")
+				return
+			}
+			plain, err := decryptor.Decrypt(msg)
+			if err != nil {
+				handleBadRequest(w, r, err, "decryptor: failed to decrypt body")
+				return
+			}
+			r.Header.Del(encryption.Header)
+			r.Body = io.NopCloser(bytes.NewReader(plain))
+			r.ContentLength = int64(len(plain))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func compress(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
@@ -125,6 +228,32 @@ func cid(next http.Handler) http.Handler {
 	})
 }
 
+// instrument records requests count and duration per route, method and response status code.
+func instrument(recorder *selfmetrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if recorder == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.RoutePattern()) != 0 {
+				route = rctx.RoutePattern()
+			}
+			labels := metric.Labels{"route": route, "method": r.Method, "code": strconv.Itoa(status)}
+			recorder.ObserveLatency(RequestDurationMetric, labels, time.Since(start).Seconds())
+			recorder.Count(RequestsMetric, labels, 1)
+		})
+	}
+}
+
 func serverLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -140,6 +269,17 @@ func serverLogger(next http.Handler) http.Handler {
 	})
 }
 
+func handleBadRequest(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	handleError(w, r, err, http.StatusBadRequest, msg)
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error, code int, msg string) {
+	ctx := r.Context()
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(serverName), logging.WithCID(ctx))
+	logger.Err(err).Msg(msg)
+	httplib.Error(w, code, msg)
+}
+
 func handleInternalError(w http.ResponseWriter, r *http.Request, err error, msg string) {
 	ctx := r.Context()
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(serverName), logging.WithCID(ctx))
@@ -2,12 +2,23 @@ package monitor
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/encryption"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/selfmetrics"
 )
 
 func TestServerCompressDecompress(t *testing.T) {
@@ -55,3 +66,151 @@ func TestServerCompressDecompress(t *testing.T) {
 		})
 	}
 }
+
+func TestServerDecrypt(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	encryptor, err := encryption.NewEncryptor(&key.PublicKey)
+	require.NoError(t, err)
+	decryptor, err := encryption.NewDecryptor(key)
+	require.NoError(t, err)
+
+	echo := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = io.Copy(writer, request.Body)
+	})
+
+	seal := func(plain []byte) []byte {
+		msg, err := encryptor.Encrypt(plain)
+		require.NoError(t, err)
+		return msg
+	}
+	gzipped := func(plain []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(plain)
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		decryptor  *encryption.Decryptor
+		header     http.Header
+		body       []byte
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Basic test",
+			decryptor:  decryptor,
+			header:     http.Header{encryption.Header: {encryption.Scheme}},
+			body:       seal([]byte("test")),
+			wantStatus: http.StatusOK,
+			want:       "test",
+		},
+		{
+			name:       "Compressed payload",
+			decryptor:  decryptor,
+			header:     http.Header{encryption.Header: {encryption.Scheme}, "Content-Encoding": {"gzip"}},
+			body:       seal(gzipped([]byte("test"))),
+			wantStatus: http.StatusOK,
+			want:       "test",
+		},
+		{
+			name:       "Plain payload",
+			decryptor:  decryptor,
+			body:       []byte("test"),
+			wantStatus: http.StatusOK,
+			want:       "test",
+		},
+		{
+			name:       "Tampered payload",
+			decryptor:  decryptor,
+			header:     http.Header{encryption.Header: {encryption.Scheme}},
+			body:       append(seal([]byte("test")), 0),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Payload is too large",
+			decryptor:  decryptor,
+			header:     http.Header{encryption.Header: {encryption.Scheme}},
+			body:       make([]byte, maxSealedBodySize+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "Decryption is not configured",
+			header:     http.Header{encryption.Header: {encryption.Scheme}},
+			body:       seal([]byte("test")),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(entryHandler(echo, decrypt(tt.decryptor), decompress))
+			defer server.Close()
+
+			req, err := http.NewRequest("POST", server.URL, bytes.NewBuffer(tt.body))
+			require.NoError(t, err)
+			req.Header = tt.header
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.want != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, string(body))
+			}
+		})
+	}
+}
+
+func TestServerInstrument(t *testing.T) {
+	recorder := selfmetrics.NewRecorder()
+	root := chi.NewRouter()
+	root.Get("/value/{id}", func(writer http.ResponseWriter, request *http.Request) {})
+	root.Post("/update/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+	})
+	server := httptest.NewServer(entryHandler(root, instrument(recorder)))
+	defer server.Close()
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/value/foo"},
+		{method: http.MethodGet, path: "/value/bar"},
+		{method: http.MethodPost, path: "/update/foo"},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest(r.method, server.URL+r.path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	list := recorder.Flush()
+	assert.Contains(t, list, metric.NewCounterMetric(RequestsMetric, 2).
+		WithLabels(metric.Labels{"route": "/value/{id}", "method": "GET", "code": "200"}))
+	assert.Contains(t, list, metric.NewCounterMetric(RequestsMetric, 1).
+		WithLabels(metric.Labels{"route": "/update/{id}", "method": "POST", "code": "400"}))
+
+	durations := 0
+	for _, mtr := range list {
+		if mtr.ID == RequestDurationMetric {
+			durations += int(mtr.Value.(*metric.Histogram).Count)
+		}
+	}
+	assert.Equal(t, len(requests), durations)
+}
+
+func TestNewServer(t *testing.T) {
+	_, err := NewServer(&config.Config{GRPCAddress: "localhost:3200", CryptoKey: "private.pem"}, http.NotFoundHandler(), nil, nil)
+	assert.Error(t, err, "gRPC payloads can't be encrypted")
+}
@@ -0,0 +1,180 @@
+// Package registry is service responsible for tracking of agents reporting to monitor server.
+package registry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+)
+
+// checkInterval is a period agents are checked for staleness with.
+const checkInterval = config.DefaultReportInterval
+
+var _ Registry = (*registry)(nil)
+
+type (
+	// Agent is a snapshot of agent state known to monitor server.
+	Agent struct {
+		ID             string    `json:"id"`
+		Hostname       string    `json:"hostname,omitempty"`
+		Version        string    `json:"version,omitempty"`
+		StartedAt      time.Time `json:"started_at,omitempty"`
+		ReportInterval string    `json:"report_interval"`
+		FirstSeen      time.Time `json:"first_seen"`
+		LastSeen       time.Time `json:"last_seen"`
+		Reports        uint64    `json:"reports"`
+		Metrics        uint64    `json:"metrics"`
+		Restarts       uint64    `json:"restarts"`
+		Stale          bool      `json:"stale"`
+	}
+
+	// Registry application service tracks reporting agents. Agent is stale if it didn't report for several of its
+	// report intervals.
+	Registry interface {
+		pkg.BackgroundService
+
+		// Report registers agent's report of specified number of metrics.
+		Report(ctx context.Context, info *agent.Info, metrics int)
+
+		// Agents returns all known agents ordered by ID.
+		Agents(ctx context.Context) []Agent
+	}
+
+	registry struct {
+		sync.RWMutex
+		agents       map[string]*record
+		staleReports int
+		now          func() time.Time
+	}
+
+	record struct {
+		info      agent.Info
+		firstSeen time.Time
+		lastSeen  time.Time
+		reports   uint64
+		metrics   uint64
+		restarts  uint64
+		stale     bool
+	}
+)
+
+func (r *registry) Report(ctx context.Context, info *agent.Info, metrics int) {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(r), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(info))
+
+	now := r.now()
+
+	r.Lock()
+	defer r.Unlock()
+
+	rec, ok := r.agents[info.ID]
+	if !ok {
+		rec = &record{firstSeen: now}
+		r.agents[info.ID] = rec
+		logger.Info().Msgf("new agent registered: %v", info)
+	} else {
+		if rec.stale {
+			logger.Info().Msgf("stale agent resumed reporting: %v", info)
+		}
+		if !rec.info.StartedAt.Equal(info.StartedAt) {
+			rec.restarts++
+			logger.Info().Msgf("agent restarted: %v", info)
+		}
+	}
+	rec.info = *info
+	rec.lastSeen = now
+	rec.reports++
+	rec.metrics += uint64(metrics)
+	rec.stale = false
+}
+
+func (r *registry) Agents(ctx context.Context) []Agent {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(r), logging.WithCID(ctx))
+
+	now := r.now()
+
+	r.RLock()
+	agents := make([]Agent, 0, len(r.agents))
+	for _, rec := range r.agents {
+		agents = append(agents, Agent{
+			ID:             rec.info.ID,
+			Hostname:       rec.info.Hostname,
+			Version:        rec.info.Version,
+			StartedAt:      rec.info.StartedAt,
+			ReportInterval: r.interval(rec).String(),
+			FirstSeen:      rec.firstSeen,
+			LastSeen:       rec.lastSeen,
+			Reports:        rec.reports,
+			Metrics:        rec.metrics,
+			Restarts:       rec.restarts,
+			Stale:          r.isStale(rec, now),
+		})
+	}
+	r.RUnlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	logger.Trace().Msgf("%d agents queried", len(agents))
+	return agents
+}
+
+// Check logs agents which have become stale since previous check.
+func (r *registry) Check(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithService(r), logging.WithCID(ctx))
+
+	now := r.now()
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, rec := range r.agents {
+		if rec.stale || !r.isStale(rec, now) {
+			continue
+		}
+		rec.stale = true
+		logger.Warn().
+			Str(logging.AgentIDKey, rec.info.ID).
+			Msgf("agent %v stopped reporting: last seen %v ago", &rec.info, now.Sub(rec.lastSeen).Truncate(time.Second))
+	}
+}
+
+func (r *registry) BackgroundTask() task.Task {
+	return task.Task(r.Check).With(task.PeriodicRun(checkInterval))
+}
+
+func (r *registry) Name() string {
+	return "Agents registry"
+}
+
+func (r *registry) isStale(rec *record, now time.Time) bool {
+	return now.Sub(rec.lastSeen) > time.Duration(r.staleReports)*r.interval(rec)
+}
+
+// interval returns agent's report interval. Default report interval is assumed if agent didn't specify it.
+func (r *registry) interval(rec *record) time.Duration {
+	if rec.info.ReportInterval > 0 {
+		return rec.info.ReportInterval
+	}
+	return config.DefaultReportInterval
+}
+
+// NewRegistry creates Registry application service. Agent is considered stale if it hasn't reported for cfg.AgentStaleReports
+// of its report intervals.
+func NewRegistry(cfg *config.Config) Registry {
+	staleReports := cfg.AgentStaleReports
+	if staleReports <= 0 {
+		staleReports = config.DefaultAgentStaleReports
+	}
+	return &registry{
+		agents:       make(map[string]*record),
+		staleReports: staleReports,
+		now:          time.Now,
+	}
+}
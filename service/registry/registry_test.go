@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/agent"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	reg := NewRegistry(&config.Config{AgentStaleReports: 3}).(*registry)
+	reg.now = func() time.Time { return now }
+
+	foo := &agent.Info{ID: "foo", Hostname: "foo.local", StartedAt: now, ReportInterval: 10 * time.Second}
+	bar := &agent.Info{ID: "bar", Hostname: "bar.local", StartedAt: now}
+
+	reg.Report(ctx, foo, 10)
+	reg.Report(ctx, bar, 5)
+	now = now.Add(10 * time.Second)
+	reg.Report(ctx, foo, 10)
+
+	agents := reg.Agents(ctx)
+	require.Len(t, agents, 2)
+	assert.Equal(t, "bar", agents[0].ID)
+	assert.Equal(t, config.DefaultReportInterval.String(), agents[0].ReportInterval)
+	assert.Equal(t, Agent{
+		ID:             "foo",
+		Hostname:       "foo.local",
+		StartedAt:      foo.StartedAt,
+		ReportInterval: "10s",
+		FirstSeen:      foo.StartedAt,
+		LastSeen:       now,
+		Reports:        2,
+		Metrics:        20,
+	}, agents[1])
+
+	t.Run("Stale agent", func(t *testing.T) {
+		now = now.Add(25 * time.Second)
+		reg.Check(ctx)
+		agents := reg.Agents(ctx)
+		assert.True(t, agents[0].Stale, "bar missed 3 default report intervals")
+		assert.False(t, agents[1].Stale)
+
+		now = now.Add(10 * time.Second)
+		agents = reg.Agents(ctx)
+		assert.True(t, agents[1].Stale, "foo missed 3 report intervals")
+	})
+
+	t.Run("Stale agent resumed", func(t *testing.T) {
+		reg.Check(ctx)
+		reg.Report(ctx, bar, 5)
+
+		agents := reg.Agents(ctx)
+		assert.False(t, agents[0].Stale)
+		assert.Equal(t, uint64(2), agents[0].Reports)
+		assert.Equal(t, uint64(0), agents[0].Restarts)
+	})
+
+	t.Run("Agent restart", func(t *testing.T) {
+		restarted := *foo
+		restarted.StartedAt = now
+		reg.Report(ctx, &restarted, 10)
+
+		agents := reg.Agents(ctx)
+		assert.False(t, agents[1].Stale)
+		assert.Equal(t, uint64(3), agents[1].Reports)
+		assert.Equal(t, uint64(1), agents[1].Restarts)
+		assert.Equal(t, restarted.StartedAt, agents[1].StartedAt)
+	})
+}
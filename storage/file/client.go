@@ -15,11 +15,17 @@ import (
 
 const fileStorageName = "File storage"
 
-var _ storage.Storage = (*client)(nil)
+var (
+	_ storage.Storage  = (*client)(nil)
+	_ storage.Replacer = (*client)(nil)
+)
 
+// client keeps metrics dump in file. Previous dumps are rotated, so that reads fall back to the latest valid dump if
+// actual one is corrupted or missing.
 type client struct {
 	sync.RWMutex
 	filename string
+	rotate   int
 }
 
 func (c *client) IsPersistent() bool {
@@ -32,53 +38,126 @@ func (c *client) Init(ctx context.Context) error {
 	return nil
 }
 
+// Clear replaces actual dump with empty one. Replaced dump is rotated.
 func (c *client) Clear(ctx context.Context) error {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
 
-	if err := os.Remove(c.filename); err != nil && err != fs.ErrNotExist {
-		logger.Err(err).Msg("unable to remove destination file")
+	c.Lock()
+	defer c.Unlock()
+	w := NewJSONFileWriter(logging.SetLogger(ctx, logger), c.filename, c.rotate)
+	defer w.Close()
+	if err := w.Commit(); err != nil {
+		logger.Err(err).Msg("unable to clear dump")
 		return err
 	}
 	logger.Info().Msg("cleared")
 	return nil
 }
 
-func (c *client) GetAll(ctx context.Context) (metric.List, error) {
+func (c *client) GetAll(ctx context.Context, filter metric.Labels) (metric.List, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(filter))
 
 	c.RLock()
 	defer c.RUnlock()
-	r, err := NewJSONFileReader(logging.SetLogger(ctx, logger), c.filename)
+	samples, err := c.read(logging.SetLogger(ctx, logger))
 	if err != nil {
-		logger.Err(err).Msg("failed to create reader")
 		return nil, err
 	}
-	defer r.Close()
+	return samples.Latest().Filter(filter), nil
+}
+
+func (c *client) History(ctx context.Context) (metric.Samples, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
 
-	list, err := r.Read()
+	c.RLock()
+	defer c.RUnlock()
+	samples, err := c.read(logging.SetLogger(ctx, logger))
 	if err != nil {
-		logger.Err(err).Msg("failed to read entire file")
 		return nil, err
 	}
-	return list, err
+	return samples, nil
 }
 
-func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
+func (c *client) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(query))
+
+	samples, err := c.History(logging.SetLogger(ctx, logger))
+	if err != nil {
+		return nil, err
+	}
+	series := query.Apply(samples)
+
+	logger.Trace().Msgf("%d points read", len(series))
+	return series, nil
+}
+
+func (c *client) Append(ctx context.Context, samples metric.Samples) error {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
 
 	c.Lock()
 	defer c.Unlock()
-	w, err := NewJSONFileWriter(logging.SetLogger(ctx, logger), c.filename)
+	actual, err := readDump(logging.SetLogger(ctx, logger), c.filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Err(err).Msg("failed to read actual dump")
+		return err
+	}
+
+	w := NewJSONFileWriter(logging.SetLogger(ctx, logger), c.filename, 0)
+	defer w.Close()
+	if err = w.WriteSamples(append(actual, samples...)); err == nil {
+		err = w.Commit()
+	}
 	if err != nil {
-		logger.Err(err).Msg("failed to create writer")
+		logger.Err(err).Msg("samples append failed")
 		return err
 	}
+	logger.Trace().Msgf("%d samples appended", len(samples))
+	return nil
+}
+
+// Replace writes samples as new dump which replaces actual one. Replaced dump is rotated.
+func (c *client) Replace(ctx context.Context, samples metric.Samples) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
+
+	c.Lock()
+	defer c.Unlock()
+	w := NewJSONFileWriter(logging.SetLogger(ctx, logger), c.filename, c.rotate)
 	defer w.Close()
 
-	if err := w.Write(list); err != nil {
+	err := w.WriteSamples(samples)
+	if err == nil {
+		err = w.Commit()
+	}
+	if err != nil {
+		logger.Err(err).Msg("dump replace failed")
+		return err
+	}
+	logger.Trace().Msgf("%d samples dumped", len(samples))
+	return nil
+}
+
+func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
+
+	c.Lock()
+	defer c.Unlock()
+	w := NewJSONFileWriter(logging.SetLogger(ctx, logger), c.filename, c.rotate)
+	defer w.Close()
+
+	err := w.Write(list)
+	if err == nil {
+		err = w.Commit()
+	}
+	if err != nil {
 		logger.Err(err).Msg("metrics update failed")
 		return err
 	}
@@ -86,7 +165,7 @@ func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
 	return nil
 }
 
-func (c *client) Get(ctx context.Context, _ string, _ metric.Type) (*metric.Metric, error) {
+func (c *client) Get(ctx context.Context, _ string, _ metric.Type, _ metric.Labels) (*metric.Metric, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(fileStorageName), logging.WithCID(ctx))
 
@@ -128,9 +207,53 @@ func (c *client) Close(ctx context.Context) {
 	logger.Info().Msg("closed")
 }
 
+// read returns samples of the latest valid dump. Thread unsafe, should be locked before read.
+func (c *client) read(ctx context.Context) (metric.Samples, error) {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	var lastErr error
+	for n := 0; n <= c.rotate; n++ {
+		name := dumpName(c.filename, n)
+		samples, err := readDump(ctx, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			logger.Warn().Err(err).Msgf("skipping invalid dump %s", name)
+			lastErr = err
+			continue
+		}
+		if n != 0 {
+			logger.Warn().Msgf("fell back to previous dump %s", name)
+		}
+		return samples, nil
+	}
+	if lastErr != nil {
+		logger.Err(lastErr).Msg("no valid dump found")
+		return nil, lastErr
+	}
+	return metric.Samples{}, nil
+}
+
+func readDump(ctx context.Context, name string) (metric.Samples, error) {
+	r, err := NewJSONFileReader(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.ReadSamples()
+}
+
+// New returns storage which dumps metrics to file. Returns nil if metrics are kept in write-ahead log or key-value store
+// instead, as they persist every update by themselves.
 func New(cfg *config.Config) storage.Storage {
-	if len(cfg.StoreFile) == 0 {
+	if len(cfg.StoreFile) == 0 || walEnabled(cfg) || kvEnabled(cfg) {
 		return nil
 	}
-	return &client{filename: cfg.StoreFile}
+	return &client{filename: cfg.StoreFile, rotate: cfg.StoreRotate}
+}
+
+// kvEnabled reports whether metrics are kept in embedded key-value store.
+func kvEnabled(cfg *config.Config) bool {
+	return len(cfg.StoreKV) != 0 && len(cfg.Database) == 0
 }
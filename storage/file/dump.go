@@ -0,0 +1,158 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// dumpVersion is a version of dump format written.
+const dumpVersion = 1
+
+const checksumPrefix = "sha256:"
+
+var ErrDumpCorrupted = errors.New("dump is corrupted")
+
+type (
+	// dumpHeader is the first line of dump file. It describes dump content which follows it.
+	dumpHeader struct {
+		Version   int       `json:"version"`
+		Timestamp time.Time `json:"timestamp"`
+		Count     int       `json:"count"`
+		Checksum  string    `json:"checksum"`
+	}
+
+	// dumpWriter buffers dump content until it is committed.
+	dumpWriter struct {
+		*jsonWriter
+		buf      *bytes.Buffer
+		fileName string
+		rotate   int
+	}
+
+	nopWriteCloser struct {
+		io.Writer
+	}
+)
+
+// Verify checks that dump content matches the header.
+func (h *dumpHeader) Verify(lines int, sum []byte) error {
+	if lines != h.Count {
+		return fmt.Errorf("%w: %d records found, %d expected", ErrDumpCorrupted, lines, h.Count)
+	}
+	if checksum := checksumPrefix + hex.EncodeToString(sum); checksum != h.Checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrDumpCorrupted)
+	}
+	return nil
+}
+
+// Commit writes dump to temporary file which then atomically replaces dump file. Replaced dump is rotated.
+func (w *dumpWriter) Commit() error {
+	_, logger := logging.GetOrCreateLogger(w.ctx)
+
+	sum := sha256.Sum256(w.buf.Bytes())
+	header, err := json.Marshal(&dumpHeader{
+		Version:   dumpVersion,
+		Timestamp: time.Now().UTC(),
+		Count:     bytes.Count(w.buf.Bytes(), []byte{'\n'}),
+		Checksum:  checksumPrefix + hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		logger.Err(err).Msg("json writer: failed to encode dump header")
+		return err
+	}
+
+	tmpName := w.fileName + ".tmp"
+	if err = writeFileSync(tmpName, append(header, '\n'), w.buf.Bytes()); err != nil {
+		logger.Err(err).Msg("json writer: failed to write temporary dump")
+		if err := os.Remove(tmpName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Err(err).Msg("json writer: failed to remove temporary dump")
+		}
+		return err
+	}
+	if err = rotateDumps(w.fileName, w.rotate); err != nil {
+		logger.Err(err).Msg("json writer: failed to rotate dumps")
+		return err
+	}
+	if err = os.Rename(tmpName, w.fileName); err != nil {
+		logger.Err(err).Msg("json writer: failed to replace dump")
+		return err
+	}
+	syncDir(w.ctx, filepath.Dir(w.fileName))
+
+	logger.Trace().Msgf("json writer: dump committed: %s", w.fileName)
+	return nil
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// parseDumpHeader returns nil if line is not a dump header.
+func parseDumpHeader(line []byte) (*dumpHeader, error) {
+	probe := &struct {
+		Version *int `json:"version"`
+	}{}
+	if json.Unmarshal(line, probe) != nil || probe.Version == nil {
+		return nil, nil
+	}
+	if *probe.Version <= 0 || *probe.Version > dumpVersion {
+		return nil, fmt.Errorf("unsupported dump version: %d", *probe.Version)
+	}
+	header := &dumpHeader{}
+	if err := json.Unmarshal(line, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// dumpName returns file name of n-th rotated dump. Zero is the actual dump.
+func dumpName(fileName string, n int) string {
+	if n == 0 {
+		return fileName
+	}
+	return fileName + "." + strconv.Itoa(n)
+}
+
+// rotateDumps shifts previous dumps keeping specified number of them. Actual dump becomes the first rotated one.
+func rotateDumps(fileName string, rotate int) error {
+	if rotate <= 0 {
+		return nil
+	}
+	for n := rotate - 1; n >= 0; n-- {
+		if err := os.Rename(dumpName(fileName, n), dumpName(fileName, n+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileSync(name string, chunks ...[]byte) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err = file.Write(chunk); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
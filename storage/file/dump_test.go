@@ -0,0 +1,205 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
+)
+
+func commitDump(t *testing.T, fileName string, rotate int, list metric.List) {
+	w := NewJSONFileWriter(context.TODO(), fileName, rotate)
+	defer w.Close()
+	require.NoError(t, w.Write(list))
+	require.NoError(t, w.Commit())
+}
+
+func Test_jsonReader_Read_header(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "metrics.json")
+	list := metric.List{
+		metric.NewGaugeMetric("foo", metric.Gauge(33.3)),
+		metric.NewCounterMetric("bar", metric.Counter(333)),
+	}
+	commitDump(t, fileName, 0, list)
+	dump, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(dump), "\n")
+	require.Len(t, lines, 4, "header, two records and trailing empty line")
+
+	tests := []struct {
+		name    string
+		dump    string
+		wantErr bool
+		errIs   error
+	}{
+		{
+			name: "Valid dump",
+			dump: string(dump),
+		},
+		{
+			name:    "Checksum mismatch",
+			dump:    lines[0] + strings.Replace(lines[1], "33.3", "33.4", 1) + lines[2],
+			wantErr: true,
+			errIs:   ErrDumpCorrupted,
+		},
+		{
+			name:    "Truncated dump",
+			dump:    lines[0] + lines[1],
+			wantErr: true,
+			errIs:   ErrDumpCorrupted,
+		},
+		{
+			name:    "Unsupported version",
+			dump:    `{"version":100}` + "\n" + lines[1] + lines[2],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := io.NopCloser(bytes.NewBufferString(tt.dump))
+			got, err := NewJSONReader(context.TODO(), src).Read()
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, list, got)
+			}
+		})
+	}
+}
+
+func Test_dumpWriter_Commit(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "metrics.json")
+
+	for i := 1; i <= 4; i++ {
+		commitDump(t, fileName, 2, metric.List{metric.NewCounterMetric("PollCount", metric.Counter(i))})
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"metrics.json", "metrics.json.1", "metrics.json.2"}, names, "no temporary files left")
+
+	for n, want := range []metric.Counter{4, 3, 2} {
+		r, err := NewJSONFileReader(context.TODO(), dumpName(fileName, n))
+		require.NoError(t, err)
+		list, err := r.Read()
+		r.Close()
+		require.NoError(t, err)
+		assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", want)}, list)
+	}
+}
+
+func TestRestoreFallback(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{StoreFile: filepath.Join(t.TempDir(), "metrics.json"), StoreRotate: 2}
+	c := New(cfg)
+
+	require.NoError(t, c.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 1)}))
+	require.NoError(t, c.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 2)}))
+
+	list, err := c.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", 2)}, list)
+
+	t.Run("Newest dump is corrupted", func(t *testing.T) {
+		dump, err := os.ReadFile(cfg.StoreFile)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(cfg.StoreFile, dump[:len(dump)-10], 0666))
+
+		list, err := c.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", 1)}, list)
+
+		history, err := c.History(ctx)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	})
+
+	t.Run("Cleared dump", func(t *testing.T) {
+		require.NoError(t, c.Clear(ctx))
+		list, err := c.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, list, "empty dump is written")
+
+		require.NoError(t, c.Append(ctx, metric.Samples{metric.NewSample(metric.NewCounterMetric("PollCount", 3), time.Now())}))
+		list, err = c.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", 3)}, list)
+	})
+
+	t.Run("No valid dump", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(cfg.StoreFile, []byte("{}}\n"), 0666))
+		for n := 1; n <= cfg.StoreRotate; n++ {
+			require.NoError(t, os.WriteFile(dumpName(cfg.StoreFile, n), []byte("{}}\n"), 0666))
+		}
+		_, err := c.GetAll(ctx, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{StoreFile: filepath.Join(t.TempDir(), "metrics.json"), StoreRotate: 2}
+	c := New(cfg).(*client)
+
+	now := time.Now()
+	first := metric.Samples{metric.NewSample(metric.NewCounterMetric("PollCount", 1), now)}
+	second := metric.Samples{metric.NewSample(metric.NewCounterMetric("PollCount", 2), now)}
+	require.NoError(t, c.Replace(ctx, first))
+	require.NoError(t, c.Replace(ctx, second))
+
+	tests := []struct {
+		name string
+		file string
+		want metric.Samples
+	}{
+		{
+			name: "Actual dump",
+			file: cfg.StoreFile,
+			want: second,
+		},
+		{
+			name: "Replaced dump is rotated as is",
+			file: dumpName(cfg.StoreFile, 1),
+			want: first,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := readDump(ctx, tt.file)
+			require.NoError(t, err)
+			if assert.Len(t, samples, len(tt.want)) {
+				assert.Equal(t, tt.want[0].Metric, samples[0].Metric)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return New(&config.Config{StoreFile: filepath.Join(t.TempDir(), "metrics.json"), StoreRotate: 2})
+		},
+		Dump: true,
+	}.Run(t)
+}
@@ -2,10 +2,11 @@ package file
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"io"
-	"io/fs"
 	"os"
 
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
@@ -31,6 +32,19 @@ func (w *jsonWriter) Write(list metric.List) error {
 	return nil
 }
 
+func (w *jsonWriter) WriteSamples(samples metric.Samples) error {
+	_, logger := logging.GetOrCreateLogger(w.ctx)
+
+	for _, smp := range samples {
+		if err := w.encoder.Encode(smp); err != nil {
+			logger.Err(err).Msgf("json writer: failed to encode sample %v", smp)
+			return err
+		}
+	}
+	logger.Trace().Msgf("json writer: wrote %d samples", len(samples))
+	return nil
+}
+
 func (w *jsonWriter) Close() {
 	if w == nil {
 		return
@@ -52,21 +66,16 @@ func NewJSONWriter(ctx context.Context, dest io.WriteCloser) *jsonWriter {
 	}
 }
 
-func NewJSONFileWriter(ctx context.Context, fileName string) (*jsonWriter, error) {
-	_, logger := logging.GetOrCreateLogger(ctx)
-
-	if err := os.Remove(fileName); err != nil && err != fs.ErrNotExist {
-		logger.Err(err).Msg("json writer: failed to clear destination")
-		return nil, err
+// NewJSONFileWriter returns writer of dump file. Dump is written to file atomically on Commit and keeps specified number
+// of previous dumps rotated, e.g. dump.json.1 is the latest previous dump.
+func NewJSONFileWriter(ctx context.Context, fileName string, rotate int) *dumpWriter {
+	buf := &bytes.Buffer{}
+	return &dumpWriter{
+		jsonWriter: NewJSONWriter(ctx, nopWriteCloser{buf}),
+		buf:        buf,
+		fileName:   fileName,
+		rotate:     rotate,
 	}
-	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE, 0777)
-	if err != nil {
-		logger.Err(err).Msg("json writer: failed to open destination")
-		return nil, err
-	}
-
-	logger.Trace().Msgf("json writer: opened for write: %s", file.Name())
-	return NewJSONWriter(ctx, file), nil
 }
 
 type jsonReader struct {
@@ -79,22 +88,74 @@ func (r *jsonReader) Read() (metric.List, error) {
 	_, logger := logging.GetOrCreateLogger(r.ctx)
 
 	list := make(metric.List, 0)
-	for r.scanner.Scan() {
+	if err := r.scan(func(data []byte) error {
 		mtr := &metric.Metric{}
 		list = append(list, mtr)
+		return json.Unmarshal(data, mtr)
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Trace().Msgf("json reader: %d records read", len(list))
+	return list, nil
+}
+
+func (r *jsonReader) ReadSamples() (metric.Samples, error) {
+	_, logger := logging.GetOrCreateLogger(r.ctx)
+
+	samples := make(metric.Samples, 0)
+	if err := r.scan(func(data []byte) error {
+		smp := &metric.Sample{}
+		samples = append(samples, smp)
+		return json.Unmarshal(data, smp)
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Trace().Msgf("json reader: %d samples read", len(samples))
+	return samples, nil
+}
+
+// scan decodes source line by line. Dump header is validated against the content if source starts with it, headerless
+// sources are read as legacy dumps.
+func (r *jsonReader) scan(decode func(data []byte) error) error {
+	_, logger := logging.GetOrCreateLogger(r.ctx)
+
+	var header *dumpHeader
+	hash := sha256.New()
+	lines := 0
+	for r.scanner.Scan() {
 		data := r.scanner.Bytes()
-		if err := json.Unmarshal(data, mtr); err != nil {
+		if lines == 0 && header == nil {
+			h, err := parseDumpHeader(data)
+			if err != nil {
+				logger.Err(err).Msg("json reader: invalid dump header")
+				return err
+			}
+			if header = h; header != nil {
+				continue
+			}
+		}
+		hash.Write(data)
+		hash.Write([]byte{'\n'})
+		lines++
+		if err := decode(data); err != nil {
 			logger.Err(err).Msgf("json reader: failed to decode: %s", string(data))
-			return nil, err
+			return err
 		}
 	}
 	if err := r.scanner.Err(); err != nil {
 		logger.Err(err).Msg("json reader: failed to read source")
-		return nil, err
+		return err
 	}
-
-	logger.Trace().Msgf("json reader: %d records read", len(list))
-	return list, nil
+	if header != nil {
+		if err := header.Verify(lines, hash.Sum(nil)); err != nil {
+			logger.Err(err).Msg("json reader: dump is corrupted")
+			return err
+		}
+		logger.Trace().Msgf("json reader: dump of %v verified", header.Timestamp)
+	}
+	return nil
 }
 
 func (r *jsonReader) Close() {
@@ -119,7 +180,7 @@ func NewJSONReader(ctx context.Context, src io.ReadCloser) *jsonReader {
 
 func NewJSONFileReader(ctx context.Context, fileName string) (*jsonReader, error) {
 	_, logger := logging.GetOrCreateLogger(ctx)
-	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0777)
+	file, err := os.Open(fileName)
 	if err != nil {
 		logger.Err(err).Msg("json reader: failed to open source")
 		return nil, err
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -111,3 +112,59 @@ func Test_jsonWriter_Write(t *testing.T) {
 		})
 	}
 }
+
+func Test_jsonReader_ReadSamples(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		sample  []string
+		want    metric.Samples
+		wantErr bool
+	}{
+		{
+			name: "Basic test",
+			sample: []string{
+				`{"ID":"foo","Type":"gauge","Value":33.3,"Timestamp":"2022-03-01T12:00:00Z"}`,
+				`{"ID":"bar","Type":"counter","Value":333,"Timestamp":"2022-03-01T12:00:00Z"}`,
+			},
+			want: metric.Samples{
+				metric.NewSample(metric.NewGaugeMetric("foo", metric.Gauge(33.3)), ts),
+				metric.NewSample(metric.NewCounterMetric("bar", metric.Counter(333)), ts),
+			},
+		},
+		{
+			name: "Labeled samples",
+			sample: []string{
+				`{"ID":"foo","Type":"gauge","Value":33.3,"Labels":{"cpu":"1"},"Timestamp":"2022-03-01T12:00:00Z"}`,
+			},
+			want: metric.Samples{
+				metric.NewSample(metric.NewGaugeMetric("foo", metric.Gauge(33.3)).WithLabels(metric.Labels{"cpu": "1"}), ts),
+			},
+		},
+		{
+			name:   "Legacy dump",
+			sample: []string{`{"ID":"foo","Type":"gauge","Value":33.3}`},
+			want: metric.Samples{
+				metric.NewSample(metric.NewGaugeMetric("foo", metric.Gauge(33.3)), time.Time{}),
+			},
+		},
+		{
+			name:    "Failed decode",
+			sample:  []string{`{"ID":"baz","Type":"counter","Value":3.33,"Timestamp":"2022-03-01T12:00:00Z"}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := io.NopCloser(bytes.NewBuffer([]byte(strings.Join(tt.sample, "\n"))))
+			samples, err := NewJSONReader(context.TODO(), src).ReadSamples()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, samples)
+			}
+		})
+	}
+}
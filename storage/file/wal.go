@@ -0,0 +1,507 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/trivial"
+)
+
+const walStorageName = "WAL file storage"
+
+// Write-ahead log fsync policies.
+const (
+	SyncAlways   = "always"
+	SyncEverySec = "everysec"
+	SyncNo       = "no"
+)
+
+// walSuffix is appended to store file name to get write-ahead log file name.
+const walSuffix = ".wal"
+
+// walCompactSize is a log size which triggers compaction regardless of store interval.
+const walCompactSize = 64 << 20
+
+// walTick is a period of background log maintenance: fsync under everysec policy and compaction.
+const walTick = time.Second
+
+var _ storage.Storage = (*walClient)(nil)
+
+type (
+	// walClient keeps metrics in memory. Every update is appended to log before it is applied, so metrics state is
+	// recovered on Init by replaying log over the latest snapshot. Log is compacted into snapshot periodically by
+	// background task. Compaction holds storage lock, so updates stall while snapshot of history is written.
+	walClient struct {
+		storage.Storage
+		sync.Mutex
+		snapshotName string
+		logName      string
+		policy       string
+		interval     time.Duration
+		log          *os.File
+		logSize      int64
+		seq          uint64
+		dirty        bool
+		compacted    time.Time
+		now          func() time.Time
+		stop         context.CancelFunc
+		done         chan struct{}
+	}
+
+	// walHeader is the first line of snapshot. Log entries up to Seq are included in snapshot.
+	walHeader struct {
+		Seq uint64 `json:"seq"`
+	}
+
+	// walEntry is a log line which holds samples of single update.
+	walEntry struct {
+		Seq     uint64         `json:"seq"`
+		Samples metric.Samples `json:"samples"`
+	}
+)
+
+func (c *walClient) IsPersistent() bool {
+	return true
+}
+
+func (c *walClient) Init(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(walStorageName))
+	ctx = logging.SetLogger(ctx, logger)
+
+	switch c.policy {
+	case SyncAlways, SyncEverySec, SyncNo:
+	default:
+		err := fmt.Errorf("unknown fsync policy: %s", c.policy)
+		logger.Err(err).Msg("failed to init")
+		return err
+	}
+	if err := c.Storage.Init(ctx); err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if err := c.replay(ctx); err != nil {
+		logger.Err(err).Msg("failed to replay log")
+		return err
+	}
+	log, err := os.OpenFile(c.logName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		logger.Err(err).Msg("failed to open log")
+		return err
+	}
+	c.log = log
+	c.compacted = c.now()
+
+	ctx, c.stop = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		task.Task(c.tick).With(task.PeriodicRun(walTick))(ctx)
+	}()
+
+	logger.Info().Msgf("initialized at log sequence %d", c.seq)
+	return nil
+}
+
+func (c *walClient) Update(ctx context.Context, mtr *metric.Metric) error {
+	return c.UpdateBulk(ctx, metric.List{mtr})
+}
+
+func (c *walClient) UpdateBulk(ctx context.Context, list metric.List) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(walStorageName), logging.WithCID(ctx))
+	ctx = logging.SetLogger(ctx, logger)
+
+	c.Lock()
+	defer c.Unlock()
+	samples, err := c.actualize(ctx, list)
+	if err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+	return c.write(ctx, samples)
+}
+
+func (c *walClient) Append(ctx context.Context, samples metric.Samples) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(walStorageName), logging.WithCID(ctx))
+
+	for _, smp := range samples {
+		if err := smp.Type().Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+		if err := smp.Labels.Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return c.write(logging.SetLogger(ctx, logger), samples)
+}
+
+func (c *walClient) Clear(ctx context.Context) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(walStorageName), logging.WithCID(ctx))
+	ctx = logging.SetLogger(ctx, logger)
+
+	c.Lock()
+	defer c.Unlock()
+	if err := c.Storage.Clear(ctx); err != nil {
+		return err
+	}
+	if err := c.compact(ctx); err != nil {
+		return err
+	}
+	logger.Info().Msg("cleared")
+	return nil
+}
+
+func (c *walClient) Close(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(walStorageName), logging.WithCID(ctx))
+
+	if c.stop != nil {
+		c.stop()
+		<-c.done
+	}
+	c.Lock()
+	if c.log != nil {
+		if err := c.log.Sync(); err != nil {
+			logger.Err(err).Msg("failed to sync log")
+		}
+		if err := c.log.Close(); err != nil {
+			logger.Err(err).Msg("failed to close log")
+		}
+		c.log = nil
+	}
+	c.Unlock()
+
+	c.Storage.Close(logging.SetLogger(ctx, logger))
+	logger.Info().Msg("closed")
+}
+
+// actualize returns samples of metrics values resulting from update. Metric is sampled once even if it is updated
+// several times. Thread unsafe, should be locked before update.
+func (c *walClient) actualize(ctx context.Context, list metric.List) (metric.Samples, error) {
+	now := c.now()
+	index := make(map[string]int, len(list))
+	samples := make(metric.Samples, 0, len(list))
+	for _, mtr := range list {
+		if err := mtr.Type().Validate(); err != nil {
+			return nil, err
+		}
+		if err := mtr.Labels.Validate(); err != nil {
+			return nil, err
+		}
+
+		key := string(mtr.Type()) + "/" + mtr.Key()
+		i, ok := index[key]
+		var prev *metric.Metric
+		if ok {
+			prev = samples[i].Metric
+		} else {
+			var err error
+			if prev, err = c.Storage.Get(ctx, mtr.ID, mtr.Type(), mtr.Labels); err != nil {
+				return nil, err
+			}
+			i = len(samples)
+			index[key] = i
+			samples = append(samples, nil)
+		}
+
+		actual := mtr
+		if prev != nil {
+			switch mtr.Type() {
+			case metric.CounterType:
+				actual = metric.NewCounterMetric(mtr.ID, *prev.Value.(*metric.Counter)+*mtr.Value.(*metric.Counter))
+			case metric.HistogramType:
+				hist := mtr.Value.(*metric.Histogram).Copy()
+				if err := hist.Merge(prev.Value.(*metric.Histogram)); err != nil {
+					return nil, err
+				}
+				actual = metric.NewHistogramMetric(mtr.ID, hist)
+			}
+		}
+		samples[i] = metric.NewSample(actual.WithLabels(mtr.Labels), now)
+	}
+	return samples, nil
+}
+
+// write appends samples to log and applies them to metrics state. Thread unsafe, should be locked before update.
+func (c *walClient) write(ctx context.Context, samples metric.Samples) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	if c.log == nil {
+		err := errors.New("storage is not initialized")
+		logger.Err(err).Msg("write failed")
+		return err
+	}
+
+	data, err := json.Marshal(&walEntry{Seq: c.seq + 1, Samples: samples})
+	if err != nil {
+		logger.Err(err).Msg("failed to encode log entry")
+		return err
+	}
+	n, err := c.log.Write(append(data, '\n'))
+	if err != nil {
+		logger.Err(err).Msg("failed to write log entry")
+		// partially written entry would make the following ones unreadable
+		if n != 0 {
+			if err := c.log.Truncate(c.logSize); err != nil {
+				logger.Err(err).Msg("failed to discard partially written log entry")
+			}
+		}
+		return err
+	}
+	c.logSize += int64(n)
+	c.seq++
+	switch c.policy {
+	case SyncAlways:
+		if err := c.log.Sync(); err != nil {
+			logger.Err(err).Msg("failed to sync log")
+			return err
+		}
+	case SyncEverySec:
+		c.dirty = true
+	}
+
+	if err := c.Storage.Append(ctx, samples); err != nil {
+		return err
+	}
+	logger.Trace().Msgf("%d samples written at log sequence %d", len(samples), c.seq)
+	return nil
+}
+
+// tick syncs log entries written since previous tick and compacts log if it has grown too large or store interval has
+// elapsed. Failed compaction is retried on next tick.
+func (c *walClient) tick(ctx context.Context) {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	c.Lock()
+	defer c.Unlock()
+	if c.log == nil {
+		return
+	}
+	if c.dirty {
+		if err := c.log.Sync(); err != nil {
+			logger.Err(err).Msg("failed to sync log")
+		} else {
+			c.dirty = false
+		}
+	}
+	if c.logSize >= walCompactSize || c.interval != 0 && c.now().Sub(c.compacted) >= c.interval {
+		if err := c.compact(ctx); err != nil {
+			logger.Err(err).Msg("compaction failed")
+		}
+	}
+}
+
+// compact writes metrics history to a new snapshot which atomically replaces the previous one and truncates log.
+// Thread unsafe, should be locked before update.
+func (c *walClient) compact(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	samples, err := c.Storage.History(ctx)
+	if err != nil {
+		return err
+	}
+
+	tmpName := c.snapshotName + ".tmp"
+	tmp, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		logger.Err(err).Msg("failed to create snapshot")
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(&walHeader{Seq: c.seq})
+	for i := 0; err == nil && i < len(samples); i++ {
+		err = enc.Encode(samples[i])
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, c.snapshotName)
+	}
+	if err != nil {
+		logger.Err(err).Msg("failed to write snapshot")
+		if err := os.Remove(tmpName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Err(err).Msg("failed to remove incomplete snapshot")
+		}
+		return err
+	}
+	syncDir(ctx, filepath.Dir(c.snapshotName))
+
+	// log entries are covered by snapshot, they are skipped on replay even if truncate fails
+	if c.log != nil {
+		if err = c.log.Truncate(0); err != nil {
+			logger.Err(err).Msg("failed to truncate log")
+			return err
+		}
+		if err = c.log.Sync(); err != nil {
+			logger.Err(err).Msg("failed to sync log")
+			return err
+		}
+		c.logSize = 0
+		c.dirty = false
+	}
+	c.compacted = c.now()
+
+	logger.Info().Msgf("compacted %d samples at log sequence %d", len(samples), c.seq)
+	return nil
+}
+
+// replay restores metrics state from snapshot and log entries written after it. Incomplete entry at the end of log is
+// discarded as it was never acknowledged. Thread unsafe, should be locked before update.
+func (c *walClient) replay(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	header, samples, err := readSnapshot(c.snapshotName)
+	if err != nil {
+		return err
+	}
+	if err = c.Storage.Append(ctx, samples); err != nil {
+		return err
+	}
+	c.seq = header.Seq
+
+	file, err := os.OpenFile(c.logName, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var offset int64
+	entries := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) == 0 {
+			break
+		}
+
+		entry := &walEntry{}
+		if complete := err == nil; !complete || json.Unmarshal(line, entry) != nil {
+			if _, err := r.Peek(1); complete && err != io.EOF {
+				return fmt.Errorf("log is corrupted at offset %d", offset)
+			}
+			logger.Warn().Msgf("discarding incomplete log entry at offset %d", offset)
+			if err := file.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		offset += int64(len(line))
+
+		if entry.Seq <= c.seq {
+			continue
+		}
+		if err := c.Storage.Append(ctx, entry.Samples); err != nil {
+			return err
+		}
+		c.seq = entry.Seq
+		entries++
+	}
+	c.logSize = offset
+
+	logger.Info().Msgf("replayed %d snapshot samples and %d log entries", len(samples), entries)
+	return nil
+}
+
+func readSnapshot(name string) (*walHeader, metric.Samples, error) {
+	header := &walHeader{}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return header, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(header); err != nil {
+		if err == io.EOF {
+			return header, nil, nil
+		}
+		return nil, nil, fmt.Errorf("malformed snapshot header: %w", err)
+	}
+	samples := make(metric.Samples, 0)
+	for dec.More() {
+		smp := &metric.Sample{}
+		if err = dec.Decode(smp); err != nil {
+			return nil, nil, fmt.Errorf("malformed snapshot: %w", err)
+		}
+		samples = append(samples, smp)
+	}
+	return header, samples, nil
+}
+
+// syncDir flushes directory entries, so that renamed file survives crash.
+func syncDir(ctx context.Context, name string) {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	dir, err := os.Open(name)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to open directory")
+		return
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer dir.Close()
+	if err = dir.Sync(); err != nil {
+		logger.Warn().Err(err).Msg("failed to sync directory")
+	}
+}
+
+// walEnabled reports if metrics are stored in write-ahead log instead of being dumped.
+func walEnabled(cfg *config.Config) bool {
+	return cfg.StoreWAL && len(cfg.StoreFile) != 0 && len(cfg.Database) == 0 && len(cfg.StoreKV) == 0
+}
+
+// NewWAL returns storage which keeps metrics in memory backed by write-ahead log. Returns nil if WAL mode is disabled.
+func NewWAL(cfg *config.Config) storage.Storage {
+	if !walEnabled(cfg) {
+		return nil
+	}
+	policy := cfg.StoreSync
+	if len(policy) == 0 {
+		policy = config.DefaultStoreSync
+	}
+	return &walClient{
+		Storage:      trivial.New(cfg),
+		snapshotName: cfg.StoreFile,
+		logName:      cfg.StoreFile + walSuffix,
+		policy:       policy,
+		interval:     cfg.StoreInterval,
+		now:          time.Now,
+	}
+}
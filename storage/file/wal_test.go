@@ -0,0 +1,186 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
+)
+
+func newTestWAL(t *testing.T, cfg *config.Config) *walClient {
+	c := NewWAL(cfg).(*walClient)
+	require.NoError(t, c.Init(context.Background()))
+	t.Cleanup(func() { c.Close(context.Background()) })
+	return c
+}
+
+func TestNewWAL(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		wantWAL  bool
+		wantDump bool
+	}{
+		{
+			name:     "Dump mode",
+			cfg:      &config.Config{StoreFile: "metrics.json"},
+			wantDump: true,
+		},
+		{
+			name:    "WAL mode",
+			cfg:     &config.Config{StoreFile: "metrics.json", StoreWAL: true},
+			wantWAL: true,
+		},
+		{
+			name:     "Database is configured",
+			cfg:      &config.Config{StoreFile: "metrics.json", StoreWAL: true, Database: "postgres://localhost/monitor"},
+			wantDump: true,
+		},
+		{
+			name: "Key-value store is configured",
+			cfg:  &config.Config{StoreFile: "metrics.json", StoreKV: "monitor.db"},
+		},
+		{
+			name:     "Key-value store is ignored with database",
+			cfg:      &config.Config{StoreFile: "metrics.json", StoreKV: "monitor.db", Database: "postgres://localhost/monitor"},
+			wantDump: true,
+		},
+		{
+			name: "Store file is not set",
+			cfg:  &config.Config{StoreWAL: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantWAL, NewWAL(tt.cfg) != nil)
+			assert.Equal(t, tt.wantDump, New(tt.cfg) != nil)
+		})
+	}
+}
+
+func TestWAL(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{StoreFile: filepath.Join(t.TempDir(), "metrics.json"), StoreWAL: true}
+	logName := cfg.StoreFile + walSuffix
+	labels := metric.Labels{"host": "foo"}
+
+	c := newTestWAL(t, cfg)
+	require.NoError(t, c.UpdateBulk(ctx, metric.List{
+		metric.NewCounterMetric("PollCount", 1),
+		metric.NewCounterMetric("PollCount", 2),
+		metric.NewGaugeMetric("Alloc", 1).WithLabels(labels),
+	}))
+	require.NoError(t, c.Update(ctx, metric.NewCounterMetric("PollCount", 3)))
+	assert.Error(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 2).WithLabels(metric.Labels{"": "foo"})))
+
+	want := metric.List{
+		metric.NewGaugeMetric("Alloc", 1).WithLabels(labels),
+		metric.NewCounterMetric("PollCount", 6),
+	}
+	requireState := func(t *testing.T, s storage.Storage, samples int) {
+		list, err := s.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, list)
+		history, err := s.History(ctx)
+		require.NoError(t, err)
+		assert.Len(t, history, samples)
+	}
+
+	got, err := c.Get(ctx, "PollCount", metric.CounterType, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.NewCounterMetric("PollCount", 6), got)
+	requireState(t, c, 3)
+	c.Close(ctx)
+
+	t.Run("Replay", func(t *testing.T) {
+		requireState(t, newTestWAL(t, cfg), 3)
+	})
+
+	t.Run("Incomplete entry", func(t *testing.T) {
+		log, err := os.OpenFile(logName, os.O_WRONLY|os.O_APPEND, 0666)
+		require.NoError(t, err)
+		_, err = log.WriteString(`{"seq":3,"samples":[{"ID":"Alloc"`)
+		require.NoError(t, err)
+		require.NoError(t, log.Close())
+
+		c := newTestWAL(t, cfg)
+		requireState(t, c, 3)
+		require.NoError(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 1).WithLabels(labels)))
+		c.Close(ctx)
+
+		requireState(t, newTestWAL(t, cfg), 4)
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		c := newTestWAL(t, cfg)
+		stale, err := os.ReadFile(logName)
+		require.NoError(t, err)
+
+		require.NoError(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 1).WithLabels(labels)))
+		now := time.Now()
+		c.Lock()
+		c.interval = time.Minute
+		c.now = func() time.Time { return now.Add(time.Minute) }
+		c.Unlock()
+		c.tick(ctx)
+		c.Close(ctx)
+
+		info, err := os.Stat(logName)
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+
+		// crash between snapshot rename and log truncation
+		require.NoError(t, os.WriteFile(logName, stale, 0666))
+		requireState(t, newTestWAL(t, cfg), 5)
+	})
+
+	t.Run("Corrupted log", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(logName, []byte("{}}\n{\"seq\":100}\n"), 0666))
+		assert.Error(t, NewWAL(cfg).Init(ctx))
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		require.NoError(t, os.Remove(logName))
+		c := newTestWAL(t, cfg)
+		require.NoError(t, c.Clear(ctx))
+		c.Close(ctx)
+
+		list, err := newTestWAL(t, cfg).GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("Sync every second", func(t *testing.T) {
+		cfg := &config.Config{StoreFile: cfg.StoreFile, StoreWAL: true, StoreSync: SyncEverySec}
+		c := newTestWAL(t, cfg)
+		require.NoError(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 1).WithLabels(labels)))
+
+		assert.Eventually(t, func() bool {
+			c.Lock()
+			defer c.Unlock()
+			return !c.dirty
+		}, 3*walTick, 10*time.Millisecond, "log is synced by background task")
+	})
+
+	t.Run("Unknown fsync policy", func(t *testing.T) {
+		assert.Error(t, NewWAL(&config.Config{StoreFile: cfg.StoreFile, StoreWAL: true, StoreSync: "sometimes"}).Init(ctx))
+	})
+}
+
+func TestWAL_Conformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return NewWAL(&config.Config{StoreFile: filepath.Join(t.TempDir(), "metrics.json"), StoreWAL: true})
+		},
+	}.Run(t)
+}
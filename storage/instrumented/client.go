@@ -0,0 +1,133 @@
+// Package instrumented is a storage decorator which records calls count, errors count and latency of every storage
+// operation as monitor server's self-metrics.
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/selfmetrics"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+)
+
+const (
+	CallsMetric   = "MonitorStorageCalls"
+	ErrorsMetric  = "MonitorStorageErrors"
+	LatencyMetric = "MonitorStorageLatency"
+
+	// StorageLabel distinguishes decorated storages, e.g. {storage="metrics"}.
+	StorageLabel = "storage"
+
+	// OperationLabel is a name of storage operation, e.g. {op="UpdateBulk"}.
+	OperationLabel = "op"
+)
+
+var (
+	_ storage.Storage  = (*client)(nil)
+	_ storage.Replacer = (*client)(nil)
+)
+
+type client struct {
+	storage.Storage
+	name     string
+	recorder *selfmetrics.Recorder
+}
+
+func (c *client) Init(ctx context.Context) error {
+	start := time.Now()
+	err := c.Storage.Init(ctx)
+	c.record("Init", start, err)
+	return err
+}
+
+func (c *client) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := c.Storage.Ping(ctx)
+	c.record("Ping", start, err)
+	return err
+}
+
+func (c *client) Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
+	start := time.Now()
+	mtr, err := c.Storage.Get(ctx, id, typ, labels)
+	c.record("Get", start, err)
+	return mtr, err
+}
+
+func (c *client) GetAll(ctx context.Context, filter metric.Labels) (metric.List, error) {
+	start := time.Now()
+	list, err := c.Storage.GetAll(ctx, filter)
+	c.record("GetAll", start, err)
+	return list, err
+}
+
+func (c *client) Update(ctx context.Context, mtr *metric.Metric) error {
+	start := time.Now()
+	err := c.Storage.Update(ctx, mtr)
+	c.record("Update", start, err)
+	return err
+}
+
+func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
+	start := time.Now()
+	err := c.Storage.UpdateBulk(ctx, list)
+	c.record("UpdateBulk", start, err)
+	return err
+}
+
+func (c *client) History(ctx context.Context) (metric.Samples, error) {
+	start := time.Now()
+	samples, err := c.Storage.History(ctx)
+	c.record("History", start, err)
+	return samples, err
+}
+
+func (c *client) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	start := time.Now()
+	series, err := c.Storage.Query(ctx, query)
+	c.record("Query", start, err)
+	return series, err
+}
+
+func (c *client) Append(ctx context.Context, samples metric.Samples) error {
+	start := time.Now()
+	err := c.Storage.Append(ctx, samples)
+	c.record("Append", start, err)
+	return err
+}
+
+func (c *client) Clear(ctx context.Context) error {
+	start := time.Now()
+	err := c.Storage.Clear(ctx)
+	c.record("Clear", start, err)
+	return err
+}
+
+// Replace replaces content of decorated storage. It is recorded as a single operation even if decorated storage is not
+// a storage.Replacer.
+func (c *client) Replace(ctx context.Context, samples metric.Samples) error {
+	start := time.Now()
+	err := storage.Replace(ctx, c.Storage, samples)
+	c.record("Replace", start, err)
+	return err
+}
+
+// record registers storage operation call.
+func (c *client) record(op string, start time.Time, err error) {
+	labels := metric.Labels{StorageLabel: c.name, OperationLabel: op}
+	c.recorder.ObserveLatency(LatencyMetric, labels, time.Since(start).Seconds())
+	c.recorder.Count(CallsMetric, labels, 1)
+	if err != nil {
+		c.recorder.Count(ErrorsMetric, labels, 1)
+	}
+}
+
+// New decorates storage with self-metrics recording. Storage is identified by name in recorded metrics labels. Storage
+// is returned as is if recorder is not specified.
+func New(st storage.Storage, name string, recorder *selfmetrics.Recorder) storage.Storage {
+	if st == nil || recorder == nil {
+		return st
+	}
+	return &client{Storage: st, name: name, recorder: recorder}
+}
@@ -0,0 +1,61 @@
+package instrumented
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/selfmetrics"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/trivial"
+)
+
+func TestNew(t *testing.T) {
+	st := trivial.New(&config.Config{})
+	assert.Equal(t, st, New(st, "metrics", nil), "not decorated without recorder")
+	assert.Nil(t, New(nil, "metrics", selfmetrics.NewRecorder()))
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	recorder := selfmetrics.NewRecorder()
+	st := New(trivial.New(&config.Config{}), "metrics", recorder)
+
+	require.NoError(t, st.Init(ctx))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+	_, err := st.Get(ctx, "PollCount", "unknown", nil)
+	require.Error(t, err)
+
+	labels := func(op string) metric.Labels {
+		return metric.Labels{StorageLabel: "metrics", OperationLabel: op}
+	}
+	list := recorder.Flush()
+	assert.Contains(t, list, metric.NewCounterMetric(CallsMetric, 1).WithLabels(labels("Init")))
+	assert.Contains(t, list, metric.NewCounterMetric(CallsMetric, 2).WithLabels(labels("Update")))
+	assert.Contains(t, list, metric.NewCounterMetric(CallsMetric, 1).WithLabels(labels("Get")))
+	assert.Contains(t, list, metric.NewCounterMetric(ErrorsMetric, 1).WithLabels(labels("Get")))
+
+	for _, mtr := range list {
+		if !mtr.Labels.Equal(labels("Update")) {
+			continue
+		}
+		assert.NotEqual(t, ErrorsMetric, mtr.ID, "successful calls are not errors")
+		if mtr.ID == LatencyMetric {
+			assert.Equal(t, uint64(2), mtr.Value.(*metric.Histogram).Count)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return New(trivial.New(&config.Config{}), "metrics", selfmetrics.NewRecorder())
+		},
+	}.Run(t)
+}
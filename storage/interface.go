@@ -22,11 +22,11 @@ type (
 		// Close releases storage resources. Should be called on end of working with storage.
 		Close(ctx context.Context)
 
-		// Get queries single metric. Returns nil if metric not found.
-		Get(ctx context.Context, id string, typ metric.Type) (*metric.Metric, error)
+		// Get queries single metric identified by ID, type and labels. Returns nil if metric not found.
+		Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error)
 
-		// GetAll queries all metrics.
-		GetAll(ctx context.Context) (metric.List, error)
+		// GetAll queries all metrics which labels match the filter. Empty filter matches all metrics.
+		GetAll(ctx context.Context, filter metric.Labels) (metric.List, error)
 
 		// Update registers or updates single metric.
 		Update(ctx context.Context, mtr *metric.Metric) error
@@ -34,10 +34,25 @@ type (
 		// UpdateBulk registers or updates all metrics in list.
 		UpdateBulk(ctx context.Context, list metric.List) error
 
+		// History queries all registered metrics samples. Every accepted update is registered as timestamped sample.
+		History(ctx context.Context) (metric.Samples, error)
+
+		// Query queries history of single metric within time range. History is aggregated by step intervals if step is set.
+		Query(ctx context.Context, query *metric.Query) (metric.Series, error)
+
+		// Append stores samples as is, keeping its timestamps. Latest sample of metric becomes its actual value.
+		Append(ctx context.Context, samples metric.Samples) error
+
 		// Clear deletes all metrics in storage.
 		Clear(ctx context.Context) error
 	}
 
+	// Replacer is implemented by storages able to replace all their content with samples as a single operation, e.g.
+	// dumps. Either previous or new content is kept if replace fails.
+	Replacer interface {
+		Replace(ctx context.Context, samples metric.Samples) error
+	}
+
 	// Factory produces initialized storage object.
 	Factory func(*config.Config) Storage
 )
@@ -51,3 +66,15 @@ func New(cfg *config.Config, factories ...Factory) Storage {
 	}
 	return nil
 }
+
+// Replace replaces storage content with samples. Storage is cleared before samples are appended if it is not a Replacer,
+// so that it may be left empty on failure.
+func Replace(ctx context.Context, st Storage, samples metric.Samples) error {
+	if r, ok := st.(Replacer); ok {
+		return r.Replace(ctx, samples)
+	}
+	if err := st.Clear(ctx); err != nil {
+		return err
+	}
+	return st.Append(ctx, samples)
+}
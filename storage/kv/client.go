@@ -0,0 +1,496 @@
+// Package kv is a metrics storage kept in embedded key-value store. Every update is persisted on its own, so that
+// single node monitor server doesn't depend on db server and doesn't rewrite all metrics on each update.
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+)
+
+const kvStorageName = "KV storage"
+
+// openTimeout limits waiting for store file lock held by another process.
+const openTimeout = 5 * time.Second
+
+var _ storage.Storage = (*client)(nil)
+
+var (
+	metricsBucket = []byte("metrics")
+	samplesBucket = []byte("samples")
+)
+
+// minTime and maxTime are bounds of timestamps representable in sample keys.
+var (
+	minTime = time.Unix(0, math.MinInt64)
+	maxTime = time.Unix(0, math.MaxInt64)
+)
+
+// client keeps actual metrics values and its samples in separate buckets. Metric is keyed by type, ID and labels.
+// Sample key is prefixed with key of its metric and followed by timestamp and sequence number, so that history of
+// metric is a chronologically ordered range of keys.
+type client struct {
+	db        *bolt.DB
+	fileName  string
+	retention time.Duration
+}
+
+func (c *client) Clear(ctx context.Context) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metricsBucket, samplesBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		logger.Err(err).Msg("failed to clear buckets")
+		return err
+	}
+	logger.Info().Msg("cleared")
+	return nil
+}
+
+func (c *client) IsPersistent() bool {
+	return true
+}
+
+func (c *client) Init(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName))
+
+	db, err := bolt.Open(c.fileName, 0666, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		logger.Err(err).Msg("failed to open store")
+		return err
+	}
+	if err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metricsBucket, samplesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		logger.Err(err).Msg("failed to create buckets")
+		if err := db.Close(); err != nil {
+			logger.Err(err).Msg("failed to close store")
+		}
+		return err
+	}
+	c.db = db
+
+	logger.Info().Msg("initialized")
+	return nil
+}
+
+func (c *client) Update(ctx context.Context, mtr *metric.Metric) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	now := time.Now()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return c.update(logging.SetLogger(ctx, logger), tx, mtr, now)
+	})
+}
+
+func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	now := time.Now()
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		for _, mtr := range list {
+			if err := c.update(logging.SetLogger(ctx, logger), tx, mtr, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	logger.Trace().Msgf("%d records updated", len(list))
+	return nil
+}
+
+func (c *client) Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (mtr *metric.Metric, err error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxKeyStr(logging.MetricIDKey, id))
+	logger.UpdateContext(logging.LogCtxFrom(typ, labels))
+
+	if err = typ.Validate(); err != nil {
+		logger.Err(err).Msg("read failed")
+		return nil, err
+	}
+	if err = c.db.View(func(tx *bolt.Tx) (err error) {
+		mtr, err = read(tx, metricKey(id, typ, labels))
+		return
+	}); err != nil {
+		logger.Err(err).Msg("read failed")
+		return nil, err
+	}
+
+	if mtr == nil {
+		logger.Trace().Msg("not found")
+		return nil, nil
+	}
+	logger.UpdateContext(logging.LogCtxFrom(mtr))
+	logger.Trace().Msg("read")
+	return mtr, nil
+}
+
+func (c *client) GetAll(ctx context.Context, filter metric.Labels) (metric.List, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(filter))
+
+	list := make(metric.List, 0)
+	if err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(metricsBucket).ForEach(func(_, value []byte) error {
+			mtr := &metric.Metric{}
+			if err := json.Unmarshal(value, mtr); err != nil {
+				return err
+			}
+			if mtr.Labels.Matches(filter) {
+				list = append(list, mtr)
+			}
+			return nil
+		})
+	}); err != nil {
+		logger.Err(err).Msg("read failed")
+		return nil, err
+	}
+
+	logger.Trace().Msgf("%d records read", len(list))
+	return list, nil
+}
+
+func (c *client) History(ctx context.Context) (metric.Samples, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	samples := make(metric.Samples, 0)
+	if err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(samplesBucket).ForEach(func(_, value []byte) error {
+			smp := &metric.Sample{}
+			if err := json.Unmarshal(value, smp); err != nil {
+				return err
+			}
+			samples = append(samples, smp)
+			return nil
+		})
+	}); err != nil {
+		logger.Err(err).Msg("read failed")
+		return nil, err
+	}
+	samples.SortByTime()
+
+	logger.Trace().Msgf("%d samples read", len(samples))
+	return samples, nil
+}
+
+func (c *client) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(query))
+
+	from, to := query.Range()
+	prefix := metricKey(query.ID, query.Type, query.Labels)
+
+	samples := make(metric.Samples, 0)
+	if err := c.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(samplesBucket).Cursor()
+		for k, v := cur.Seek(sampleKey(prefix, from, 0)); k != nil && bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+			if sampleTime(prefix, k).After(to) {
+				break
+			}
+			smp := &metric.Sample{}
+			if err := json.Unmarshal(v, smp); err != nil {
+				return err
+			}
+			samples = append(samples, smp)
+		}
+		return nil
+	}); err != nil {
+		logger.Err(err).Msg("read failed")
+		return nil, err
+	}
+	points := query.Apply(samples)
+
+	logger.Trace().Msgf("%d points read", len(points))
+	return points, nil
+}
+
+func (c *client) Append(ctx context.Context, samples metric.Samples) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	sorted := make(metric.Samples, len(samples))
+	copy(sorted, samples)
+	sorted.SortByTime()
+
+	now := time.Now()
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		for _, smp := range sorted {
+			if err := smp.Type().Validate(); err != nil {
+				return err
+			}
+			if err := smp.Labels.Validate(); err != nil {
+				return err
+			}
+			k := metricKey(smp.ID, smp.Type(), smp.Labels)
+			if latest, ok := latestSampleTime(tx, k); !ok || !smp.Timestamp.Before(latest) {
+				if err := write(tx, k, smp.Metric); err != nil {
+					return err
+				}
+			}
+			if err := c.record(tx, k, smp, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		logger.Err(err).Msg("append failed")
+		return err
+	}
+
+	logger.Trace().Msgf("%d samples appended", len(samples))
+	return nil
+}
+
+func (c *client) Ping(ctx context.Context) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	if c.db == nil {
+		err := bolt.ErrDatabaseNotOpen
+		logger.Err(err).Msg("storage is offline")
+		return err
+	}
+	if err := c.db.View(func(*bolt.Tx) error { return nil }); err != nil {
+		logger.Err(err).Msg("storage is offline")
+		return err
+	}
+	logger.Trace().Msg("storage is online")
+	return nil
+}
+
+func (c *client) Close(ctx context.Context) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+
+	if c.db == nil {
+		return
+	}
+	if err := c.db.Close(); err != nil {
+		logger.Err(err).Msg("failed to close store")
+		return
+	}
+	logger.Info().Msg("closed")
+}
+
+func (c *client) update(ctx context.Context, tx *bolt.Tx, mtr *metric.Metric, timestamp time.Time) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(kvStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(mtr))
+
+	if err := mtr.Type().Validate(); err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+	if err := mtr.Labels.Validate(); err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+
+	k := metricKey(mtr.ID, mtr.Type(), mtr.Labels)
+	stored, err := read(tx, k)
+	if err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+
+	var actual *metric.Metric
+	switch mtr.Type() {
+	case metric.GaugeType:
+		actual = metric.NewGaugeMetric(mtr.ID, *mtr.Value.(*metric.Gauge))
+	case metric.CounterType:
+		delta := *mtr.Value.(*metric.Counter)
+		if stored != nil {
+			delta += *stored.Value.(*metric.Counter)
+		}
+		actual = metric.NewCounterMetric(mtr.ID, delta)
+	case metric.HistogramType:
+		hist := mtr.Value.(*metric.Histogram).Copy()
+		if stored != nil {
+			if err := hist.Merge(stored.Value.(*metric.Histogram)); err != nil {
+				logger.Err(err).Msg("update failed")
+				return err
+			}
+		}
+		actual = metric.NewHistogramMetric(mtr.ID, hist)
+	default:
+		err := fmt.Errorf("unknown metric %v", mtr.Type())
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+	actual.WithLabels(mtr.Labels)
+
+	if err = write(tx, k, actual); err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+	if err = c.record(tx, k, metric.NewSample(actual, timestamp), timestamp); err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
+
+	logger.Trace().Msg("updated")
+	return nil
+}
+
+// record stores sample of metric and drops its samples which are out of retention period. The latest sample of metric
+// is always kept.
+func (c *client) record(tx *bolt.Tx, prefix []byte, smp *metric.Sample, now time.Time) error {
+	bucket := tx.Bucket(samplesBucket)
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(smp)
+	if err != nil {
+		return err
+	}
+	if err = bucket.Put(sampleKey(prefix, smp.Timestamp, seq), value); err != nil {
+		return err
+	}
+	if c.retention == 0 {
+		return nil
+	}
+
+	expired := now.Add(-c.retention)
+	stale := make([][]byte, 0)
+	last := true
+	cur := bucket.Cursor()
+	for k, _ := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cur.Next() {
+		if !sampleTime(prefix, k).Before(expired) {
+			last = false
+			break
+		}
+		stale = append(stale, k)
+	}
+	if last && len(stale) != 0 {
+		stale = stale[:len(stale)-1]
+	}
+	for _, k := range stale {
+		if err = bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read returns metric stored under the key or nil if it is absent.
+func read(tx *bolt.Tx, k []byte) (*metric.Metric, error) {
+	value := tx.Bucket(metricsBucket).Get(k)
+	if value == nil {
+		return nil, nil
+	}
+	mtr := &metric.Metric{}
+	if err := json.Unmarshal(value, mtr); err != nil {
+		return nil, err
+	}
+	return mtr, nil
+}
+
+func write(tx *bolt.Tx, k []byte, mtr *metric.Metric) error {
+	value, err := json.Marshal(mtr)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(metricsBucket).Put(k, value)
+}
+
+// latestSampleTime returns timestamp of the latest sample of metric.
+func latestSampleTime(tx *bolt.Tx, prefix []byte) (time.Time, bool) {
+	cur := tx.Bucket(samplesBucket).Cursor()
+	upper := make([]byte, len(prefix), len(prefix)+16)
+	copy(upper, prefix)
+	upper = append(upper, bytes.Repeat([]byte{0xff}, 16)...)
+
+	k, _ := cur.Seek(upper)
+	if k == nil {
+		k, _ = cur.Last()
+	} else {
+		k, _ = cur.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return time.Time{}, false
+	}
+	return sampleTime(prefix, k), true
+}
+
+// metricKey returns key of metric, e.g. counter\x00PollCount\x00{instance="foo"}\x00
+func metricKey(id string, typ metric.Type, labels metric.Labels) []byte {
+	k := make([]byte, 0, len(typ)+len(id)+3)
+	k = append(append(k, typ...), 0)
+	k = append(append(k, id...), 0)
+	k = append(append(k, labels.String()...), 0)
+	return k
+}
+
+// sampleKey returns key of metric sample. Sequence number distinguishes simultaneous samples.
+func sampleKey(prefix []byte, timestamp time.Time, seq uint64) []byte {
+	k := make([]byte, len(prefix)+16)
+	copy(k, prefix)
+	binary.BigEndian.PutUint64(k[len(prefix):], encodeTime(timestamp))
+	binary.BigEndian.PutUint64(k[len(prefix)+8:], seq)
+	return k
+}
+
+func sampleTime(prefix []byte, k []byte) time.Time {
+	return decodeTime(binary.BigEndian.Uint64(k[len(prefix):]))
+}
+
+// encodeTime represents timestamp as unsigned number keeping chronological order of its big-endian bytes. Timestamps
+// out of nanoseconds range are clamped.
+func encodeTime(timestamp time.Time) uint64 {
+	var nanos int64
+	switch {
+	case timestamp.Before(minTime):
+		nanos = math.MinInt64
+	case timestamp.After(maxTime):
+		nanos = math.MaxInt64
+	default:
+		nanos = timestamp.UnixNano()
+	}
+	return uint64(nanos) ^ 1<<63
+}
+
+func decodeTime(v uint64) time.Time {
+	return time.Unix(0, int64(v^1<<63))
+}
+
+// New returns storage kept in embedded key-value store file. Returns nil if store file is not set.
+func New(cfg *config.Config) storage.Storage {
+	if len(cfg.StoreKV) == 0 {
+		return nil
+	}
+	return &client{fileName: cfg.StoreKV, retention: cfg.Retention}
+}
@@ -0,0 +1,203 @@
+package kv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
+)
+
+func newTestClient(t *testing.T, cfg *config.Config) *client {
+	c := New(cfg).(*client)
+	require.NoError(t, c.Init(context.Background()))
+	t.Cleanup(func() { c.Close(context.Background()) })
+	return c
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(&config.Config{}))
+	assert.NotNil(t, New(&config.Config{StoreKV: "monitor.db"}))
+}
+
+func TestKV(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{StoreKV: filepath.Join(t.TempDir(), "monitor.db")}
+	labels := metric.Labels{"host": "foo"}
+
+	hist := func(values ...float64) metric.Histogram {
+		h := metric.NewHistogram(1, 10)
+		for _, v := range values {
+			h.Observe(v)
+		}
+		return h
+	}
+
+	c := newTestClient(t, cfg)
+	require.NoError(t, c.UpdateBulk(ctx, metric.List{
+		metric.NewCounterMetric("PollCount", 1),
+		metric.NewCounterMetric("PollCount", 2),
+		metric.NewGaugeMetric("Alloc", 1).WithLabels(labels),
+		metric.NewGaugeMetric("Alloc", 2),
+		metric.NewHistogramMetric("Latency", hist(0.5)),
+	}))
+	require.NoError(t, c.Update(ctx, metric.NewCounterMetric("PollCount", 3)))
+	require.NoError(t, c.Update(ctx, metric.NewHistogramMetric("Latency", hist(5))))
+	assert.Error(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 3).WithLabels(metric.Labels{"": "foo"})))
+	c.Close(ctx)
+
+	c = newTestClient(t, cfg)
+
+	tests := []struct {
+		name   string
+		id     string
+		typ    metric.Type
+		labels metric.Labels
+		want   *metric.Metric
+	}{
+		{
+			name: "Counter is accumulated",
+			id:   "PollCount",
+			typ:  metric.CounterType,
+			want: metric.NewCounterMetric("PollCount", 6),
+		},
+		{
+			name:   "Labeled gauge",
+			id:     "Alloc",
+			typ:    metric.GaugeType,
+			labels: labels,
+			want:   metric.NewGaugeMetric("Alloc", 1).WithLabels(labels),
+		},
+		{
+			name: "Histogram is merged",
+			id:   "Latency",
+			typ:  metric.HistogramType,
+			want: metric.NewHistogramMetric("Latency", hist(0.5, 5)),
+		},
+		{
+			name: "Not found",
+			id:   "Alloc",
+			typ:  metric.CounterType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Get(ctx, tt.id, tt.typ, tt.labels)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("GetAll", func(t *testing.T) {
+		list, err := c.GetAll(ctx, labels)
+		require.NoError(t, err)
+		assert.Equal(t, metric.List{metric.NewGaugeMetric("Alloc", 1).WithLabels(labels)}, list)
+
+		list, err = c.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Len(t, list, 4)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		history, err := c.History(ctx)
+		require.NoError(t, err)
+		assert.Len(t, history, 7)
+
+		series, err := c.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType})
+		require.NoError(t, err)
+		if assert.Len(t, series, 3) {
+			assert.Equal(t, []float64{1, 3, 6}, []float64{series[0].Value, series[1].Value, series[2].Value})
+		}
+
+		series, err = c.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType, From: time.Now().Add(time.Minute)})
+		require.NoError(t, err)
+		assert.Empty(t, series)
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		now := time.Now()
+		require.NoError(t, c.Append(ctx, metric.Samples{
+			metric.NewSample(metric.NewGaugeMetric("Alloc", 10), now.Add(time.Minute)),
+			metric.NewSample(metric.NewGaugeMetric("Alloc", 5), now.Add(-time.Hour)),
+		}))
+
+		got, err := c.Get(ctx, "Alloc", metric.GaugeType, nil)
+		require.NoError(t, err)
+		assert.Equal(t, metric.NewGaugeMetric("Alloc", 10), got, "the latest sample is actual value")
+
+		series, err := c.Query(ctx, &metric.Query{ID: "Alloc", Type: metric.GaugeType})
+		require.NoError(t, err)
+		if assert.Len(t, series, 3) {
+			assert.Equal(t, []float64{5, 2, 10}, []float64{series[0].Value, series[1].Value, series[2].Value})
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		require.NoError(t, c.Clear(ctx))
+		list, err := c.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		history, err := c.History(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
+
+func TestKV_retention(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, &config.Config{StoreKV: filepath.Join(t.TempDir(), "monitor.db"), Retention: time.Hour})
+
+	now := time.Now()
+	require.NoError(t, c.Append(ctx, metric.Samples{
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 1), now.Add(-3*time.Hour)),
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 2), now.Add(-2*time.Hour)),
+		metric.NewSample(metric.NewGaugeMetric("PollInterval", 1), now.Add(-2*time.Hour)),
+	}))
+
+	series, err := c.Query(ctx, &metric.Query{ID: "Alloc", Type: metric.GaugeType})
+	require.NoError(t, err)
+	if assert.Len(t, series, 1, "the latest sample is always kept") {
+		assert.Equal(t, float64(2), series[0].Value)
+	}
+
+	require.NoError(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 3)))
+	series, err = c.Query(ctx, &metric.Query{ID: "Alloc", Type: metric.GaugeType})
+	require.NoError(t, err)
+	if assert.Len(t, series, 1) {
+		assert.Equal(t, float64(3), series[0].Value)
+	}
+
+	series, err = c.Query(ctx, &metric.Query{ID: "PollInterval", Type: metric.GaugeType})
+	require.NoError(t, err)
+	assert.Len(t, series, 1, "other metrics are not affected")
+}
+
+func Test_encodeTime(t *testing.T) {
+	timestamps := []time.Time{
+		{},
+		time.Unix(-1, 0),
+		time.Unix(0, 0),
+		time.Unix(1, 0),
+		time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for i := 1; i < len(timestamps); i++ {
+		assert.Less(t, encodeTime(timestamps[i-1]), encodeTime(timestamps[i]))
+	}
+	assert.True(t, time.Unix(1, 5).Equal(decodeTime(encodeTime(time.Unix(1, 5)))))
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return New(&config.Config{StoreKV: filepath.Join(t.TempDir(), "monitor.db")})
+		},
+	}.Run(t)
+}
@@ -5,17 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"time"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/task"
 	"github.com/zhupanovdm/go-runtime-monitor/storage"
 )
 
 const dbStorageName = "SQL DB storage"
 const defaultTimeout = 5 * time.Second
 
+// maxTrimInterval limits period of expired samples trimming.
+const maxTrimInterval = time.Minute
+
+// initBackoff allows db server to be unavailable for a while at startup.
+var initBackoff = task.Backoff{
+	Initial:     500 * time.Millisecond,
+	Max:         5 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+	MaxAttempts: 6,
+}
+
 var _ storage.Storage = (*client)(nil)
 
 type (
@@ -23,33 +37,61 @@ type (
 		Driver
 		db         *sql.DB
 		timeout    time.Duration
+		retention  time.Duration
 		dataSource string
 		statements map[Query]*sql.Stmt
+		stopTrim   context.CancelFunc
+		trimDone   chan struct{}
 	}
 
+	// Driver hides specifics of db server. Queries are written in PostgreSQL dialect and translated by driver if needed.
 	Driver interface {
+		accepts(dataSource string) bool
 		open(dataSource string) (*sql.DB, error)
-		prepare(ctx context.Context, db *sql.DB) error
+		dialect(query Query) string
+
+		// migrations provides schema migration scripts written in driver's dialect.
+		migrations() fs.FS
+
+		// lock holds exclusive migrations lock until transaction ends.
+		lock(ctx context.Context, tx *sql.Tx) error
 	}
 
 	Query string
 )
 
 const (
-	CreateQuery        Query = "INSERT INTO metrics (metric_id, metric_type, value, delta) VALUES ($1,$2,$3,$4)"
-	ReadQuery          Query = "SELECT value, delta FROM metrics WHERE metric_id=$1 AND metric_type=$2 LIMIT 1"
-	UpdateGaugeQuery   Query = "UPDATE metrics SET value=$3 WHERE metric_id=$1 AND metric_type=$2"
-	UpdateCounterQuery Query = "UPDATE metrics SET delta=delta+$3 WHERE metric_id=$1 AND metric_type=$2"
-	ReadAllQuery       Query = "SELECT metric_id, metric_type, value, delta FROM metrics"
-	DeleteAllQuery     Query = "DELETE FROM metrics"
+	ReadQuery      Query = "SELECT value, delta, histogram FROM metrics WHERE metric_id=$1 AND metric_type=$2 AND labels=$3 LIMIT 1"
+	ReadAllQuery   Query = "SELECT metric_id, metric_type, labels, value, delta, histogram FROM metrics"
+	DeleteAllQuery Query = "DELETE FROM metrics"
+
+	ReadAllSamplesQuery Query = "SELECT metric_id, metric_type, labels, value, delta, histogram, ts FROM metric_samples ORDER BY ts"
+	ReadRangeQuery      Query = "SELECT metric_id, metric_type, labels, value, delta, histogram, ts FROM metric_samples " +
+		"WHERE metric_id=$1 AND metric_type=$2 AND labels=$3 AND ts>=$4 AND ts<=$5 ORDER BY ts"
+	DeleteAllSamplesQuery Query = "DELETE FROM metric_samples"
+	TrimSamplesQuery      Query = "DELETE FROM metric_samples WHERE ts<$1 AND ts<(" +
+		"SELECT MAX(h.ts) FROM metric_samples h " +
+		"WHERE h.metric_id=metric_samples.metric_id AND h.metric_type=metric_samples.metric_type " +
+		"AND h.labels=metric_samples.labels)"
 )
 
 func (c *client) Clear(ctx context.Context) error {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
-	if err := c.queryWithTx(ctx, DeleteAllQuery, exec()); err != nil {
-		logger.Err(err).Msg("failed to clear metrics table")
+	if err := c.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, query := range []Query{DeleteAllQuery, DeleteAllSamplesQuery} {
+			stmt, err := c.stmt(ctx, tx, query)
+			if err != nil {
+				return err
+			}
+			if err := exec()(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		logger.Err(err).Msg("failed to clear metrics tables")
 		return err
 	}
 	logger.Info().Msg("cleared")
@@ -63,6 +105,20 @@ func (c *client) IsPersistent() bool {
 func (c *client) Init(ctx context.Context) error {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName))
 
+	err := task.ErrTask(c.init).With(task.Retry(initBackoff))(logging.SetLogger(ctx, logger))
+	if err != nil {
+		return err
+	}
+	if c.retention != 0 {
+		c.startTrim(logging.SetLogger(ctx, logger))
+	}
+	logger.Info().Msg("initialized")
+	return nil
+}
+
+func (c *client) init(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName))
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -72,42 +128,48 @@ func (c *client) Init(ctx context.Context) error {
 		return err
 	}
 
-	// init script may be sensitive to sql dialect and depends on db server
-	if err = c.prepare(ctx, db); err != nil {
-		logger.Err(err).Msg("failed to prepare db")
+	if err = migrate(logging.SetLogger(ctx, logger), db, c.Driver, LatestVersion); err != nil {
+		logger.Err(err).Msg("failed to migrate db")
+		if err := db.Close(); err != nil {
+			logger.Err(err).Msg("failed to close db connection")
+		}
 		return err
 	}
 
-	statements, err := prepareStmts(ctx, db,
-		CreateQuery, ReadQuery, UpdateGaugeQuery, UpdateCounterQuery,
-		ReadAllQuery, DeleteAllQuery)
+	statements, err := prepareStmts(ctx, db, c.Driver,
+		ReadQuery, ReadAllQuery, DeleteAllQuery,
+		ReadAllSamplesQuery, ReadRangeQuery, DeleteAllSamplesQuery, TrimSamplesQuery)
 
 	if err != nil {
 		logger.Err(err).Msg("failed to prepare statements")
+		if err := db.Close(); err != nil {
+			logger.Err(err).Msg("failed to close db connection")
+		}
 		return err
 	}
 	c.statements = statements
 	c.db = db
-
-	logger.Info().Msg("initialized")
 	return nil
 }
 
-func (c *client) GetAll(ctx context.Context) (metric.List, error) {
+func (c *client) GetAll(ctx context.Context, filter metric.Labels) (metric.List, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(filter))
 
 	list := make(metric.List, 0)
 	fetchMetrics := fetch(func(_ context.Context, rows *sql.Rows) error {
 		m := &Metrics{}
-		if err := rows.Scan(&m.ID, &m.typ, &m.value, &m.delta); err != nil {
+		if err := rows.Scan(&m.ID, &m.typ, &m.labels, &m.value, &m.delta, &m.histogram); err != nil {
 			return err
 		}
 		mtr := m.ToCanonical()
 		if mtr == nil {
 			return errors.New("unable to convert row to canonical metric")
 		}
-		list = append(list, mtr)
+		if mtr.Labels.Matches(filter) {
+			list = append(list, mtr)
+		}
 		return nil
 	})
 	if err := c.queryWithTx(ctx, ReadAllQuery, fetchMetrics); err != nil {
@@ -124,23 +186,68 @@ func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
 	return c.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		for _, mtr := range list {
-			if err := c.process(logging.SetLogger(ctx, logger), tx, mtr); err != nil {
-				return err
-			}
+		if err := c.upsert(logging.SetLogger(ctx, logger), tx, list, time.Now()); err != nil {
+			return err
 		}
 		logger.Trace().Msgf("%d metrics processed", len(list))
 		return nil
 	})
 }
 
-func (c *client) Get(ctx context.Context, id string, typ metric.Type) (*metric.Metric, error) {
+func (c *client) History(ctx context.Context) (metric.Samples, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
+
+	samples := make(metric.Samples, 0)
+	if err := c.queryWithTx(ctx, ReadAllSamplesQuery, fetchSamples(&samples)); err != nil {
+		logger.Err(err).Msg("failed to query metrics history")
+		return nil, err
+	}
+
+	logger.Trace().Msgf("%d samples read", len(samples))
+	return samples, nil
+}
+
+func (c *client) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(query))
+
+	from, to := query.Range()
+	samples := make(metric.Samples, 0)
+	if err := c.queryWithTx(ctx, ReadRangeQuery,
+		fetchSamples(&samples, query.ID, string(query.Type), query.Labels.String(), from.UTC(), to.UTC())); err != nil {
+		logger.Err(err).Msg("failed to query metric history")
+		return nil, err
+	}
+	series := query.Apply(samples)
+
+	logger.Trace().Msgf("%d points read", len(series))
+	return series, nil
+}
+
+func (c *client) Append(ctx context.Context, samples metric.Samples) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
+
+	// restore may append whole history at once, so transaction timeout is granted per batch
+	timeout := c.timeout * time.Duration(1+len(samples)/batchSize)
+	return c.withTxTimeout(ctx, timeout, func(ctx context.Context, tx *sql.Tx) error {
+		if err := c.appendBatch(logging.SetLogger(ctx, logger), tx, samples); err != nil {
+			return err
+		}
+		logger.Trace().Msgf("%d samples appended", len(samples))
+		return nil
+	})
+}
+
+func (c *client) Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
 	var mtr *metric.Metric
 	fetch := func(ctx context.Context, tx *sql.Tx) (err error) {
-		mtr, err = c.read(logging.SetLogger(ctx, logger), tx, id, typ)
+		mtr, err = c.read(logging.SetLogger(ctx, logger), tx, id, typ, labels)
 		return
 	}
 	if err := c.withTx(ctx, fetch); err != nil {
@@ -154,7 +261,7 @@ func (c *client) Update(ctx context.Context, mtr *metric.Metric) error {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
 	return c.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		return c.process(logging.SetLogger(ctx, logger), tx, mtr)
+		return c.upsert(logging.SetLogger(ctx, logger), tx, metric.List{mtr}, time.Now())
 	})
 }
 
@@ -178,55 +285,20 @@ func (c *client) Close(ctx context.Context) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
+	if c.stopTrim != nil {
+		c.stopTrim()
+		<-c.trimDone
+	}
 	if err := c.db.Close(); err != nil {
 		logger.Err(err).Msg("failed to close db connection")
 	}
 	logger.Info().Msg("closed")
 }
 
-func (c *client) process(ctx context.Context, tx *sql.Tx, mtr *metric.Metric) error {
-	m, err := c.read(ctx, tx, mtr.ID, mtr.Type())
-	if err != nil {
-		return err
-	}
-	if m == nil {
-		return c.create(ctx, tx, mtr)
-	}
-	return c.update(ctx, tx, mtr)
-}
-
-func (c *client) create(ctx context.Context, tx *sql.Tx, mtr *metric.Metric) error {
-	_, logger := logging.GetOrCreateLogger(ctx)
-	logger.UpdateContext(logging.LogCtxFrom(mtr))
-
-	if err := mtr.Type().Validate(); err != nil {
-		logger.Err(err).Msgf("create failed")
-		return err
-	}
-
-	stmt, err := c.stmt(ctx, tx, CreateQuery)
-	if err != nil {
-		logger.Err(err).Msgf("create failed")
-		return err
-	}
-	v, d := toPrimitive(mtr)
-	if _, err := stmt.ExecContext(ctx,
-		mtr.ID,
-		string(mtr.Type()),
-		v,
-		d); err != nil {
-		logger.Err(err).Msgf("create failed")
-		return err
-	}
-
-	logger.Trace().Msg("created")
-	return nil
-}
-
-func (c *client) read(ctx context.Context, tx *sql.Tx, id string, typ metric.Type) (*metric.Metric, error) {
+func (c *client) read(ctx context.Context, tx *sql.Tx, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
 	_, logger := logging.GetOrCreateLogger(ctx)
 	logger.UpdateContext(logging.LogCtxKeyStr(logging.MetricIDKey, id))
-	logger.UpdateContext(logging.LogCtxFrom(typ))
+	logger.UpdateContext(logging.LogCtxFrom(typ, labels))
 
 	if err := typ.Validate(); err != nil {
 		logger.Err(err).Msgf("query failed")
@@ -239,11 +311,11 @@ func (c *client) read(ctx context.Context, tx *sql.Tx, id string, typ metric.Typ
 		return nil, err
 	}
 
-	var v float64
-	var d int64
+	m := &Metrics{ID: id, typ: string(typ), labels: labels.String()}
 	if err := stmt.QueryRowContext(ctx,
 		id,
-		string(typ)).Scan(&v, &d); err != nil {
+		string(typ),
+		m.labels).Scan(&m.value, &m.delta, &m.histogram); err != nil {
 		if err == sql.ErrNoRows {
 			logger.Trace().Msg("not found")
 			return nil, nil
@@ -252,14 +324,9 @@ func (c *client) read(ctx context.Context, tx *sql.Tx, id string, typ metric.Typ
 		return nil, err
 	}
 
-	var mtr *metric.Metric
-	switch typ {
-	case metric.GaugeType:
-		mtr = metric.NewGaugeMetric(id, metric.Gauge(v))
-	case metric.CounterType:
-		mtr = metric.NewCounterMetric(id, metric.Counter(d))
-	default:
-		err := fmt.Errorf("unknown metric %v", typ)
+	mtr := m.ToCanonical()
+	if mtr == nil {
+		err := fmt.Errorf("unable to convert %v record to canonical metric", typ)
 		logger.Err(err).Msgf("query failed")
 		return nil, err
 	}
@@ -269,50 +336,40 @@ func (c *client) read(ctx context.Context, tx *sql.Tx, id string, typ metric.Typ
 	return mtr, nil
 }
 
-func (c *client) update(ctx context.Context, tx *sql.Tx, mtr *metric.Metric) (err error) {
-	_, logger := logging.GetOrCreateLogger(ctx)
-	logger.UpdateContext(logging.LogCtxFrom(mtr))
-
-	if err = mtr.Type().Validate(); err != nil {
-		logger.Err(err).Msgf("update failed")
-		return
+// startTrim runs periodic removal of samples which are out of retention period until storage is closed. Trimming is
+// kept out of write path as it scans for the latest sample of every metric.
+func (c *client) startTrim(ctx context.Context) {
+	interval := c.retention
+	if interval > maxTrimInterval {
+		interval = maxTrimInterval
 	}
+	ctx, c.stopTrim = context.WithCancel(ctx)
+	c.trimDone = make(chan struct{})
+	go func() {
+		defer close(c.trimDone)
+		task.ErrTask(c.trim).Task().With(task.PeriodicRun(interval))(ctx)
+	}()
+}
 
-	var stmt *sql.Stmt
-	v, d := toPrimitive(mtr)
-	switch mtr.Type() {
-	case metric.GaugeType:
-		if stmt, err = c.stmt(ctx, tx, UpdateGaugeQuery); err == nil {
-			_, err = stmt.ExecContext(ctx,
-				mtr.ID,
-				string(mtr.Type()),
-				v)
-		}
-	case metric.CounterType:
-		if stmt, err = c.stmt(ctx, tx, UpdateCounterQuery); err == nil {
-			_, err = stmt.ExecContext(ctx,
-				mtr.ID,
-				string(mtr.Type()),
-				d)
-		}
-	default:
-		err = fmt.Errorf("unknown metric %v", mtr.Type())
-	}
-	if err != nil {
-		logger.Err(err).Msg("update failed")
-		return
+// trim drops samples which are out of retention period. The latest sample of every metric is kept.
+func (c *client) trim(ctx context.Context) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	expired := time.Now().Add(-c.retention).UTC()
+	if err := c.queryWithTx(ctx, TrimSamplesQuery, exec(expired)); err != nil {
+		logger.Err(err).Msgf("trim failed")
+		return err
 	}
-	logger.Trace().Msg("updated")
-	return
+	return nil
 }
 
-func prepareStmts(ctx context.Context, db *sql.DB, queries ...Query) (map[Query]*sql.Stmt, error) {
+func prepareStmts(ctx context.Context, db *sql.DB, driver Driver, queries ...Query) (map[Query]*sql.Stmt, error) {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName))
 
 	statements := make(map[Query]*sql.Stmt)
 	var err error
 	for _, query := range queries {
-		s := string(query)
+		s := driver.dialect(query)
 		var stmt *sql.Stmt
 		if stmt, err = db.PrepareContext(ctx, s); err != nil {
 			logger.Err(err).Msgf("failed to prepare statement: %s", s)
@@ -334,9 +391,13 @@ func (c *client) stmt(ctx context.Context, tx *sql.Tx, query Query) (*sql.Stmt,
 }
 
 func (c *client) withTx(ctx context.Context, underTx func(ctx context.Context, tx *sql.Tx) error) error {
+	return c.withTxTimeout(ctx, c.timeout, underTx)
+}
+
+func (c *client) withTxTimeout(ctx context.Context, timeout time.Duration, underTx func(ctx context.Context, tx *sql.Tx) error) error {
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
 
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	tx, err := c.db.BeginTx(ctx, nil)
@@ -391,6 +452,21 @@ func fetch(fetch func(ctx context.Context, rows *sql.Rows) error, args ...interf
 	}
 }
 
+func fetchSamples(samples *metric.Samples, args ...interface{}) func(ctx context.Context, stmt *sql.Stmt) error {
+	return fetch(func(_ context.Context, rows *sql.Rows) error {
+		m := &Metrics{}
+		if err := rows.Scan(&m.ID, &m.typ, &m.labels, &m.value, &m.delta, &m.histogram, &m.timestamp); err != nil {
+			return err
+		}
+		smp := m.ToSample()
+		if smp == nil {
+			return errors.New("unable to convert row to canonical sample")
+		}
+		*samples = append(*samples, smp)
+		return nil
+	}, args...)
+}
+
 func exec(args ...interface{}) func(ctx context.Context, stmt *sql.Stmt) error {
 	return func(ctx context.Context, stmt *sql.Stmt) error {
 		_, err := stmt.ExecContext(ctx, args...)
@@ -398,13 +474,16 @@ func exec(args ...interface{}) func(ctx context.Context, stmt *sql.Stmt) error {
 	}
 }
 
+// New returns factory of SQL DB storage. Factory produces nil if database is not configured or driver does not accept
+// configured data source.
 func New(driver Driver) storage.Factory {
 	return func(cfg *config.Config) storage.Storage {
-		if len(cfg.Database) == 0 {
+		if len(cfg.Database) == 0 || !driver.accepts(cfg.Database) {
 			return nil
 		}
 		return &client{
 			timeout:    defaultTimeout,
+			retention:  cfg.Retention,
 			dataSource: cfg.Database,
 			Driver:     driver,
 		}
@@ -0,0 +1,229 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// LatestVersion is a target version of Migrate which applies all known migrations.
+const LatestVersion = -1
+
+const (
+	CreateMigrationsQuery Query = "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version integer PRIMARY KEY," +
+		"name varchar(255) NOT NULL," +
+		"applied_at timestamp NOT NULL" +
+		")"
+	ReadMigrationsQuery  Query = "SELECT version FROM schema_migrations ORDER BY version"
+	CreateMigrationQuery Query = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1,$2,$3)"
+	DeleteMigrationQuery Query = "DELETE FROM schema_migrations WHERE version=$1"
+)
+
+// migrationFile matches migration script name, e.g. 0001_init.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrNoDriver = errors.New("no sql driver accepts data source")
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// Migrate migrates schema of database configured by cfg.Database to specified version. Version 0 reverts all
+// migrations, LatestVersion applies all of them. Database is served by the first driver that accepts its data source.
+func Migrate(ctx context.Context, cfg *config.Config, version int, drivers ...Driver) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName), logging.WithCID(ctx))
+
+	for _, driver := range drivers {
+		if !driver.accepts(cfg.Database) {
+			continue
+		}
+		db, err := driver.open(cfg.Database)
+		if err != nil {
+			logger.Err(err).Msg("failed to open db")
+			return err
+		}
+		//goland:noinspection GoUnhandledErrorResult
+		defer db.Close()
+		return migrate(logging.SetLogger(ctx, logger), db, driver, version)
+	}
+	return ErrNoDriver
+}
+
+// migrate applies or reverts migrations in a single transaction. Driver's lock prevents concurrent servers from
+// migrating the same database simultaneously.
+func migrate(ctx context.Context, db *sql.DB, driver Driver, version int) error {
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(dbStorageName))
+
+	migrations, err := loadMigrations(driver.migrations())
+	if err != nil {
+		logger.Err(err).Msg("failed to load migrations")
+		return err
+	}
+	known := 0
+	if len(migrations) != 0 {
+		known = migrations[len(migrations)-1].version
+	}
+	if version == LatestVersion {
+		version = known
+	}
+	if version < 0 || version > known {
+		err = fmt.Errorf("unknown schema version %d: latest is %d", version, known)
+		logger.Err(err).Msg("failed to migrate")
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Err(err).Msg("failed to open transaction")
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer tx.Rollback()
+
+	if err = driver.lock(ctx, tx); err != nil {
+		logger.Err(err).Msg("failed to acquire migrations lock")
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, driver.dialect(CreateMigrationsQuery)); err != nil {
+		logger.Err(err).Msg("failed to create migrations table")
+		return err
+	}
+	applied, err := appliedMigrations(ctx, tx, driver)
+	if err != nil {
+		logger.Err(err).Msg("failed to query applied migrations")
+		return err
+	}
+	if current := len(applied); current != 0 && applied[current-1] > known {
+		err = fmt.Errorf("schema version %d is newer than latest known %d", applied[current-1], known)
+		logger.Err(err).Msg("failed to migrate")
+		return err
+	}
+
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if m.version > version || done[m.version] {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, m.up); err != nil {
+			logger.Err(err).Msgf("failed to apply migration %04d_%s", m.version, m.name)
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, driver.dialect(CreateMigrationQuery), m.version, m.name, time.Now().UTC()); err != nil {
+			logger.Err(err).Msg("failed to register migration")
+			return err
+		}
+		logger.Info().Msgf("migration %04d_%s applied", m.version, m.name)
+		count++
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= version || !done[m.version] {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, m.down); err != nil {
+			logger.Err(err).Msgf("failed to revert migration %04d_%s", m.version, m.name)
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, driver.dialect(DeleteMigrationQuery), m.version); err != nil {
+			logger.Err(err).Msg("failed to unregister migration")
+			return err
+		}
+		logger.Info().Msgf("migration %04d_%s reverted", m.version, m.name)
+		count++
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Err(err).Msg("failed to commit transaction")
+		return err
+	}
+	logger.Info().Msgf("schema is at version %d: %d migrations processed", version, count)
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, tx *sql.Tx, driver Driver) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, driver.dialect(ReadMigrationsQuery))
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	applied := make([]int, 0)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied = append(applied, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// loadMigrations reads migration scripts ordered by version. Every migration should have both up and down scripts.
+func loadMigrations(fsys fs.FS) ([]*migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file: %s", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		} else if m.name != match[2] {
+			return nil, fmt.Errorf("migration version %d is ambiguous: %s and %s", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(m.up) == 0 || len(m.down) == 0 {
+			return nil, fmt.Errorf("migration %04d_%s should have both up and down scripts", m.version, m.name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
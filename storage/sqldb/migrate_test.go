@@ -0,0 +1,176 @@
+package sqldb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{Database: SQLiteScheme + filepath.Join(t.TempDir(), "monitor.db")}
+
+	db, err := SQLite{}.open(cfg.Database)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// database prepared before migrations were introduced
+	_, err = db.ExecContext(ctx, "CREATE TABLE metrics (metric_id varchar(255) NOT NULL, metric_type varchar(255) NOT NULL, "+
+		"labels text NOT NULL DEFAULT '', value double precision, delta int8, histogram text)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO metrics (metric_id, metric_type, value) VALUES ('foo','gauge',1),('foo','gauge',2)")
+	require.NoError(t, err)
+
+	versions := func() []int {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		applied, err := appliedMigrations(ctx, tx, SQLite{})
+		require.NoError(t, err)
+		return applied
+	}
+	tables := func() []string {
+		rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type='table' ORDER BY name")
+		require.NoError(t, err)
+		defer rows.Close()
+		names := make([]string, 0)
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	t.Run("Existing database", func(t *testing.T) {
+		require.NoError(t, migrate(ctx, db, SQLite{}, LatestVersion))
+		assert.Equal(t, []int{1, 2, 3}, versions())
+
+		var value float64
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT value FROM metrics").Scan(&value))
+		assert.Equal(t, float64(2), value, "duplicates collapsed to latest row")
+
+		_, err := db.ExecContext(ctx, "INSERT INTO metrics (metric_id, metric_type, value) VALUES ('foo','gauge',3)")
+		assert.Error(t, err, "metric key is unique")
+	})
+
+	t.Run("Up to date", func(t *testing.T) {
+		require.NoError(t, migrate(ctx, db, SQLite{}, LatestVersion))
+		assert.Equal(t, []int{1, 2, 3}, versions())
+	})
+
+	t.Run("Revert", func(t *testing.T) {
+		require.NoError(t, Migrate(ctx, cfg, 1, SQLite{}, PGX{}))
+		assert.Equal(t, []int{1}, versions())
+		_, err := db.ExecContext(ctx, "INSERT INTO metrics (metric_id, metric_type, value) VALUES ('foo','gauge',3)")
+		assert.NoError(t, err)
+
+		require.NoError(t, Migrate(ctx, cfg, 0, SQLite{}))
+		assert.Empty(t, versions())
+		assert.Equal(t, []string{"schema_migrations"}, tables())
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		assert.Error(t, migrate(ctx, db, SQLite{}, 4))
+		assert.Empty(t, versions())
+	})
+
+	t.Run("Newer schema", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (4,'future',CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+		assert.Error(t, migrate(ctx, db, SQLite{}, LatestVersion))
+		assert.Equal(t, []string{"schema_migrations"}, tables(), "nothing applied")
+	})
+
+	t.Run("No driver", func(t *testing.T) {
+		assert.ErrorIs(t, Migrate(ctx, cfg, LatestVersion), ErrNoDriver)
+	})
+}
+
+func Test_loadMigrations(t *testing.T) {
+	script := &fstest.MapFile{Data: []byte("SELECT 1")}
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []int
+		wantErr bool
+	}{
+		{
+			name: "Ordered by version",
+			fsys: fstest.MapFS{
+				"0010_baz.up.sql":   script,
+				"0010_baz.down.sql": script,
+				"0002_bar.up.sql":   script,
+				"0002_bar.down.sql": script,
+				"0001_foo.up.sql":   script,
+				"0001_foo.down.sql": script,
+			},
+			want: []int{1, 2, 10},
+		},
+		{
+			name: "Empty",
+			fsys: fstest.MapFS{},
+			want: []int{},
+		},
+		{
+			name:    "Missing down script",
+			fsys:    fstest.MapFS{"0001_foo.up.sql": script},
+			wantErr: true,
+		},
+		{
+			name: "Ambiguous version",
+			fsys: fstest.MapFS{
+				"0001_foo.up.sql":   script,
+				"0001_bar.down.sql": script,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Unexpected file",
+			fsys:    fstest.MapFS{"README.md": script},
+			wantErr: true,
+		},
+		{
+			name: "Zero version",
+			fsys: fstest.MapFS{
+				"0000_foo.up.sql":   script,
+				"0000_foo.down.sql": script,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.fsys)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				versions := make([]int, 0, len(migrations))
+				for _, m := range migrations {
+					versions = append(versions, m.version)
+				}
+				assert.Equal(t, tt.want, versions)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for name, driver := range map[string]Driver{"PGX": PGX{}, "SQLite": SQLite{}} {
+		t.Run(name, func(t *testing.T) {
+			migrations, err := loadMigrations(driver.migrations())
+			require.NoError(t, err)
+			assert.NotEmpty(t, migrations)
+		})
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS metric_samples;
+DROP TABLE IF EXISTS metrics;
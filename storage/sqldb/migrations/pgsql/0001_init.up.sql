@@ -0,0 +1,23 @@
+-- tables could have been created before migrations were introduced, so statements are idempotent
+CREATE TABLE IF NOT EXISTS metrics (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    value double precision,
+    delta int8
+);
+
+CREATE TABLE IF NOT EXISTS metric_samples (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    value double precision,
+    delta int8,
+    ts timestamptz NOT NULL
+);
+
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS histogram text;
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS labels text NOT NULL DEFAULT '';
+ALTER TABLE metric_samples ADD COLUMN IF NOT EXISTS histogram text;
+ALTER TABLE metric_samples ADD COLUMN IF NOT EXISTS labels text NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS metric_samples_idx;
+CREATE INDEX IF NOT EXISTS metric_samples_labels_idx ON metric_samples (metric_id, metric_type, labels, ts);
@@ -0,0 +1 @@
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
@@ -0,0 +1,6 @@
+-- concurrent reports could have duplicated metrics before the key existed, the latest written row is kept
+DELETE FROM metrics a USING metrics b
+WHERE a.ctid < b.ctid AND a.metric_id = b.metric_id AND a.metric_type = b.metric_type AND a.labels = b.labels;
+
+-- key index also serves lookups by (metric_id, metric_type) as its prefix
+ALTER TABLE metrics ADD CONSTRAINT metrics_pkey PRIMARY KEY (metric_id, metric_type, labels);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS metrics (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    labels text NOT NULL DEFAULT '',
+    value double precision,
+    delta int8,
+    histogram text
+);
+
+CREATE TABLE IF NOT EXISTS metric_samples (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    labels text NOT NULL DEFAULT '',
+    value double precision,
+    delta int8,
+    histogram text,
+    ts timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS metric_samples_labels_idx ON metric_samples (metric_id, metric_type, labels, ts);
@@ -0,0 +1,14 @@
+CREATE TABLE metrics_old (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    labels text NOT NULL DEFAULT '',
+    value double precision,
+    delta int8,
+    histogram text
+);
+
+INSERT INTO metrics_old (metric_id, metric_type, labels, value, delta, histogram)
+SELECT metric_id, metric_type, labels, value, delta, histogram FROM metrics;
+
+DROP TABLE metrics;
+ALTER TABLE metrics_old RENAME TO metrics;
@@ -0,0 +1,17 @@
+-- SQLite can't add primary key to existing table, so the table is rebuilt. Duplicated metrics are collapsed to the
+-- latest written row. Key index also serves lookups by (metric_id, metric_type) as its prefix.
+CREATE TABLE metrics_new (
+    metric_id varchar(255) NOT NULL,
+    metric_type varchar(255) NOT NULL,
+    labels text NOT NULL DEFAULT '',
+    value double precision,
+    delta int8,
+    histogram text,
+    PRIMARY KEY (metric_id, metric_type, labels)
+);
+
+INSERT OR REPLACE INTO metrics_new (metric_id, metric_type, labels, value, delta, histogram)
+SELECT metric_id, metric_type, labels, value, delta, histogram FROM metrics ORDER BY rowid;
+
+DROP TABLE metrics;
+ALTER TABLE metrics_new RENAME TO metrics;
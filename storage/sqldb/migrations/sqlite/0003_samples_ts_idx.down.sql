@@ -0,0 +1 @@
+DROP INDEX IF EXISTS metric_samples_ts_idx;
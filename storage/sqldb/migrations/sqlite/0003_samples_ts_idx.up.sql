@@ -0,0 +1,3 @@
+-- expired samples are trimmed by timestamp across all metrics, lookups of metric samples are served by
+-- metric_samples_labels_idx
+CREATE INDEX IF NOT EXISTS metric_samples_ts_idx ON metric_samples (ts);
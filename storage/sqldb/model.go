@@ -1,27 +1,51 @@
 package sqldb
 
 import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 )
 
 type Metrics struct {
-	ID    string
-	typ   string
-	value float64
-	delta int64
+	ID        string
+	typ       string
+	labels    string
+	value     float64
+	delta     int64
+	histogram sql.NullString
+	timestamp time.Time
 }
 
 func (m Metrics) ToCanonical() *metric.Metric {
+	labels, err := metric.ParseLabels(m.labels)
+	if err != nil {
+		return nil
+	}
 	switch metric.Type(m.typ) {
 	case metric.GaugeType:
-		return metric.NewGaugeMetric(m.ID, metric.Gauge(m.value))
+		return metric.NewGaugeMetric(m.ID, metric.Gauge(m.value)).WithLabels(labels)
 	case metric.CounterType:
-		return metric.NewCounterMetric(m.ID, metric.Counter(m.delta))
+		return metric.NewCounterMetric(m.ID, metric.Counter(m.delta)).WithLabels(labels)
+	case metric.HistogramType:
+		hist := metric.Histogram{}
+		if !m.histogram.Valid || json.Unmarshal([]byte(m.histogram.String), &hist) != nil || hist.Validate() != nil {
+			return nil
+		}
+		return metric.NewHistogramMetric(m.ID, hist).WithLabels(labels)
+	}
+	return nil
+}
+
+func (m Metrics) ToSample() *metric.Sample {
+	if mtr := m.ToCanonical(); mtr != nil {
+		return metric.NewSample(mtr, m.timestamp)
 	}
 	return nil
 }
 
-func toPrimitive(value metric.Value) (v float64, d int64) {
+func toPrimitive(value metric.Value) (v float64, d int64, h sql.NullString) {
 	if m, ok := value.(*metric.Metric); ok {
 		value = m.Value
 	}
@@ -30,6 +54,10 @@ func toPrimitive(value metric.Value) (v float64, d int64) {
 		v = float64(*value.(*metric.Gauge))
 	case metric.CounterType:
 		d = int64(*value.(*metric.Counter))
+	case metric.HistogramType:
+		if data, err := json.Marshal(value); err == nil {
+			h = sql.NullString{String: string(data), Valid: true}
+		}
 	}
 	return
 }
@@ -0,0 +1,97 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+// Per metric read-modify-write path superseded by upsert. It is kept as a baseline for BenchmarkUpdateBulk.
+const (
+	perMetricCreateQuery Query = "INSERT INTO metrics (metric_id, metric_type, labels, value, delta, histogram) " +
+		"VALUES ($1,$2,$3,$4,$5,$6)"
+	perMetricUpdateGaugeQuery     Query = "UPDATE metrics SET value=$4 WHERE metric_id=$1 AND metric_type=$2 AND labels=$3"
+	perMetricUpdateCounterQuery   Query = "UPDATE metrics SET delta=delta+$4 WHERE metric_id=$1 AND metric_type=$2 AND labels=$3"
+	perMetricUpdateHistogramQuery Query = "UPDATE metrics SET histogram=$4 WHERE metric_id=$1 AND metric_type=$2 AND labels=$3"
+	perMetricCreateSampleQuery    Query = "INSERT INTO metric_samples (metric_id, metric_type, labels, value, delta, histogram, ts) " +
+		"VALUES ($1,$2,$3,$4,$5,$6,$7)"
+)
+
+// processPerMetric reads stored metric, then creates or updates it and records resulting value as sample.
+func (c *client) processPerMetric(ctx context.Context, tx *sql.Tx, mtr *metric.Metric, timestamp time.Time) error {
+	stored, err := c.read(ctx, tx, mtr.ID, mtr.Type(), mtr.Labels)
+	if err != nil {
+		return err
+	}
+
+	key := []interface{}{mtr.ID, string(mtr.Type()), mtr.Labels.String()}
+	v, d, h := toPrimitive(mtr)
+	actual := mtr
+	if stored == nil {
+		_, err = tx.ExecContext(ctx, c.dialect(perMetricCreateQuery), append(key, v, d, h)...)
+	} else {
+		switch mtr.Type() {
+		case metric.GaugeType:
+			_, err = tx.ExecContext(ctx, c.dialect(perMetricUpdateGaugeQuery), append(key, v)...)
+		case metric.CounterType:
+			actual = metric.NewCounterMetric(mtr.ID, *stored.Value.(*metric.Counter)+*mtr.Value.(*metric.Counter)).
+				WithLabels(mtr.Labels)
+			_, err = tx.ExecContext(ctx, c.dialect(perMetricUpdateCounterQuery), append(key, d)...)
+		case metric.HistogramType:
+			hist := mtr.Value.(*metric.Histogram).Copy()
+			if err = hist.Merge(stored.Value.(*metric.Histogram)); err != nil {
+				return err
+			}
+			actual = metric.NewHistogramMetric(mtr.ID, hist).WithLabels(mtr.Labels)
+			_, _, h = toPrimitive(actual)
+			_, err = tx.ExecContext(ctx, c.dialect(perMetricUpdateHistogramQuery), append(key, h)...)
+		default:
+			err = fmt.Errorf("unknown metric %v", mtr.Type())
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	v, d, h = toPrimitive(actual)
+	_, err = tx.ExecContext(ctx, c.dialect(perMetricCreateSampleQuery), append(key, v, d, h, timestamp.UTC())...)
+	return err
+}
+
+func TestProcessPerMetric(t *testing.T) {
+	ctx := context.Background()
+	hist := metric.NewHistogram(1, 10)
+	hist.Observe(5)
+	list := metric.List{
+		metric.NewGaugeMetric("Alloc", 1),
+		metric.NewCounterMetric("PollCount", 2),
+		metric.NewHistogramMetric("Latency", hist),
+	}
+
+	perMetric, upserted := newSQLiteClient(t), newSQLiteClient(t)
+	for i := 0; i < 2; i++ {
+		now := time.Now()
+		require.NoError(t, perMetric.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			for _, mtr := range list {
+				if err := perMetric.processPerMetric(ctx, tx, mtr, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		require.NoError(t, upserted.UpdateBulk(ctx, list))
+	}
+
+	want, err := upserted.GetAll(ctx, nil)
+	require.NoError(t, err)
+	got, err := perMetric.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, got, "baseline path yields the same values as upsert")
+}
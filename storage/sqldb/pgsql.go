@@ -3,25 +3,41 @@ package sqldb
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"io/fs"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
+// migrationsLockID is a key of PostgreSQL advisory lock held while schema is being migrated.
+const migrationsLockID = 0x6d6f6e69746f72
+
 var _ Driver = (*PGX)(nil)
 
+//go:embed migrations/pgsql/*.sql
+var pgsqlMigrations embed.FS
+
+// PGX is PostgreSQL driver. It accepts any data source, so it should be the last driver tried.
 type PGX struct{}
 
+func (P PGX) accepts(string) bool {
+	return true
+}
+
 func (P PGX) open(dataSource string) (*sql.DB, error) {
 	return sql.Open("pgx", dataSource)
 }
 
-func (P PGX) prepare(ctx context.Context, db *sql.DB) (err error) {
-	_, err = db.ExecContext(ctx,
-		`CREATE TABLE IF NOT EXISTS metrics (`+
-			`metric_id varchar(255) NOT NULL,`+
-			`metric_type varchar(255) NOT NULL,`+
-			`value double precision,`+
-			`delta int8`+
-			`);`)
-	return
+func (P PGX) dialect(query Query) string {
+	return string(query)
+}
+
+func (P PGX) migrations() fs.FS {
+	sub, _ := fs.Sub(pgsqlMigrations, "migrations/pgsql")
+	return sub
+}
+
+func (P PGX) lock(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationsLockID)
+	return err
 }
@@ -0,0 +1,101 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteScheme is data source prefix which selects SQLite driver, e.g. sqlite:///var/lib/monitor.db
+const SQLiteScheme = "sqlite://"
+
+// sqliteOptions makes transactions take write lock at start and wait for it if database file is used by another process.
+// Timestamps are written in SQLite format, so they are compared and parsed back as such.
+const sqliteOptions = "_txlock=immediate&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+var _ Driver = (*SQLite)(nil)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLite is embedded SQLite database driver. It is pure Go, so cgo is not required.
+type SQLite struct{}
+
+func (S SQLite) accepts(dataSource string) bool {
+	return strings.HasPrefix(dataSource, SQLiteScheme)
+}
+
+func (S SQLite) open(dataSource string) (*sql.DB, error) {
+	dataSource = strings.TrimPrefix(dataSource, SQLiteScheme)
+	if strings.ContainsRune(dataSource, '?') {
+		dataSource += "&" + sqliteOptions
+	} else {
+		dataSource += "?" + sqliteOptions
+	}
+	db, err := sql.Open("sqlite", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows single writer only, concurrent transactions would fail with database locked error
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func (S SQLite) migrations() fs.FS {
+	sub, _ := fs.Sub(sqliteMigrations, "migrations/sqlite")
+	return sub
+}
+
+// lock is no-op as transactions are opened in immediate mode which acquires database write lock at start.
+func (S SQLite) lock(context.Context, *sql.Tx) error {
+	return nil
+}
+
+// dialect replaces $N placeholders with ?N ones as SQLite treats $N as named parameter numbered in order of appearance.
+// Placeholders are replaced with anonymous ? if parameters appear in order, numbered ones are slow to compile in large
+// multi-row statements. String literals and quoted identifiers are kept intact.
+func (S SQLite) dialect(query Query) string {
+	s := string(query)
+	var numbered, anonymous strings.Builder
+	numbered.Grow(len(s))
+	anonymous.Grow(len(s))
+	ordered := true
+	var quote byte
+	for i, n := 0, 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+			numbered.WriteByte(s[i])
+			anonymous.WriteByte(s[i])
+			continue
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		}
+		j := i + 1
+		for s[i] == '$' && j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			numbered.WriteByte(s[i])
+			anonymous.WriteByte(s[i])
+			continue
+		}
+		n++
+		ordered = ordered && s[i+1:j] == strconv.Itoa(n)
+		numbered.WriteByte('?')
+		numbered.WriteString(s[i+1 : j])
+		anonymous.WriteByte('?')
+		i = j - 1
+	}
+	if ordered {
+		return anonymous.String()
+	}
+	return numbered.String()
+}
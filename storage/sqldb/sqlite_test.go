@@ -0,0 +1,180 @@
+package sqldb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
+)
+
+func TestSQLite_dialect(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  string
+	}{
+		{
+			name:  "Positional parameters",
+			query: "UPDATE metrics SET value=$4 WHERE metric_id=$1 AND metric_type=$2 AND labels=$3",
+			want:  "UPDATE metrics SET value=?4 WHERE metric_id=?1 AND metric_type=?2 AND labels=?3",
+		},
+		{
+			name:  "Ordered parameters",
+			query: CreateSamplesQuery(2),
+			want: "INSERT INTO metric_samples (metric_id, metric_type, labels, value, delta, histogram, ts) VALUES " +
+				"(?,?,?,?,?,?,?),(?,?,?,?,?,?,?)",
+		},
+		{
+			name:  "Quoted parameters",
+			query: `UPDATE metrics SET labels='$1 it''s $2' WHERE metric_id=$1 AND "$2"=$2`,
+			want:  `UPDATE metrics SET labels='$1 it''s $2' WHERE metric_id=? AND "$2"=?`,
+		},
+		{
+			name:  "No parameters",
+			query: DeleteAllQuery,
+			want:  "DELETE FROM metrics",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SQLite{}.dialect(tt.query))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     Driver
+		dataSource string
+		want       bool
+	}{
+		{
+			name:       "SQLite",
+			driver:     SQLite{},
+			dataSource: "sqlite:///var/lib/monitor.db",
+			want:       true,
+		},
+		{
+			name:       "SQLite rejects PostgreSQL data source",
+			driver:     SQLite{},
+			dataSource: "postgres://localhost:5432/monitor",
+		},
+		{
+			name:       "PostgreSQL",
+			driver:     PGX{},
+			dataSource: "postgres://localhost:5432/monitor",
+			want:       true,
+		},
+		{
+			name:   "Database is not configured",
+			driver: PGX{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := New(tt.driver)(&config.Config{Database: tt.dataSource})
+			assert.Equal(t, tt.want, st != nil)
+		})
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	ctx := context.Background()
+	dataSource := SQLiteScheme + filepath.Join(t.TempDir(), "monitor.db")
+
+	st := New(SQLite{})(&config.Config{Database: dataSource})
+	require.NoError(t, st.Init(ctx))
+	defer st.Close(ctx)
+	require.NoError(t, st.Ping(ctx))
+
+	labels := metric.Labels{"host": "foo"}
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{
+		metric.NewGaugeMetric("Alloc", 1.5).WithLabels(labels),
+		metric.NewCounterMetric("PollCount", 1),
+	}))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 2)))
+	require.NoError(t, st.Update(ctx, metric.NewGaugeMetric("Alloc", 2.5).WithLabels(labels)))
+
+	got, err := st.Get(ctx, "PollCount", metric.CounterType, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.NewCounterMetric("PollCount", 3), got)
+
+	got, err = st.Get(ctx, "Alloc", metric.GaugeType, nil)
+	require.NoError(t, err)
+	assert.Nil(t, got, "metrics of different labels are distinct")
+
+	list, err := st.GetAll(ctx, labels)
+	require.NoError(t, err)
+	assert.Equal(t, metric.List{metric.NewGaugeMetric("Alloc", 2.5).WithLabels(labels)}, list)
+
+	series, err := st.Query(ctx, &metric.Query{ID: "Alloc", Type: metric.GaugeType, Labels: labels, From: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, series, 2)
+
+	t.Run("Append", func(t *testing.T) {
+		ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+		require.NoError(t, st.Append(ctx, metric.Samples{metric.NewSample(metric.NewGaugeMetric("Alloc", 0.5).WithLabels(labels), ts)}))
+
+		got, err := st.Get(ctx, "Alloc", metric.GaugeType, labels)
+		require.NoError(t, err)
+		assert.Equal(t, metric.NewGaugeMetric("Alloc", 2.5).WithLabels(labels), got, "outdated sample doesn't change value")
+
+		series, err := st.Query(ctx, &metric.Query{ID: "Alloc", Type: metric.GaugeType, Labels: labels, To: ts})
+		require.NoError(t, err)
+		if assert.Len(t, series, 1) {
+			assert.True(t, ts.Equal(series[0].Timestamp))
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		require.NoError(t, st.Clear(ctx))
+		list, err := st.GetAll(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+func TestTrim(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{Database: SQLiteScheme + filepath.Join(t.TempDir(), "monitor.db"), Retention: time.Hour}
+	c := New(SQLite{})(cfg).(*client)
+	require.NoError(t, c.Init(ctx))
+	defer c.Close(ctx)
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, c.Append(ctx, metric.Samples{
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 1), now.Add(-3*time.Hour)),
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 2), now.Add(-2*time.Hour)),
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 3), now),
+		metric.NewSample(metric.NewGaugeMetric("Sys", 1), now.Add(-3*time.Hour)),
+		metric.NewSample(metric.NewGaugeMetric("Sys", 2), now.Add(-2*time.Hour)),
+	}))
+	require.NoError(t, c.trim(ctx))
+
+	samples, err := c.History(ctx)
+	require.NoError(t, err)
+	values := make(map[string][]float64)
+	for _, smp := range samples {
+		values[smp.ID] = append(values[smp.ID], float64(*smp.Value.(*metric.Gauge)))
+	}
+	assert.Equal(t, map[string][]float64{"Alloc": {3}, "Sys": {2}}, values, "the latest sample is kept regardless of retention")
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return New(SQLite{})(&config.Config{Database: SQLiteScheme + filepath.Join(t.TempDir(), "monitor.db")})
+		},
+	}.Run(t)
+}
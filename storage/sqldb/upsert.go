@@ -0,0 +1,383 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/pkg/logging"
+)
+
+// batchSize is a number of rows written by single statement. It keeps statement parameters count far below limits of
+// supported db servers.
+const batchSize = 500
+
+// upsert registers or updates metrics with multi-row statements relying on metrics primary key. Resulting metric
+// values are recorded as samples.
+func (c *client) upsert(ctx context.Context, tx *sql.Tx, list metric.List, timestamp time.Time) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	merged, err := c.merge(ctx, tx, list)
+	if err != nil {
+		logger.Err(err).Msg("upsert failed")
+		return err
+	}
+
+	for start := 0; start < len(merged); start += batchSize {
+		end := start + batchSize
+		if end > len(merged) {
+			end = len(merged)
+		}
+		batch := merged[start:end]
+
+		args := make([]interface{}, 0, len(batch)*6)
+		for _, mtr := range batch {
+			v, d, h := toPrimitive(mtr)
+			args = append(args, mtr.ID, string(mtr.Type()), mtr.Labels.String(), v, d, h)
+		}
+		samples, err := c.upsertBatch(ctx, tx, args, len(batch), timestamp)
+		if err != nil {
+			logger.Err(err).Msg("upsert failed")
+			return err
+		}
+		if err = c.recordBatch(ctx, tx, samples); err != nil {
+			return err
+		}
+	}
+
+	logger.Trace().Msgf("%d metrics upserted", len(merged))
+	return nil
+}
+
+// upsertBatch executes single upsert statement and returns resulting metrics values as samples.
+func (c *client) upsertBatch(ctx context.Context, tx *sql.Tx, args []interface{}, rows int, timestamp time.Time) (metric.Samples, error) {
+	result, err := tx.QueryContext(ctx, c.dialect(UpsertQuery(rows)), args...)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer result.Close()
+
+	samples := make(metric.Samples, 0, rows)
+	for result.Next() {
+		m := &Metrics{timestamp: timestamp}
+		if err := result.Scan(&m.ID, &m.typ, &m.labels, &m.value, &m.delta, &m.histogram); err != nil {
+			return nil, err
+		}
+		smp := m.ToSample()
+		if smp == nil {
+			return nil, errors.New("unable to convert row to canonical sample")
+		}
+		samples = append(samples, smp)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// merge validates metrics and collapses duplicates, so that every metric is affected once per statement. Histograms
+// are merged with stored ones as db server is unable to do it.
+func (c *client) merge(ctx context.Context, tx *sql.Tx, list metric.List) (metric.List, error) {
+	merged := make(metric.List, 0, len(list))
+	index := make(map[string]int, len(list))
+	for _, mtr := range list {
+		if err := mtr.Type().Validate(); err != nil {
+			return nil, err
+		}
+		if err := mtr.Labels.Validate(); err != nil {
+			return nil, err
+		}
+
+		key := string(mtr.Type()) + "/" + mtr.Key()
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, mtr)
+			continue
+		}
+		switch mtr.Type() {
+		case metric.CounterType:
+			merged[i] = metric.NewCounterMetric(mtr.ID, *merged[i].Value.(*metric.Counter)+*mtr.Value.(*metric.Counter)).
+				WithLabels(mtr.Labels)
+		case metric.HistogramType:
+			hist := merged[i].Value.(*metric.Histogram).Copy()
+			if err := hist.Merge(mtr.Value.(*metric.Histogram)); err != nil {
+				return nil, err
+			}
+			merged[i] = metric.NewHistogramMetric(mtr.ID, hist).WithLabels(mtr.Labels)
+		default:
+			merged[i] = mtr
+		}
+	}
+
+	if err := c.mergeStored(ctx, tx, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// mergeStored merges histograms with stored ones. Histogram rows are locked first, so that concurrent transactions
+// merging the same histogram wait for each other instead of overwriting each other's observations. Rows are locked in
+// key order to avoid deadlocks.
+func (c *client) mergeStored(ctx context.Context, tx *sql.Tx, list metric.List) error {
+	hists := make([]int, 0)
+	for i, mtr := range list {
+		if mtr.Type() == metric.HistogramType {
+			hists = append(hists, i)
+		}
+	}
+	sort.Slice(hists, func(i, j int) bool { return list[hists[i]].Key() < list[hists[j]].Key() })
+
+	for start := 0; start < len(hists); start += batchSize {
+		end := start + batchSize
+		if end > len(hists) {
+			end = len(hists)
+		}
+		batch := hists[start:end]
+
+		args := make([]interface{}, 0, len(batch)*5)
+		for _, i := range batch {
+			args = append(args, list[i].ID, string(list[i].Type()), list[i].Labels.String(), float64(0), int64(0))
+		}
+		stored, err := c.lockBatch(ctx, tx, args, len(batch))
+		if err != nil {
+			return err
+		}
+		for _, i := range batch {
+			mtr := list[i]
+			prev, ok := stored[mtr.Key()]
+			if !ok {
+				continue
+			}
+			hist := mtr.Value.(*metric.Histogram).Copy()
+			if err = hist.Merge(prev.Value.(*metric.Histogram)); err != nil {
+				return err
+			}
+			list[i] = metric.NewHistogramMetric(mtr.ID, hist).WithLabels(mtr.Labels)
+		}
+	}
+	return nil
+}
+
+// lockBatch executes single lock statement and returns stored metrics by key. Metrics which were not registered yet
+// are omitted.
+func (c *client) lockBatch(ctx context.Context, tx *sql.Tx, args []interface{}, rows int) (map[string]*metric.Metric, error) {
+	result, err := tx.QueryContext(ctx, c.dialect(LockQuery(rows)), args...)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer result.Close()
+
+	stored := make(map[string]*metric.Metric, rows)
+	for result.Next() {
+		m := &Metrics{}
+		if err := result.Scan(&m.ID, &m.typ, &m.labels, &m.histogram); err != nil {
+			return nil, err
+		}
+		if !m.histogram.Valid {
+			continue
+		}
+		mtr := m.ToCanonical()
+		if mtr == nil {
+			return nil, errors.New("unable to convert row to canonical metric")
+		}
+		stored[mtr.Key()] = mtr
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// appendBatch stores samples with multi-row statements. The latest sample of every metric replaces its actual value
+// unless newer sample of the metric is already stored.
+func (c *client) appendBatch(ctx context.Context, tx *sql.Tx, samples metric.Samples) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	latest := make(metric.Samples, 0, len(samples))
+	index := make(map[string]int, len(samples))
+	for _, smp := range samples {
+		if err := smp.Type().Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+		if err := smp.Labels.Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+
+		key := string(smp.Type()) + "/" + smp.Key()
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(latest)
+			latest = append(latest, smp)
+			continue
+		}
+		if !smp.Timestamp.Before(latest[i].Timestamp) {
+			latest[i] = smp
+		}
+	}
+
+	for start := 0; start < len(latest); start += batchSize {
+		end := start + batchSize
+		if end > len(latest) {
+			end = len(latest)
+		}
+		batch := latest[start:end]
+
+		superseded, err := c.superseded(ctx, tx, batch)
+		if err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+		args := make([]interface{}, 0, len(batch)*6)
+		rows := 0
+		for _, smp := range batch {
+			if superseded[string(smp.Type())+"/"+smp.Key()] {
+				continue
+			}
+			v, d, h := toPrimitive(smp.Metric)
+			args = append(args, smp.ID, string(smp.Type()), smp.Labels.String(), v, d, h)
+			rows++
+		}
+		if rows == 0 {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, c.dialect(SetQuery(rows)), args...); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+	}
+	return c.recordBatch(ctx, tx, samples)
+}
+
+// superseded returns keys of metrics which have stored samples newer than the specified ones.
+func (c *client) superseded(ctx context.Context, tx *sql.Tx, samples metric.Samples) (map[string]bool, error) {
+	args := make([]interface{}, 0, len(samples)*4)
+	for _, smp := range samples {
+		args = append(args, smp.ID, string(smp.Type()), smp.Labels.String(), smp.Timestamp.UTC())
+	}
+	rows, err := tx.QueryContext(ctx, c.dialect(NewerSamplesQuery(len(samples))), args...)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	superseded := make(map[string]bool)
+	for rows.Next() {
+		m := &Metrics{}
+		if err := rows.Scan(&m.ID, &m.typ, &m.labels); err != nil {
+			return nil, err
+		}
+		superseded[m.typ+"/"+m.ID+m.labels] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return superseded, nil
+}
+
+// recordBatch stores samples with multi-row statements.
+func (c *client) recordBatch(ctx context.Context, tx *sql.Tx, samples metric.Samples) error {
+	_, logger := logging.GetOrCreateLogger(ctx)
+
+	for start := 0; start < len(samples); start += batchSize {
+		end := start + batchSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		batch := samples[start:end]
+
+		args := make([]interface{}, 0, len(batch)*7)
+		for _, smp := range batch {
+			v, d, h := toPrimitive(smp.Metric)
+			// timestamps are kept in UTC as some db engines compare them as text
+			args = append(args, smp.ID, string(smp.Type()), smp.Labels.String(), v, d, h, smp.Timestamp.UTC())
+		}
+		if _, err := tx.ExecContext(ctx, c.dialect(CreateSamplesQuery(len(batch))), args...); err != nil {
+			logger.Err(err).Msg("record failed")
+			return err
+		}
+	}
+
+	logger.Trace().Msgf("%d samples recorded", len(samples))
+	return nil
+}
+
+// UpsertQuery builds statement which registers or updates specified number of metrics. Counter delta is added to
+// stored one, gauge value and histogram are replaced. Resulting rows are returned.
+func UpsertQuery(rows int) Query {
+	return Query("INSERT INTO metrics (metric_id, metric_type, labels, value, delta, histogram) VALUES " +
+		placeholders(rows, 6) +
+		" ON CONFLICT (metric_id, metric_type, labels) DO UPDATE SET " +
+		"value=excluded.value, delta=metrics.delta+excluded.delta, histogram=excluded.histogram " +
+		"RETURNING metric_id, metric_type, labels, value, delta, histogram")
+}
+
+// LockQuery builds statement which locks rows of specified number of metrics and returns their stored histograms. Row
+// is registered with zero value and delta and no histogram if metric is absent, so that concurrent registration is
+// locked too.
+func LockQuery(rows int) Query {
+	return Query("INSERT INTO metrics (metric_id, metric_type, labels, value, delta) VALUES " +
+		placeholders(rows, 5) +
+		" ON CONFLICT (metric_id, metric_type, labels) DO UPDATE SET histogram=metrics.histogram " +
+		"RETURNING metric_id, metric_type, labels, histogram")
+}
+
+// SetQuery builds statement which registers or replaces values of specified number of metrics.
+func SetQuery(rows int) Query {
+	return Query("INSERT INTO metrics (metric_id, metric_type, labels, value, delta, histogram) VALUES " +
+		placeholders(rows, 6) +
+		" ON CONFLICT (metric_id, metric_type, labels) DO UPDATE SET " +
+		"value=excluded.value, delta=excluded.delta, histogram=excluded.histogram")
+}
+
+// NewerSamplesQuery builds statement which selects distinct metrics having samples newer than specified timestamps.
+// Every metric is matched by four parameters: ID, type, labels and timestamp.
+func NewerSamplesQuery(rows int) Query {
+	var sb strings.Builder
+	sb.WriteString("SELECT DISTINCT metric_id, metric_type, labels FROM metric_samples WHERE ")
+	for r := 0; r < rows; r++ {
+		if r != 0 {
+			sb.WriteString(" OR ")
+		}
+		n := r * 4
+		sb.WriteString("(metric_id=$" + strconv.Itoa(n+1) + " AND metric_type=$" + strconv.Itoa(n+2) +
+			" AND labels=$" + strconv.Itoa(n+3) + " AND ts>$" + strconv.Itoa(n+4) + ")")
+	}
+	return Query(sb.String())
+}
+
+// CreateSamplesQuery builds statement which stores specified number of samples.
+func CreateSamplesQuery(rows int) Query {
+	return Query("INSERT INTO metric_samples (metric_id, metric_type, labels, value, delta, histogram, ts) VALUES " +
+		placeholders(rows, 7))
+}
+
+// placeholders returns values list of positional parameters, e.g. ($1,$2),($3,$4)
+func placeholders(rows, columns int) string {
+	var sb strings.Builder
+	for r := 0; r < rows; r++ {
+		if r != 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('(')
+		for col := 1; col <= columns; col++ {
+			if col != 1 {
+				sb.WriteByte(',')
+			}
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(r*columns + col))
+		}
+		sb.WriteByte(')')
+	}
+	return sb.String()
+}
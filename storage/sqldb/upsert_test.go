@@ -0,0 +1,222 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/config"
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func newSQLiteClient(tb testing.TB) *client {
+	cfg := &config.Config{Database: SQLiteScheme + filepath.Join(tb.TempDir(), "monitor.db")}
+	c := New(SQLite{})(cfg).(*client)
+	require.NoError(tb, c.Init(context.Background()))
+	tb.Cleanup(func() { c.Close(context.Background()) })
+	return c
+}
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	c := newSQLiteClient(t)
+
+	hist := func(values ...float64) metric.Histogram {
+		h := metric.NewHistogram(1, 10)
+		for _, v := range values {
+			h.Observe(v)
+		}
+		return h
+	}
+
+	list := metric.List{
+		metric.NewCounterMetric("PollCount", 1),
+		metric.NewGaugeMetric("Alloc", 1),
+		metric.NewHistogramMetric("Latency", hist(0.5)),
+		metric.NewCounterMetric("PollCount", 2),
+		metric.NewGaugeMetric("Alloc", 2),
+		metric.NewHistogramMetric("Latency", hist(5)),
+	}
+	for i := 0; i < batchSize; i++ {
+		list = append(list, metric.NewGaugeMetric("Gauge"+strconv.Itoa(i), metric.Gauge(i)))
+	}
+	require.NoError(t, c.UpdateBulk(ctx, list))
+	require.NoError(t, c.UpdateBulk(ctx, metric.List{
+		metric.NewCounterMetric("PollCount", 3),
+		metric.NewHistogramMetric("Latency", hist(50)),
+	}))
+
+	tests := []struct {
+		name string
+		id   string
+		typ  metric.Type
+		want *metric.Metric
+	}{
+		{
+			name: "Counter is accumulated",
+			id:   "PollCount",
+			typ:  metric.CounterType,
+			want: metric.NewCounterMetric("PollCount", 6),
+		},
+		{
+			name: "Gauge is replaced",
+			id:   "Alloc",
+			typ:  metric.GaugeType,
+			want: metric.NewGaugeMetric("Alloc", 2),
+		},
+		{
+			name: "Histogram is merged",
+			id:   "Latency",
+			typ:  metric.HistogramType,
+			want: metric.NewHistogramMetric("Latency", hist(0.5, 5, 50)),
+		},
+		{
+			name: "Metric of second batch",
+			id:   "Gauge" + strconv.Itoa(batchSize-1),
+			typ:  metric.GaugeType,
+			want: metric.NewGaugeMetric("Gauge"+strconv.Itoa(batchSize-1), batchSize-1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Get(ctx, tt.id, tt.typ, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Samples", func(t *testing.T) {
+		samples, err := c.History(ctx)
+		require.NoError(t, err)
+		assert.Len(t, samples, batchSize+5, "duplicates are recorded once per update")
+
+		series, err := c.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType})
+		require.NoError(t, err)
+		if assert.Len(t, series, 2) {
+			assert.Equal(t, float64(3), series[0].Value)
+			assert.Equal(t, float64(6), series[1].Value)
+		}
+	})
+
+	t.Run("Invalid labels", func(t *testing.T) {
+		invalid := metric.NewGaugeMetric("Alloc", 3).WithLabels(metric.Labels{"": "foo"})
+		assert.Error(t, c.UpdateBulk(ctx, metric.List{metric.NewGaugeMetric("Alloc", 4), invalid}))
+
+		got, err := c.Get(ctx, "Alloc", metric.GaugeType, nil)
+		require.NoError(t, err)
+		assert.Equal(t, metric.NewGaugeMetric("Alloc", 2), got, "nothing is written")
+	})
+}
+
+func TestAppendBatch(t *testing.T) {
+	ctx := context.Background()
+	c := newSQLiteClient(t)
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, c.Update(ctx, metric.NewGaugeMetric("Alloc", 1)))
+
+	samples := metric.Samples{
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 2), now.Add(-time.Hour)),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 5), now.Add(-time.Second)),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 7), now),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 3), now.Add(-2*time.Second)),
+	}
+	for i := 0; i < batchSize; i++ {
+		samples = append(samples, metric.NewSample(metric.NewGaugeMetric("Gauge"+strconv.Itoa(i), metric.Gauge(i)), now))
+	}
+	require.NoError(t, c.Append(ctx, samples))
+
+	tests := []struct {
+		name string
+		id   string
+		typ  metric.Type
+		want *metric.Metric
+	}{
+		{
+			name: "Outdated sample doesn't change value",
+			id:   "Alloc",
+			typ:  metric.GaugeType,
+			want: metric.NewGaugeMetric("Alloc", 1),
+		},
+		{
+			name: "Latest sample is actual value",
+			id:   "PollCount",
+			typ:  metric.CounterType,
+			want: metric.NewCounterMetric("PollCount", 7),
+		},
+		{
+			name: "Metric of second batch",
+			id:   "Gauge" + strconv.Itoa(batchSize-1),
+			typ:  metric.GaugeType,
+			want: metric.NewGaugeMetric("Gauge"+strconv.Itoa(batchSize-1), batchSize-1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Get(ctx, tt.id, tt.typ, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Samples", func(t *testing.T) {
+		history, err := c.History(ctx)
+		require.NoError(t, err)
+		assert.Len(t, history, len(samples)+1)
+	})
+}
+
+func Test_placeholders(t *testing.T) {
+	assert.Equal(t, "($1,$2,$3)", placeholders(1, 3))
+	assert.Equal(t, "($1,$2),($3,$4),($5,$6)", placeholders(3, 2))
+}
+
+// BenchmarkUpdateBulk compares per metric read-modify-write path with batched upsert on a typical agent report.
+func BenchmarkUpdateBulk(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	ctx := context.Background()
+	list := make(metric.List, 0, 1000)
+	for i := 0; i < cap(list)/2; i++ {
+		list = append(list,
+			metric.NewGaugeMetric("Gauge"+strconv.Itoa(i), metric.Gauge(i)),
+			metric.NewCounterMetric("Counter"+strconv.Itoa(i), 1))
+	}
+
+	b.Run("per-metric", func(b *testing.B) {
+		c := newSQLiteClient(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			now := time.Now()
+			require.NoError(b, c.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				for _, mtr := range list {
+					if err := c.processPerMetric(ctx, tx, mtr, now); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+		}
+	})
+
+	b.Run("upsert", func(b *testing.B) {
+		c := newSQLiteClient(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			now := time.Now()
+			require.NoError(b, c.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				return c.upsert(ctx, tx, list, now)
+			}))
+		}
+	})
+}
@@ -0,0 +1,419 @@
+// Package storagetest verifies that storage backend conforms to storage.Storage contract. Backend tests run the suite
+// against its own factory, so that all backends are checked by the same tests.
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+	"github.com/zhupanovdm/go-runtime-monitor/storage"
+)
+
+// Suite describes storage under test.
+type Suite struct {
+	// New returns storage which is not initialized yet. Storages returned by different calls should not share state.
+	New func(t *testing.T) storage.Storage
+
+	// Dump marks storage which keeps metrics as is, e.g. file dump. Such storage doesn't support single metric
+	// operations and its bulk update replaces all stored metrics.
+	Dump bool
+}
+
+// kind restricts test to storages of specific kind.
+type kind int
+
+const (
+	anyStorage kind = iota
+	metricsOnly
+	dumpsOnly
+)
+
+// unknownValue is a metric value of type which is unknown to storages.
+type unknownValue struct {
+	metric.Gauge
+}
+
+func (*unknownValue) Type() metric.Type {
+	return "unknown"
+}
+
+// Run runs every contract test against newly initialized storage.
+func (s Suite) Run(t *testing.T) {
+	tests := []struct {
+		name string
+		kind kind
+		test func(t *testing.T, st storage.Storage)
+	}{
+		{name: "Gauge is overwritten", kind: metricsOnly, test: testGauge},
+		{name: "Counter is accumulated", kind: metricsOnly, test: testCounter},
+		{name: "Histogram is merged", kind: metricsOnly, test: testHistogram},
+		{name: "Unknown type", kind: metricsOnly, test: testUnknownType},
+		{name: "Invalid labels", kind: metricsOnly, test: testInvalidLabels},
+		{name: "Labels distinguish metrics", kind: metricsOnly, test: testLabels},
+		{name: "Query", kind: metricsOnly, test: testQuery},
+		{name: "Append", kind: metricsOnly, test: testAppend},
+		{name: "Concurrent updates", kind: metricsOnly, test: testConcurrentUpdates},
+		{name: "Bulk update replaces metrics", kind: dumpsOnly, test: testReplace},
+		{name: "Concurrent updates", kind: dumpsOnly, test: testConcurrentReplace},
+		{name: "GetAll", test: testGetAll},
+		{name: "History", test: testHistory},
+		{name: "Clear", test: testClear},
+		{name: "Replace", test: testReplaceSamples},
+		{name: "Cancelled context", test: testCancelledContext},
+	}
+
+	for _, tt := range tests {
+		if tt.kind == metricsOnly && s.Dump || tt.kind == dumpsOnly && !s.Dump {
+			continue
+		}
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := s.New(t)
+			require.NotNil(t, st)
+			require.NoError(t, st.Init(ctx))
+			t.Cleanup(func() { st.Close(ctx) })
+			require.NoError(t, st.Ping(ctx))
+
+			tt.test(t, st)
+		})
+	}
+}
+
+func testGauge(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.Update(ctx, metric.NewGaugeMetric("Alloc", 1)))
+	require.NoError(t, st.Update(ctx, metric.NewGaugeMetric("Alloc", 2)))
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewGaugeMetric("Alloc", 3), metric.NewGaugeMetric("Alloc", 4)}))
+	requireMetric(t, st, metric.NewGaugeMetric("Alloc", 4))
+}
+
+func testCounter(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 2)))
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 3), metric.NewCounterMetric("PollCount", 4)}))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 10))
+}
+
+func testHistogram(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.Update(ctx, metric.NewHistogramMetric("Latency", histogram(0.5))))
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{
+		metric.NewHistogramMetric("Latency", histogram(5)),
+		metric.NewHistogramMetric("Latency", histogram(50)),
+	}))
+	requireMetric(t, st, metric.NewHistogramMetric("Latency", histogram(0.5, 5, 50)))
+
+	mismatch := metric.NewHistogram(1, 2, 3)
+	assert.Error(t, st.Update(ctx, metric.NewHistogramMetric("Latency", mismatch)), "bounds mismatch")
+	requireMetric(t, st, metric.NewHistogramMetric("Latency", histogram(0.5, 5, 50)))
+}
+
+func testUnknownType(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	_, err := st.Get(ctx, "Alloc", "unknown", nil)
+	assert.Error(t, err)
+
+	unknown := &metric.Metric{ID: "Alloc", Value: &unknownValue{}}
+	assert.Error(t, st.Update(ctx, unknown))
+	assert.Error(t, st.UpdateBulk(ctx, metric.List{unknown}))
+
+	got, err := st.Get(ctx, "Alloc", metric.GaugeType, nil)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func testInvalidLabels(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+
+	invalid := metric.NewCounterMetric("PollCount", 1).WithLabels(metric.Labels{"": "foo"})
+	assert.Error(t, st.Update(ctx, invalid))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 1))
+	assert.Error(t, st.UpdateBulk(ctx, metric.List{invalid}))
+}
+
+func testLabels(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	foo := metric.Labels{"host": "foo"}
+	bar := metric.Labels{"host": "bar", "cpu": "1"}
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{
+		metric.NewCounterMetric("PollCount", 1).WithLabels(foo),
+		metric.NewCounterMetric("PollCount", 2).WithLabels(bar),
+		metric.NewCounterMetric("PollCount", 3),
+		metric.NewGaugeMetric("PollCount", 4).WithLabels(foo),
+	}))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1).WithLabels(foo)))
+
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 2).WithLabels(foo))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 2).WithLabels(bar))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 3))
+	requireMetric(t, st, metric.NewGaugeMetric("PollCount", 4).WithLabels(foo))
+
+	got, err := st.Get(ctx, "PollCount", metric.CounterType, metric.Labels{"host": "bar"})
+	require.NoError(t, err)
+	assert.Nil(t, got, "labels subset doesn't identify metric")
+}
+
+func testQuery(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 2)))
+	require.NoError(t, st.Update(ctx, metric.NewGaugeMetric("PollCount", 10)))
+
+	series, err := st.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 3}, values(series))
+
+	series, err = st.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType, From: start, To: time.Now().Add(time.Second)})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 3}, values(series))
+
+	series, err = st.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType, To: start})
+	require.NoError(t, err)
+	assert.Empty(t, series)
+}
+
+func testAppend(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, st.Append(ctx, metric.Samples{
+		metric.NewSample(metric.NewCounterMetric("PollCount", 5), now.Add(-time.Second)),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 7), now),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 3), now.Add(-2*time.Second)),
+	}))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 7))
+
+	series, err := st.Query(ctx, &metric.Query{ID: "PollCount", Type: metric.CounterType})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 5, 7}, values(series))
+
+	require.NoError(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)))
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", 8))
+}
+
+func testConcurrentUpdates(t *testing.T, st storage.Storage) {
+	const workers, updates = 8, 10
+	ctx := context.Background()
+
+	hist := func(n int) metric.Histogram {
+		h := metric.NewHistogram(1, 10)
+		h.ObserveN(5, uint64(n))
+		return h
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*updates*3)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				errs <- st.Update(ctx, metric.NewCounterMetric("PollCount", 1))
+				errs <- st.Update(ctx, metric.NewHistogramMetric("Latency", hist(1)))
+				_, err := st.GetAll(ctx, nil)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	requireMetric(t, st, metric.NewCounterMetric("PollCount", workers*updates))
+	requireMetric(t, st, metric.NewHistogramMetric("Latency", hist(workers*updates)))
+}
+
+func testReplace(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 1), metric.NewGaugeMetric("Alloc", 1)}))
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 2)}))
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", 2)}, list)
+
+	_, err = st.Get(ctx, "PollCount", metric.CounterType, nil)
+	assert.Error(t, err, "single metric operations are unsupported")
+	assert.Error(t, st.Update(ctx, metric.NewCounterMetric("PollCount", 1)), "single metric operations are unsupported")
+}
+
+func testConcurrentReplace(t *testing.T, st storage.Storage) {
+	const workers, updates = 8, 10
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*updates*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				errs <- st.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", metric.Counter(w))})
+				_, err := st.GetAll(ctx, nil)
+				errs <- err
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func testGetAll(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	foo := metric.Labels{"host": "foo"}
+	want := metric.List{
+		metric.NewGaugeMetric("Alloc", 1),
+		metric.NewCounterMetric("PollCount", 2).WithLabels(foo),
+		metric.NewHistogramMetric("Latency", histogram(5)).WithLabels(metric.Labels{"host": "foo", "cpu": "1"}),
+		metric.NewGaugeMetric("Alloc", 3).WithLabels(metric.Labels{"host": "bar"}),
+	}
+	require.NoError(t, st.UpdateBulk(ctx, want))
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, list)
+
+	list, err = st.GetAll(ctx, foo)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want[1:3], list)
+
+	list, err = st.GetAll(ctx, metric.Labels{"host": "baz"})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testHistory(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	samples := metric.Samples{
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 1), now.Add(-2*time.Second)),
+		metric.NewSample(metric.NewCounterMetric("PollCount", 2).WithLabels(metric.Labels{"host": "foo"}), now.Add(-time.Second)),
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 3), now),
+	}
+	require.NoError(t, st.Append(ctx, samples))
+
+	history, err := st.History(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, history, len(samples)) {
+		for i, smp := range history {
+			assert.Equal(t, samples[i].Metric, smp.Metric)
+			assert.True(t, samples[i].Timestamp.Equal(smp.Timestamp), "timestamp is kept")
+		}
+	}
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, metric.List{samples[1].Metric, samples[2].Metric}, list)
+}
+
+func testClear(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewGaugeMetric("Alloc", 1), metric.NewCounterMetric("PollCount", 1)}))
+	require.NoError(t, st.Clear(ctx))
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	history, err := st.History(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewCounterMetric("PollCount", 2)}))
+	list, err = st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.List{metric.NewCounterMetric("PollCount", 2)}, list)
+}
+
+func testReplaceSamples(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	require.NoError(t, st.UpdateBulk(ctx, metric.List{metric.NewGaugeMetric("Alloc", 1), metric.NewCounterMetric("PollCount", 1)}))
+
+	now := time.Now().Truncate(time.Millisecond)
+	samples := metric.Samples{
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 2), now.Add(-time.Second)),
+		metric.NewSample(metric.NewGaugeMetric("Alloc", 3), now),
+	}
+	require.NoError(t, storage.Replace(ctx, st, samples))
+
+	history, err := st.History(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, history, len(samples)) {
+		for i, smp := range history {
+			assert.Equal(t, samples[i].Metric, smp.Metric)
+		}
+	}
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, metric.List{samples[1].Metric}, list)
+}
+
+// testCancelledContext checks that operation on cancelled context either fails or is applied as a whole, and storage
+// is usable afterwards.
+func testCancelledContext(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	list := metric.List{metric.NewCounterMetric("PollCount", 1), metric.NewGaugeMetric("Alloc", 1)}
+	want := metric.List{}
+	if err := st.UpdateBulk(cancelled, list); err == nil {
+		want = list
+	}
+	got, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, got)
+
+	require.NoError(t, st.UpdateBulk(ctx, list[:1]))
+	got, err = st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, got)
+}
+
+// requireMetric checks that metric is stored both as single metric and in metrics list.
+func requireMetric(t *testing.T, st storage.Storage, want *metric.Metric) {
+	t.Helper()
+	ctx := context.Background()
+
+	got, err := st.Get(ctx, want.ID, want.Type(), want.Labels)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	list, err := st.GetAll(ctx, nil)
+	require.NoError(t, err)
+	assert.Contains(t, list, want)
+}
+
+func histogram(values ...float64) metric.Histogram {
+	h := metric.NewHistogram(1, 10)
+	for _, v := range values {
+		h.Observe(v)
+	}
+	return h
+}
+
+func values(series metric.Series) []float64 {
+	v := make([]float64, 0, len(series))
+	for _, point := range series {
+		v = append(v, point.Value)
+	}
+	return v
+}
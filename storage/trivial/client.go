@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
@@ -15,11 +16,28 @@ const trivialStorageName = "Trivial storage"
 
 var _ storage.Storage = (*client)(nil)
 
-type client struct {
-	sync.RWMutex
-	gauges   map[string]metric.Gauge
-	counters map[string]metric.Counter
-}
+type (
+	client struct {
+		sync.RWMutex
+		gauges     map[series]metric.Gauge
+		counters   map[series]metric.Counter
+		histograms map[series]metric.Histogram
+		labels     map[string]metric.Labels
+		history    map[key]metric.Samples
+		retention  time.Duration
+	}
+
+	// series identifies metric by ID and canonical representation of its labels.
+	series struct {
+		id     string
+		labels string
+	}
+
+	key struct {
+		series
+		typ metric.Type
+	}
+)
 
 func (c *client) Clear(ctx context.Context) error {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
@@ -27,8 +45,11 @@ func (c *client) Clear(ctx context.Context) error {
 
 	c.Lock()
 	defer c.Unlock()
-	c.gauges = make(map[string]metric.Gauge)
-	c.counters = make(map[string]metric.Counter)
+	c.gauges = make(map[series]metric.Gauge)
+	c.counters = make(map[series]metric.Counter)
+	c.histograms = make(map[series]metric.Histogram)
+	c.labels = make(map[string]metric.Labels)
+	c.history = make(map[key]metric.Samples)
 
 	logger.Info().Msg("cleared")
 	return nil
@@ -68,25 +89,34 @@ func (c *client) UpdateBulk(ctx context.Context, list metric.List) error {
 	return nil
 }
 
-func (c *client) Get(ctx context.Context, id string, typ metric.Type) (*metric.Metric, error) {
+func (c *client) Get(ctx context.Context, id string, typ metric.Type, labels metric.Labels) (*metric.Metric, error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
 	logger.UpdateContext(logging.LogCtxKeyStr(logging.MetricIDKey, id))
-	logger.UpdateContext(logging.LogCtxFrom(typ))
+	logger.UpdateContext(logging.LogCtxFrom(typ, labels))
+
+	s := series{id, labels.String()}
 
 	c.RLock()
 	defer c.RUnlock()
 	switch typ {
 	case metric.GaugeType:
-		if value, ok := c.gauges[id]; ok {
-			mtr := metric.NewGaugeMetric(id, value)
+		if value, ok := c.gauges[s]; ok {
+			mtr := metric.NewGaugeMetric(id, value).WithLabels(labels)
 			logger.UpdateContext(logging.LogCtxFrom(mtr))
 			logger.Trace().Msg("read")
 			return mtr, nil
 		}
 	case metric.CounterType:
-		if delta, ok := c.counters[id]; ok {
-			mtr := metric.NewCounterMetric(id, delta)
+		if delta, ok := c.counters[s]; ok {
+			mtr := metric.NewCounterMetric(id, delta).WithLabels(labels)
+			logger.UpdateContext(logging.LogCtxFrom(mtr))
+			logger.Trace().Msg("read")
+			return mtr, nil
+		}
+	case metric.HistogramType:
+		if hist, ok := c.histograms[s]; ok {
+			mtr := metric.NewHistogramMetric(id, hist.Copy()).WithLabels(labels)
 			logger.UpdateContext(logging.LogCtxFrom(mtr))
 			logger.Trace().Msg("read")
 			return mtr, nil
@@ -101,25 +131,103 @@ func (c *client) Get(ctx context.Context, id string, typ metric.Type) (*metric.M
 	return nil, nil
 }
 
-func (c *client) GetAll(ctx context.Context) (list metric.List, _ error) {
+func (c *client) GetAll(ctx context.Context, filter metric.Labels) (list metric.List, _ error) {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
-
-	list = make([]*metric.Metric, 0, len(c.gauges)+len(c.counters))
+	logger.UpdateContext(logging.LogCtxFrom(filter))
 
 	c.RLock()
 	defer c.RUnlock()
+
+	list = make([]*metric.Metric, 0, len(c.gauges)+len(c.counters)+len(c.histograms))
 	for k, v := range c.gauges {
-		list = append(list, metric.NewGaugeMetric(k, v))
+		if labels := c.labels[k.labels]; labels.Matches(filter) {
+			list = append(list, metric.NewGaugeMetric(k.id, v).WithLabels(labels))
+		}
 	}
 	for k, v := range c.counters {
-		list = append(list, metric.NewCounterMetric(k, v))
+		if labels := c.labels[k.labels]; labels.Matches(filter) {
+			list = append(list, metric.NewCounterMetric(k.id, v).WithLabels(labels))
+		}
+	}
+	for k, v := range c.histograms {
+		if labels := c.labels[k.labels]; labels.Matches(filter) {
+			list = append(list, metric.NewHistogramMetric(k.id, v.Copy()).WithLabels(labels))
+		}
 	}
 
 	logger.Trace().Msgf("%d records read", len(list))
 	return
 }
 
+func (c *client) History(ctx context.Context) (metric.Samples, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
+
+	c.RLock()
+	defer c.RUnlock()
+	samples := make(metric.Samples, 0)
+	for k, history := range c.history {
+		for _, smp := range history {
+			samples = append(samples, c.newSample(k, smp))
+		}
+	}
+	samples.SortByTime()
+
+	logger.Trace().Msgf("%d samples read", len(samples))
+	return samples, nil
+}
+
+func (c *client) Query(ctx context.Context, query *metric.Query) (metric.Series, error) {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
+	logger.UpdateContext(logging.LogCtxFrom(query))
+
+	c.RLock()
+	defer c.RUnlock()
+	points := query.Apply(c.history[key{series{query.ID, query.Labels.String()}, query.Type}])
+
+	logger.Trace().Msgf("%d points read", len(points))
+	return points, nil
+}
+
+func (c *client) Append(ctx context.Context, samples metric.Samples) error {
+	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
+	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
+
+	sorted := make(metric.Samples, len(samples))
+	copy(sorted, samples)
+	sorted.SortByTime()
+
+	c.Lock()
+	defer c.Unlock()
+	for _, smp := range sorted {
+		if err := smp.Type().Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+		if err := smp.Labels.Validate(); err != nil {
+			logger.Err(err).Msg("append failed")
+			return err
+		}
+		k := c.key(smp.Metric)
+		if history := c.history[k]; len(history) == 0 || !smp.Timestamp.Before(history[len(history)-1].Timestamp) {
+			switch smp.Type() {
+			case metric.GaugeType:
+				c.gauges[k.series] = *smp.Value.(*metric.Gauge)
+			case metric.CounterType:
+				c.counters[k.series] = *smp.Value.(*metric.Counter)
+			case metric.HistogramType:
+				c.histograms[k.series] = smp.Value.(*metric.Histogram).Copy()
+			}
+		}
+		c.record(k, c.newSample(k, smp))
+	}
+
+	logger.Trace().Msgf("%d samples appended", len(samples))
+	return nil
+}
+
 func (c *client) Ping(ctx context.Context) error {
 	ctx, _ = logging.SetIfAbsentCID(ctx, logging.NewCID())
 	_, logger := logging.GetOrCreateLogger(ctx, logging.WithServiceName(trivialStorageName), logging.WithCID(ctx))
@@ -141,25 +249,94 @@ func (c *client) update(ctx context.Context, mtr *metric.Metric) error {
 		logger.Err(err).Msg("update failed")
 		return err
 	}
+	if err := mtr.Labels.Validate(); err != nil {
+		logger.Err(err).Msg("update failed")
+		return err
+	}
 
+	k := c.key(mtr)
+	var actual *metric.Metric
 	switch mtr.Type() {
 	case metric.GaugeType:
-		c.gauges[mtr.ID] = *mtr.Value.(*metric.Gauge)
+		c.gauges[k.series] = *mtr.Value.(*metric.Gauge)
+		actual = metric.NewGaugeMetric(mtr.ID, c.gauges[k.series])
 	case metric.CounterType:
-		c.counters[mtr.ID] += *mtr.Value.(*metric.Counter)
+		c.counters[k.series] += *mtr.Value.(*metric.Counter)
+		actual = metric.NewCounterMetric(mtr.ID, c.counters[k.series])
+	case metric.HistogramType:
+		hist := mtr.Value.(*metric.Histogram).Copy()
+		if prev, ok := c.histograms[k.series]; ok {
+			if err := hist.Merge(&prev); err != nil {
+				logger.Err(err).Msg("update failed")
+				return err
+			}
+		}
+		c.histograms[k.series] = hist
+		actual = metric.NewHistogramMetric(mtr.ID, hist.Copy())
 	default:
 		err := fmt.Errorf("unknown metric %v", mtr.Type())
 		logger.Err(err).Msg("update failed")
 		return err
 	}
+	c.record(k, metric.NewSample(actual.WithLabels(c.labels[k.labels]), time.Now()))
 
 	logger.Trace().Msg("updated")
 	return nil
 }
 
-func New(*config.Config) storage.Storage {
+// record adds sample to metric history and drops samples which are out of retention period. The latest sample of
+// metric is always kept. Thread unsafe, should be locked before update.
+func (c *client) record(k key, smp *metric.Sample) {
+	if c.history == nil {
+		c.history = make(map[key]metric.Samples)
+	}
+	history := append(c.history[k], smp)
+	if c.retention != 0 {
+		expired := time.Now().Add(-c.retention)
+		i := 0
+		for i < len(history)-1 && history[i].Timestamp.Before(expired) {
+			i++
+		}
+		history = history[i:]
+	}
+	c.history[k] = history
+}
+
+// key returns storage key of metric and registers its labels. Thread unsafe, should be locked before update.
+func (c *client) key(mtr *metric.Metric) key {
+	s := series{mtr.ID, mtr.Labels.String()}
+	if _, ok := c.labels[s.labels]; !ok {
+		if c.labels == nil {
+			c.labels = make(map[string]metric.Labels)
+		}
+		c.labels[s.labels] = mtr.Labels.Copy()
+	}
+	return key{s, mtr.Type()}
+}
+
+// newSample returns copy of sample stored under the key.
+func (c *client) newSample(k key, smp *metric.Sample) *metric.Sample {
+	var mtr *metric.Metric
+	switch k.typ {
+	case metric.GaugeType:
+		mtr = metric.NewGaugeMetric(k.id, *smp.Value.(*metric.Gauge))
+	case metric.CounterType:
+		mtr = metric.NewCounterMetric(k.id, *smp.Value.(*metric.Counter))
+	case metric.HistogramType:
+		mtr = metric.NewHistogramMetric(k.id, smp.Value.(*metric.Histogram).Copy())
+	default:
+		return nil
+	}
+	return metric.NewSample(mtr.WithLabels(c.labels[k.labels]), smp.Timestamp)
+}
+
+func New(cfg *config.Config) storage.Storage {
 	return &client{
-		gauges:   make(map[string]metric.Gauge),
-		counters: make(map[string]metric.Counter),
+		gauges:     make(map[series]metric.Gauge),
+		counters:   make(map[series]metric.Counter),
+		histograms: make(map[series]metric.Histogram),
+		labels:     make(map[string]metric.Labels),
+		history:    make(map[key]metric.Samples),
+		retention:  cfg.Retention,
 	}
 }
@@ -3,19 +3,23 @@ package trivial
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/zhupanovdm/go-runtime-monitor/config"
 	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
 	"github.com/zhupanovdm/go-runtime-monitor/storage"
+	"github.com/zhupanovdm/go-runtime-monitor/storage/storagetest"
 )
 
 func Test_trivialCounterStorage_Get(t *testing.T) {
 	var s storage.Storage = &client{
-		counters: map[string]metric.Counter{
-			"counter0": 0,
-			"counter1": 1,
-			"counter2": 2,
+		counters: map[series]metric.Counter{
+			{id: "counter0"}: 0,
+			{id: "counter1"}: 1,
+			{id: "counter2"}: 2,
 		},
 	}
 
@@ -43,7 +47,7 @@ func Test_trivialCounterStorage_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := s.Get(context.TODO(), tt.id, metric.CounterType)
+			got, err := s.Get(context.TODO(), tt.id, metric.CounterType, nil)
 			if assert.NoError(t, err) {
 				assert.Equal(t, tt.want, got)
 			}
@@ -53,10 +57,10 @@ func Test_trivialCounterStorage_Get(t *testing.T) {
 
 func Test_trivialCounterStorage_GetAll(t *testing.T) {
 	var s storage.Storage = &client{
-		counters: map[string]metric.Counter{
-			"counter0": 0,
-			"counter1": 1,
-			"counter2": 2,
+		counters: map[series]metric.Counter{
+			{id: "counter0"}: 0,
+			{id: "counter1"}: 1,
+			{id: "counter2"}: 2,
 		},
 	}
 
@@ -76,7 +80,7 @@ func Test_trivialCounterStorage_GetAll(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotList, err := s.GetAll(context.TODO())
+			gotList, err := s.GetAll(context.TODO(), nil)
 			if assert.NoError(t, err) {
 				assert.ElementsMatch(t, tt.wantList, gotList)
 			}
@@ -85,10 +89,10 @@ func Test_trivialCounterStorage_GetAll(t *testing.T) {
 }
 
 func Test_trivialCounterStorage_Update(t *testing.T) {
-	data := map[string]metric.Counter{
-		"counter0": 0,
-		"counter1": 1,
-		"counter2": 2,
+	data := map[series]metric.Counter{
+		{id: "counter0"}: 0,
+		{id: "counter1"}: 1,
+		{id: "counter2"}: 2,
 	}
 
 	var s storage.Storage = &client{counters: data}
@@ -114,7 +118,7 @@ func Test_trivialCounterStorage_Update(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			err := s.Update(context.TODO(), tt.metric)
 			if assert.NoError(t, err) {
-				m, _ := s.Get(context.TODO(), tt.metric.ID, tt.metric.Type())
+				m, _ := s.Get(context.TODO(), tt.metric.ID, tt.metric.Type(), nil)
 				assert.Equal(t, tt.want, m)
 			}
 		})
@@ -123,10 +127,10 @@ func Test_trivialCounterStorage_Update(t *testing.T) {
 
 func Test_trivialGaugeStorage_Get(t *testing.T) {
 	var s storage.Storage = &client{
-		gauges: map[string]metric.Gauge{
-			"gauge0": 0,
-			"gauge1": .1,
-			"gauge2": .2,
+		gauges: map[series]metric.Gauge{
+			{id: "gauge0"}: 0,
+			{id: "gauge1"}: .1,
+			{id: "gauge2"}: .2,
 		},
 	}
 
@@ -154,7 +158,7 @@ func Test_trivialGaugeStorage_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := s.Get(context.TODO(), tt.id, metric.GaugeType)
+			got, err := s.Get(context.TODO(), tt.id, metric.GaugeType, nil)
 			if assert.NoError(t, err) {
 				assert.Equal(t, tt.want, got)
 			}
@@ -164,10 +168,10 @@ func Test_trivialGaugeStorage_Get(t *testing.T) {
 
 func Test_trivialGaugeStorage_GetAll(t *testing.T) {
 	var s storage.Storage = &client{
-		gauges: map[string]metric.Gauge{
-			"gauge0": 0,
-			"gauge1": .1,
-			"gauge2": .2,
+		gauges: map[series]metric.Gauge{
+			{id: "gauge0"}: 0,
+			{id: "gauge1"}: .1,
+			{id: "gauge2"}: .2,
 		},
 	}
 
@@ -187,7 +191,7 @@ func Test_trivialGaugeStorage_GetAll(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotList, err := s.GetAll(context.TODO())
+			gotList, err := s.GetAll(context.TODO(), nil)
 			if assert.NoError(t, err) {
 				assert.ElementsMatch(t, tt.wantList, gotList)
 			}
@@ -196,10 +200,10 @@ func Test_trivialGaugeStorage_GetAll(t *testing.T) {
 }
 
 func Test_trivialGaugeStorage_Update(t *testing.T) {
-	data := map[string]metric.Gauge{
-		"gauge0": 0,
-		"gauge1": .1,
-		"gauge2": .2,
+	data := map[series]metric.Gauge{
+		{id: "gauge0"}: 0,
+		{id: "gauge1"}: .1,
+		{id: "gauge2"}: .2,
 	}
 
 	var s storage.Storage = &client{gauges: data}
@@ -225,9 +229,195 @@ func Test_trivialGaugeStorage_Update(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			err := s.Update(context.TODO(), tt.metric)
 			if assert.NoError(t, err) {
-				m, _ := s.Get(context.TODO(), tt.metric.ID, tt.metric.Type())
+				m, _ := s.Get(context.TODO(), tt.metric.ID, tt.metric.Type(), nil)
 				assert.Equal(t, tt.want, m)
 			}
 		})
 	}
 }
+
+func Test_trivialStorage_History(t *testing.T) {
+	s := New(&config.Config{})
+	ctx := context.TODO()
+
+	require.NoError(t, s.Update(ctx, metric.NewGaugeMetric("foo", 1)))
+	require.NoError(t, s.Update(ctx, metric.NewGaugeMetric("foo", 2)))
+	require.NoError(t, s.UpdateBulk(ctx, metric.List{
+		metric.NewCounterMetric("bar", 1),
+		metric.NewCounterMetric("bar", 2),
+	}))
+
+	samples, err := s.History(ctx)
+	require.NoError(t, err)
+
+	list := make(metric.List, 0, len(samples))
+	for _, smp := range samples {
+		assert.False(t, smp.Timestamp.IsZero())
+		list = append(list, smp.Metric)
+	}
+	assert.ElementsMatch(t, metric.List{
+		metric.NewGaugeMetric("foo", 1),
+		metric.NewGaugeMetric("foo", 2),
+		metric.NewCounterMetric("bar", 1),
+		metric.NewCounterMetric("bar", 3),
+	}, list)
+}
+
+func Test_trivialStorage_Append(t *testing.T) {
+	ts := time.Now()
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		samples   metric.Samples
+		wantList  metric.List
+		wantCount int
+	}{
+		{
+			name: "Latest sample is actual",
+			samples: metric.Samples{
+				metric.NewSample(metric.NewGaugeMetric("foo", 2), ts),
+				metric.NewSample(metric.NewGaugeMetric("foo", 1), ts.Add(-time.Second)),
+				metric.NewSample(metric.NewCounterMetric("bar", 5), ts),
+			},
+			wantList: metric.List{
+				metric.NewGaugeMetric("foo", 2),
+				metric.NewCounterMetric("bar", 5),
+			},
+			wantCount: 3,
+		},
+		{
+			name:      "Retention keeps actual value",
+			retention: time.Minute,
+			samples: metric.Samples{
+				metric.NewSample(metric.NewGaugeMetric("foo", 1), ts.Add(-time.Hour)),
+				metric.NewSample(metric.NewGaugeMetric("foo", 2), ts.Add(-time.Hour+time.Second)),
+				metric.NewSample(metric.NewCounterMetric("bar", 5), ts.Add(-time.Hour)),
+			},
+			wantList: metric.List{
+				metric.NewGaugeMetric("foo", 2),
+				metric.NewCounterMetric("bar", 5),
+			},
+			wantCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&config.Config{Retention: tt.retention})
+			ctx := context.TODO()
+
+			require.NoError(t, s.Append(ctx, tt.samples))
+
+			list, err := s.GetAll(ctx, nil)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.wantList, list)
+
+			samples, err := s.History(ctx)
+			require.NoError(t, err)
+			assert.Len(t, samples, tt.wantCount)
+		})
+	}
+}
+
+func Test_trivialHistogramStorage_Update(t *testing.T) {
+	s := New(&config.Config{})
+	ctx := context.TODO()
+
+	require.NoError(t, s.Update(ctx, metric.NewHistogramMetric("foo",
+		metric.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1})))
+
+	tests := []struct {
+		name    string
+		metric  *metric.Metric
+		want    *metric.Metric
+		wantErr bool
+	}{
+		{
+			name: "Buckets merge",
+			metric: metric.NewHistogramMetric("foo",
+				metric.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 5.5, Count: 3}),
+			want: metric.NewHistogramMetric("foo",
+				metric.Histogram{Bounds: []float64{1}, Counts: []uint64{2, 2}, Sum: 6, Count: 4}),
+		},
+		{
+			name: "Bounds mismatch",
+			metric: metric.NewHistogramMetric("foo",
+				metric.Histogram{Bounds: []float64{2}, Counts: []uint64{1, 0}, Sum: 1, Count: 1}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Update(ctx, tt.metric)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				m, _ := s.Get(ctx, tt.metric.ID, tt.metric.Type(), nil)
+				assert.Equal(t, tt.want, m)
+			}
+		})
+	}
+}
+
+func Test_trivialStorage_Labels(t *testing.T) {
+	s := New(&config.Config{})
+	ctx := context.TODO()
+
+	require.NoError(t, s.UpdateBulk(ctx, metric.List{
+		metric.NewGaugeMetric("cpu", 10).WithLabels(metric.Labels{"cpu": "1", "host": "foo"}),
+		metric.NewGaugeMetric("cpu", 20).WithLabels(metric.Labels{"cpu": "2", "host": "foo"}),
+		metric.NewGaugeMetric("cpu", 30),
+		metric.NewCounterMetric("requests", 1).WithLabels(metric.Labels{"host": "bar"}),
+		metric.NewCounterMetric("requests", 2).WithLabels(metric.Labels{"host": "bar"}),
+	}))
+
+	t.Run("Get", func(t *testing.T) {
+		m, err := s.Get(ctx, "cpu", metric.GaugeType, metric.Labels{"cpu": "2", "host": "foo"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, metric.NewGaugeMetric("cpu", 20).WithLabels(metric.Labels{"cpu": "2", "host": "foo"}), m)
+		}
+		m, err = s.Get(ctx, "cpu", metric.GaugeType, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, metric.NewGaugeMetric("cpu", 30), m)
+		}
+		m, err = s.Get(ctx, "cpu", metric.GaugeType, metric.Labels{"cpu": "3"})
+		if assert.NoError(t, err) {
+			assert.Nil(t, m)
+		}
+		m, err = s.Get(ctx, "requests", metric.CounterType, metric.Labels{"host": "bar"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, metric.NewCounterMetric("requests", 3).WithLabels(metric.Labels{"host": "bar"}), m)
+		}
+	})
+
+	t.Run("GetAll filtered", func(t *testing.T) {
+		list, err := s.GetAll(ctx, metric.Labels{"host": "foo"})
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, metric.List{
+				metric.NewGaugeMetric("cpu", 10).WithLabels(metric.Labels{"cpu": "1", "host": "foo"}),
+				metric.NewGaugeMetric("cpu", 20).WithLabels(metric.Labels{"cpu": "2", "host": "foo"}),
+			}, list)
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		series, err := s.Query(ctx, &metric.Query{ID: "cpu", Type: metric.GaugeType, Labels: metric.Labels{"cpu": "1", "host": "foo"}})
+		if assert.NoError(t, err) && assert.Len(t, series, 1) {
+			assert.Equal(t, float64(10), series[0].Value)
+		}
+	})
+
+	t.Run("Invalid labels", func(t *testing.T) {
+		assert.Error(t, s.Update(ctx, metric.NewGaugeMetric("cpu", 1).WithLabels(metric.Labels{"1cpu": "1"})))
+	})
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Suite{
+		New: func(t *testing.T) storage.Storage {
+			return New(&config.Config{})
+		},
+	}.Run(t)
+}
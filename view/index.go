@@ -1,9 +1,39 @@
 package view
 
-import "html/template"
+import (
+	"html/template"
+	"sort"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
 
 var Index = template.Must(template.New("index").Parse(index()))
 
+// IndexSection is a list of metrics reported by single source instance.
+type IndexSection struct {
+	Instance string
+	Metrics  metric.List
+}
+
+// NewIndex groups metrics by source instance. Sections are ordered by instance, metrics of anonymous sources go first.
+// Order of metrics within section is preserved.
+func NewIndex(list metric.List) []IndexSection {
+	sections := make([]IndexSection, 0)
+	index := make(map[string]int)
+	for _, mtr := range list {
+		instance := mtr.Labels.Instance()
+		i, ok := index[instance]
+		if !ok {
+			i = len(sections)
+			index[instance] = i
+			sections = append(sections, IndexSection{Instance: instance})
+		}
+		sections[i].Metrics = append(sections[i].Metrics, mtr)
+	}
+	sort.SliceStable(sections, func(i, j int) bool { return sections[i].Instance < sections[j].Instance })
+	return sections
+}
+
 func index() string {
 	return `<!DOCTYPE html>
 <html lang="en">
@@ -12,7 +42,9 @@ func index() string {
 <title>Title</title>
 </head>
 <body>
-{{range .}}<div><a href="/value/{{.Value.Type}}/{{.ID}}">{{.}}</a></div>{{end}}
+{{range .}}<h3>{{if .Instance}}{{.Instance}}{{else}}anonymous{{end}}</h3>
+{{range .Metrics}}<div><a href="/value/{{.Value.Type}}/{{.ID}}{{if .Labels}}?labels={{.Labels}}{{end}}">{{.}}</a></div>
+{{end}}{{end}}
 </body>
 </html>`
 }
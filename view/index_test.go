@@ -0,0 +1,31 @@
+package view
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestNewIndex(t *testing.T) {
+	fooAlloc := metric.NewGaugeMetric("Alloc", 1).WithLabels(metric.Labels{metric.InstanceLabel: "foo"})
+	barAlloc := metric.NewGaugeMetric("Alloc", 2).WithLabels(metric.Labels{metric.InstanceLabel: "bar"})
+	fooPoll := metric.NewCounterMetric("PollCount", 3).WithLabels(metric.Labels{metric.InstanceLabel: "foo"})
+	anonymous := metric.NewGaugeMetric("Alloc", 4)
+
+	sections := NewIndex(metric.List{fooAlloc, barAlloc, fooPoll, anonymous})
+	assert.Equal(t, []IndexSection{
+		{Metrics: metric.List{anonymous}},
+		{Instance: "bar", Metrics: metric.List{barAlloc}},
+		{Instance: "foo", Metrics: metric.List{fooAlloc, fooPoll}},
+	}, sections)
+
+	var buf bytes.Buffer
+	require.NoError(t, Index.Execute(&buf, sections))
+	assert.Contains(t, buf.String(), "<h3>anonymous</h3>")
+	assert.Contains(t, buf.String(), "<h3>foo</h3>")
+	assert.Contains(t, buf.String(), `href="/value/counter/PollCount?labels=%7binstance%3d%22foo%22%7d"`)
+}
@@ -0,0 +1,166 @@
+package view
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+// PrometheusContentType is the content type of Prometheus text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var promLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type promSample struct {
+	name  string
+	typ   string
+	lines []string
+}
+
+// Prometheus writes metrics in Prometheus text exposition format. Metric IDs are sanitized to valid Prometheus names,
+// counters names are suffixed with "_total". Metrics of the same ID and type are exposed as a single family
+// distinguished by labels. If metrics of several IDs are sanitized to the same name only the first one (in order of
+// IDs) is exposed.
+func Prometheus(w io.Writer, list metric.List) error {
+	sorted := make(metric.List, len(list))
+	copy(sorted, list)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ID != sorted[j].ID {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Labels.String() < sorted[j].Labels.String()
+	})
+
+	type owner struct {
+		id  string
+		typ metric.Type
+	}
+	owners := make(map[string]owner)
+	families := make([]*promSample, 0, len(sorted))
+	index := make(map[string]*promSample)
+	for _, mtr := range sorted {
+		smp, ok := newPromSample(mtr)
+		if !ok {
+			continue
+		}
+		if o, ok := owners[smp.name]; ok && (o.id != mtr.ID || o.typ != mtr.Type()) {
+			continue
+		}
+		owners[smp.name] = owner{mtr.ID, mtr.Type()}
+		if family, ok := index[smp.name]; ok {
+			family.lines = append(family.lines, smp.lines...)
+			continue
+		}
+		index[smp.name] = &smp
+		families = append(families, &smp)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	buf := bufio.NewWriter(w)
+	for _, family := range families {
+		if _, err := fmt.Fprintf(buf, "# TYPE %s %s\n", family.name, family.typ); err != nil {
+			return err
+		}
+		for _, line := range family.lines {
+			if _, err := fmt.Fprintln(buf, line); err != nil {
+				return err
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+func newPromSample(mtr *metric.Metric) (smp promSample, ok bool) {
+	name := PrometheusName(mtr.ID)
+	labels := promLabels(mtr.Labels)
+	switch value := mtr.Value.(type) {
+	case *metric.Gauge:
+		return promSample{name: name, typ: "gauge", lines: []string{
+			fmt.Sprintf("%s%s %s", name, labels, promFloat(float64(*value))),
+		}}, true
+	case *metric.Counter:
+		if !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+		return promSample{name: name, typ: "counter", lines: []string{
+			fmt.Sprintf("%s%s %d", name, labels, int64(*value)),
+		}}, true
+	case *metric.Histogram:
+		lines := make([]string, 0, len(value.Counts)+2)
+		var cumulative uint64
+		for i, count := range value.Counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(value.Bounds) {
+				le = promFloat(value.Bounds[i])
+			}
+			lines = append(lines, fmt.Sprintf("%s_bucket%s %d", name, promLabels(mtr.Labels, "le", le), cumulative))
+		}
+		lines = append(lines,
+			fmt.Sprintf("%s_sum%s %s", name, labels, promFloat(value.Sum)),
+			fmt.Sprintf("%s_count%s %d", name, labels, value.Count))
+		return promSample{name: name, typ: "histogram", lines: lines}, true
+	}
+	return
+}
+
+// promLabels formats labels set followed by extra name-value pairs. Empty set is formatted as empty string.
+func promLabels(labels metric.Labels, extra ...string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, promLabelValueEscaper.Replace(labels[name])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[i], promLabelValueEscaper.Replace(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// PrometheusName converts metric ID to valid Prometheus metric name. Invalid characters are replaced with underscore.
+func PrometheusName(id string) string {
+	var builder strings.Builder
+	for i, r := range id {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			builder.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	if builder.Len() == 0 {
+		return "_"
+	}
+	return builder.String()
+}
+
+func promFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,119 @@
+package view
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhupanovdm/go-runtime-monitor/model/metric"
+)
+
+func TestPrometheus(t *testing.T) {
+	tests := []struct {
+		name string
+		list metric.List
+		want string
+	}{
+		{
+			name: "Basic test",
+			list: metric.List{
+				metric.NewGaugeMetric("HeapAlloc", 1024),
+				metric.NewCounterMetric("PollCount", 5),
+				metric.NewGaugeMetric("GCCPUFraction", 0.25),
+			},
+			want: "# TYPE GCCPUFraction gauge\nGCCPUFraction 0.25\n" +
+				"# TYPE HeapAlloc gauge\nHeapAlloc 1024\n" +
+				"# TYPE PollCount_total counter\nPollCount_total 5\n",
+		},
+		{
+			name: "Sanitized names",
+			list: metric.List{
+				metric.NewGaugeMetric("1st.metric-name", 1),
+				metric.NewCounterMetric("requests_total", 2),
+			},
+			want: "# TYPE _1st_metric_name gauge\n_1st_metric_name 1\n" +
+				"# TYPE requests_total counter\nrequests_total 2\n",
+		},
+		{
+			name: "Same name and type collision",
+			list: metric.List{
+				metric.NewGaugeMetric("foo_bar", 2),
+				metric.NewGaugeMetric("foo-bar", 1),
+			},
+			want: "# TYPE foo_bar gauge\nfoo_bar 1\n",
+		},
+		{
+			name: "Same name of different types",
+			list: metric.List{
+				metric.NewGaugeMetric("foo", 1),
+				metric.NewCounterMetric("foo", 2),
+			},
+			want: "# TYPE foo gauge\nfoo 1\n# TYPE foo_total counter\nfoo_total 2\n",
+		},
+		{
+			name: "Labeled metrics",
+			list: metric.List{
+				metric.NewGaugeMetric("CPUutilization", 20).WithLabels(metric.Labels{"cpu": "2"}),
+				metric.NewGaugeMetric("CPUutilization", 10).WithLabels(metric.Labels{"cpu": "1"}),
+				metric.NewCounterMetric("Requests", 3).WithLabels(metric.Labels{"path": "/a\"b\""}),
+				metric.NewHistogramMetric("Latency", metric.Histogram{
+					Bounds: []float64{1},
+					Counts: []uint64{1, 1},
+					Sum:    2.5,
+					Count:  2,
+				}).WithLabels(metric.Labels{"path": "/"}),
+			},
+			want: "# TYPE CPUutilization gauge\n" +
+				"CPUutilization{cpu=\"1\"} 10\n" +
+				"CPUutilization{cpu=\"2\"} 20\n" +
+				"# TYPE Latency histogram\n" +
+				"Latency_bucket{path=\"/\",le=\"1\"} 1\n" +
+				"Latency_bucket{path=\"/\",le=\"+Inf\"} 2\n" +
+				"Latency_sum{path=\"/\"} 2.5\n" +
+				"Latency_count{path=\"/\"} 2\n" +
+				"# TYPE Requests_total counter\n" +
+				"Requests_total{path=\"/a\\\"b\\\"\"} 3\n",
+		},
+		{
+			name: "Special values",
+			list: metric.List{
+				metric.NewGaugeMetric("inf", metric.Gauge(math.Inf(1))),
+				metric.NewGaugeMetric("nan", metric.Gauge(math.NaN())),
+			},
+			want: "# TYPE inf gauge\ninf +Inf\n# TYPE nan gauge\nnan NaN\n",
+		},
+		{
+			name: "Histogram",
+			list: metric.List{
+				metric.NewHistogramMetric("GCPause", metric.Histogram{
+					Bounds: []float64{0.001, 0.01},
+					Counts: []uint64{2, 1, 1},
+					Sum:    0.5,
+					Count:  4,
+				}),
+			},
+			want: "# TYPE GCPause histogram\n" +
+				"GCPause_bucket{le=\"0.001\"} 2\n" +
+				"GCPause_bucket{le=\"0.01\"} 3\n" +
+				"GCPause_bucket{le=\"+Inf\"} 4\n" +
+				"GCPause_sum 0.5\n" +
+				"GCPause_count 4\n",
+		},
+		{
+			name: "Empty list",
+			list: metric.List{},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if assert.NoError(t, Prometheus(buf, tt.list)) {
+				assert.Equal(t, tt.want, buf.String())
+			}
+		})
+	}
+}